@@ -1,60 +1,234 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/CAATHARSIS/task-tracking/internal/auth"
 	"github.com/CAATHARSIS/task-tracking/internal/config"
 	"github.com/CAATHARSIS/task-tracking/internal/handlers/api"
 	"github.com/CAATHARSIS/task-tracking/internal/handlers/web"
-	board_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board"
-	board_task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board_task"
-	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
-	user_repo "github.com/CAATHARSIS/task-tracking/internal/repository/user"
+	"github.com/CAATHARSIS/task-tracking/internal/health"
+	"github.com/CAATHARSIS/task-tracking/internal/logging"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
 	"github.com/CAATHARSIS/task-tracking/internal/router"
-	"github.com/CAATHARSIS/task-tracking/pkg/database"
+	"github.com/CAATHARSIS/task-tracking/internal/scheduler"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/CAATHARSIS/task-tracking/internal/tracing"
+	"github.com/gin-gonic/gin"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
 
+// @title Task Tracking API
+// @version 1.0
+// @description JSON API трекера задач: доски, задачи, учёт времени и сохранённые представления. Запросы POST, PATCH и DELETE принимают заголовок Idempotency-Key: повтор с тем же ключом получает сохранённый ответ (заголовок Idempotent-Replayed), тот же ключ с другим запросом отклоняется с 422, тело больше IDEMPOTENCY_MAX_BODY_SIZE - с 413. Частота входа, регистрации и запросов пользователя ограничена: ответы содержат заголовки RateLimit-Limit, RateLimit-Remaining и RateLimit-Reset, превышение лимита - 429 с Retry-After
+// @BasePath /api
+// @securityDefinitions.apikey CookieAuth
+// @in cookie
+// @name auth_token
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			fatal("Command failed", err)
+		}
+		return
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("Failed to load config", err)
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fatal("Failed to configure logging", err)
 	}
+	slog.SetDefault(logger)
+	configureGin(cfg.AppEnv)
 
-	db, err := database.NewPostgresDB(cfg)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
 	if err != nil {
-		log.Fatalf("PostgreSQL connection error: %v", err)
+		fatal("Failed to configure tracing", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, repos, err := openRepositories(cfg)
+	if err != nil {
+		fatal("Failed to open repositories", err)
 	}
 	defer db.Close()
 
+	redisClient := openRedis(cfg)
+	if redisClient != nil {
+		defer redisClient.Close()
+	}
+
+	taskCache := openCache(cfg, redisClient)
+	if taskCache != nil {
+		repos.invalidate(taskCache)
+	}
+
+	metricsHandler, metricsServer, err := openMetrics(cfg, db, taskCache)
+	if err != nil {
+		fatal("Failed to start metrics server", err)
+	}
+	checker := openHealthChecks(cfg, db, redisClient)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	jwtService := auth.NewJWTService(cfg)
 
-	boardRepo := board_repo.NewBoardPostgresRepo(db)
-	boardTaskRepo := board_task_repo.NewBoardTaskPostgresRepo(db)
-	taskRepo := task_repo.NewTaskPostgresRepo(db)
-	userRepo := user_repo.NewUserPostgresRepo(db)
-
-	apiBoardHandler := api.NewBoardHandler(boardRepo)
-	webBoardHandler := web.NewBoardHandler(boardRepo, boardTaskRepo, taskRepo)
-	apiBoardTaskHandler := api.NewBoardTaskRealtionHandler(boardTaskRepo)
-	apiTaskHandler := api.NewTaskHandler(taskRepo)
-	webTaskHandler := web.NewTaskHandler(taskRepo)
-	apiUserHandler := api.NewUserHandler(userRepo)
-	webUserHandler := web.NewUserHandler(userRepo, taskRepo)
-
-	r := router.SetupRouter(
-		apiBoardHandler,
-		webBoardHandler,
-		apiBoardTaskHandler,
-		apiTaskHandler,
-		webTaskHandler,
-		apiUserHandler,
-		webUserHandler,
-		jwtService,
-	)
-
-	r.Run(":" + cfg.AppPort)
+	boardRepo := repos.boards
+	boardTaskRepo := repos.boardTasks
+	taskRepo := repos.tasks
+	userRepo := repos.users
+	savedViewRepo := repos.savedViews
+	taskSeriesRepo := repos.taskSeries
+	timeEntryRepo := repos.timeEntries
+	customFieldRepo := repos.customFields
+	uow := repos.uow
+
+	recurrenceScheduler := scheduler.NewRecurrenceScheduler(taskSeriesRepo, taskRepo, boardTaskRepo, uow, cfg.RecurrenceCheckInterval)
+	go recurrenceScheduler.Start(ctx)
+
+	taskService := service.NewTaskService(taskRepo, boardRepo, boardTaskRepo, taskSeriesRepo, customFieldRepo, userRepo, uow, taskCache)
+	boardService := service.NewBoardService(boardRepo, boardTaskRepo, taskRepo, customFieldRepo, uow, taskCache)
+	userService := service.NewUserService(userRepo, jwtService)
+
+	apiBoardHandler := api.NewBoardHandler(boardService)
+	webBoardHandler := web.NewBoardHandler(boardService, taskService)
+	apiBoardTaskHandler := api.NewBoardTaskRealtionHandler(boardService)
+	apiTaskHandler := api.NewTaskHandler(taskService)
+	webTaskHandler := web.NewTaskHandler(taskService, boardService)
+	apiUserHandler := api.NewUserHandler(userService)
+	webUserHandler := web.NewUserHandler(userService, taskService)
+	apiSavedViewHandler := api.NewSavedViewHandler(savedViewRepo, taskRepo, boardRepo, customFieldRepo)
+	webSavedViewHandler := web.NewSavedViewHandler(savedViewRepo, taskRepo, boardRepo)
+	apiTaskSeriesHandler := api.NewTaskSeriesHandler(taskSeriesRepo, taskRepo, uow)
+	apiTimeEntryHandler := api.NewTimeEntryHandler(timeEntryRepo, taskRepo, boardRepo)
+	apiCustomFieldHandler := api.NewCustomFieldHandler(customFieldRepo, boardRepo, taskService)
+	webCustomFieldHandler := web.NewCustomFieldHandler(customFieldRepo, boardRepo)
+
+	r := router.SetupRouter(router.Deps{
+		APIBoard:       apiBoardHandler,
+		WebBoard:       webBoardHandler,
+		APIBoardTask:   apiBoardTaskHandler,
+		APITask:        apiTaskHandler,
+		WebTask:        webTaskHandler,
+		APIUser:        apiUserHandler,
+		WebUser:        webUserHandler,
+		APISavedView:   apiSavedViewHandler,
+		WebSavedView:   webSavedViewHandler,
+		APITaskSeries:  apiTaskSeriesHandler,
+		APITimeEntry:   apiTimeEntryHandler,
+		APICustomField: apiCustomFieldHandler,
+		WebCustomField: webCustomFieldHandler,
+		JWT:            jwtService,
+		Idempotency: middleware.Idempotency(openIdempotencyKeys(cfg, repos, redisClient), middleware.IdempotencyOptions{
+			TTL:         cfg.IdempotencyTTL,
+			Lease:       cfg.IdempotencyLease,
+			MaxBodySize: cfg.IdempotencyMaxBodySize,
+		}),
+		RateLimits: middleware.RateLimits{
+			Store:      openRateLimitStore(cfg, redisClient),
+			AuthIP:     cfg.RateLimitAuthIP,
+			LoginEmail: cfg.RateLimitLoginEmail,
+			APIUser:    cfg.RateLimitAPIUser,
+		},
+		Metrics:        metricsHandler,
+		Health:         checker,
+		TrustedProxies: cfg.TrustedProxies,
+	})
+
+	server := &http.Server{Addr: ":" + cfg.AppPort, Handler: r}
+	go func() {
+		slog.Info("Serving HTTP", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Server stopped", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	shutdown(checker, cfg, server, metricsServer)
+}
+
+/*
+Вне development gin работает в режиме release и не пишет служебных сообщений,
+в development маршруты и предупреждения gin идут через общий логгер на уровне debug, а не строками [GIN-debug] в stdout
+*/
+func configureGin(appEnv string) {
+	if appEnv != "development" {
+		gin.SetMode(gin.ReleaseMode)
+		return
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("Route registered", "method", method, "path", path, "handler", handler, "handlers", handlers)
+	}
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)), "component", "gin")
+	}
+}
+
+/*
+Плавная остановка по SIGINT или SIGTERM: сначала /readyz начинает отвечать 503,
+через SHUTDOWN_DELAY серверы перестают принимать соединения и ждут начатые запросы до SHUTDOWN_TIMEOUT.
+Сервер метрик останавливается последним, чтобы Prometheus видел остановку. nil в servers пропускается.
+Повторный сигнал во время ожидания завершает процесс сразу
+*/
+func shutdown(checker *health.Checker, cfg *config.Config, servers ...*http.Server) {
+	slog.Info("Shutting down", "delay", cfg.ShutdownDelay.String(), "timeout", cfg.ShutdownTimeout.String())
+	checker.Shutdown()
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error("Failed to finish requests before shutdown", "addr", server.Addr, "error", err)
+		}
+	}
+}
+
+/*
+Подкоманды вместо запуска сервера: config print выводит итоговую конфигурацию со скрытыми секретами
+Конфигурация выводится до проверки, чтобы было видно, какие значения получились, а ошибки проверки
+сообщаются после вывода и завершают команду с ненулевым кодом
+*/
+func runCommand(args []string) error {
+	command := strings.Join(args, " ")
+	switch command {
+	case "config print":
+		cfg, err := config.Read()
+		if err != nil {
+			return err
+		}
+		if err := cfg.Print(os.Stdout); err != nil {
+			return err
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration:\n%w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown command %q, expected \"config print\"", command)
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
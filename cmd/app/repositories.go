@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/health"
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/CAATHARSIS/task-tracking/internal/ratelimit"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	board_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board"
+	board_task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board_task"
+	custom_field_repo "github.com/CAATHARSIS/task-tracking/internal/repository/custom_field"
+	idempotency_repo "github.com/CAATHARSIS/task-tracking/internal/repository/idempotency"
+	saved_view_repo "github.com/CAATHARSIS/task-tracking/internal/repository/saved_view"
+	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
+	task_series_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task_series"
+	time_entry_repo "github.com/CAATHARSIS/task-tracking/internal/repository/time_entry"
+	user_repo "github.com/CAATHARSIS/task-tracking/internal/repository/user"
+	"github.com/CAATHARSIS/task-tracking/internal/scheduler"
+	"github.com/CAATHARSIS/task-tracking/pkg/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// Репозитории хранилища, выбранного в DB_DRIVER
+type repositories struct {
+	boards       repository.BoardRepository
+	boardTasks   repository.BoardTaskRepository
+	tasks        repository.TaskRepository
+	users        repository.UserRepository
+	savedViews   repository.SavedViewRepository
+	taskSeries   repository.TaskSeriesRepository
+	timeEntries  repository.TimeEntryRepository
+	customFields repository.CustomFieldRepository
+	idempotency  repository.IdempotencyRepository
+	uow          repository.UnitOfWork
+}
+
+func openRepositories(cfg *config.Config) (*sql.DB, *repositories, error) {
+	switch cfg.DBDriver {
+	case config.DriverPostgres:
+		db, err := database.NewPostgresDB(cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("PostgreSQL connection error: %w", err)
+		}
+
+		return db, &repositories{
+			boards:       board_repo.NewBoardPostgresRepo(db, cfg.DBQueryTimeout),
+			boardTasks:   board_task_repo.NewBoardTaskPostgresRepo(db, cfg.DBQueryTimeout),
+			tasks:        task_repo.NewTaskPostgresRepo(db, cfg.DBQueryTimeout),
+			users:        user_repo.NewUserPostgresRepo(db, cfg.DBQueryTimeout),
+			savedViews:   saved_view_repo.NewSavedViewPostgresRepo(db, cfg.DBQueryTimeout),
+			taskSeries:   task_series_repo.NewTaskSeriesPostgresRepo(db, cfg.DBQueryTimeout),
+			timeEntries:  time_entry_repo.NewTimeEntryPostgresRepo(db, cfg.DBQueryTimeout),
+			customFields: custom_field_repo.NewCustomFieldPostgresRepo(db, cfg.DBQueryTimeout),
+			idempotency:  idempotency_repo.NewIdempotencyPostgresRepo(db, cfg.DBQueryTimeout),
+			uow:          repository.NewDB(db),
+		}, nil
+
+	case config.DriverSQLite:
+		db, err := database.NewSQLiteDB(cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("SQLite connection error: %w", err)
+		}
+
+		return db, &repositories{
+			boards:       board_repo.NewBoardSQLiteRepo(db, cfg.DBQueryTimeout),
+			boardTasks:   board_task_repo.NewBoardTaskSQLiteRepo(db, cfg.DBQueryTimeout),
+			tasks:        task_repo.NewTaskSQLiteRepo(db, cfg.DBQueryTimeout),
+			users:        user_repo.NewUserSQLiteRepo(db, cfg.DBQueryTimeout),
+			savedViews:   saved_view_repo.NewSavedViewSQLiteRepo(db, cfg.DBQueryTimeout),
+			taskSeries:   task_series_repo.NewTaskSeriesSQLiteRepo(db, cfg.DBQueryTimeout),
+			timeEntries:  time_entry_repo.NewTimeEntrySQLiteRepo(db, cfg.DBQueryTimeout),
+			customFields: custom_field_repo.NewCustomFieldSQLiteRepo(db, cfg.DBQueryTimeout),
+			idempotency:  idempotency_repo.NewIdempotencySQLiteRepo(db, cfg.DBQueryTimeout),
+			uow:          repository.NewDB(db),
+		}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown DB_DRIVER %q, expected %q or %q", cfg.DBDriver, config.DriverPostgres, config.DriverSQLite)
+}
+
+// Подключение к Redis, nil - Redis недоступен и его хранилища заменяются запасными
+func openRedis(cfg *config.Config) *redis.Client {
+	client, err := database.NewRedisClient(cfg)
+	if err != nil {
+		slog.Warn("Redis is unavailable", "error", err)
+		return nil
+	}
+	return client
+}
+
+/*
+Ключи идемпотентности хранятся в Redis, если он доступен, иначе в основной базе
+Истёкшие ключи в базе удаляет фоновая очистка, в Redis они истекают сами
+*/
+func openIdempotencyKeys(cfg *config.Config, repos *repositories, client *redis.Client) repository.IdempotencyRepository {
+	if client == nil {
+		slog.Info("Idempotency keys are stored in the database")
+
+		cleaner := scheduler.NewIdempotencyCleaner(repos.idempotency, cfg.IdempotencyCleanupInterval)
+		go cleaner.Start(context.Background())
+		return repos.idempotency
+	}
+
+	return idempotency_repo.NewIdempotencyRedisRepo(client, cfg.DBQueryTimeout)
+}
+
+/*
+Кэш списков задач, nil - кэш отключён
+Без Redis кэш не включается: сброс в памяти одного экземпляра не дошёл бы до остальных,
+и они отдавали бы устаревшие списки до истечения CACHE_TTL
+*/
+func openCache(cfg *config.Config, client *redis.Client) *cache.Cache {
+	if cfg.CacheTTL <= 0 {
+		return nil
+	}
+	if client == nil {
+		slog.Warn("Task list cache is disabled: it requires Redis")
+		return nil
+	}
+
+	return cache.New(cache.NewRedisStore(client), cfg.CacheTTL, cfg.DBQueryTimeout)
+}
+
+// Подменяет репозитории обёртками, которые сбрасывают списки в кэше при изменениях
+func (r *repositories) invalidate(c *cache.Cache) {
+	wrapped := cache.Invalidating(c, cache.Repositories{
+		Tasks:        r.tasks,
+		Boards:       r.boards,
+		BoardTasks:   r.boardTasks,
+		CustomFields: r.customFields,
+		Users:        r.users,
+		TaskSeries:   r.taskSeries,
+	})
+
+	r.tasks = wrapped.Tasks
+	r.boards = wrapped.Boards
+	r.boardTasks = wrapped.BoardTasks
+	r.customFields = wrapped.CustomFields
+	r.users = wrapped.Users
+	r.taskSeries = wrapped.TaskSeries
+}
+
+// Без Redis счётчики хранятся в памяти, и каждый экземпляр приложения ограничивает запросы сам
+func openRateLimitStore(cfg *config.Config, client *redis.Client) ratelimit.Store {
+	if client == nil {
+		slog.Info("Rate limits are counted in memory of this instance")
+		return ratelimit.NewMemoryStore()
+	}
+
+	return ratelimit.NewRedisStore(client, cfg.DBQueryTimeout)
+}
+
+/*
+Регистрирует метрики пула соединений и кэша и возвращает обработчик /metrics для основного роутера
+Если задан METRICS_ADDR, метрики отдаёт отдельный сервер: основной роутер получает nil, а сервер
+возвращается для плавной остановки. Занятый адрес - ошибка запуска, а не запись в журнале
+*/
+func openMetrics(cfg *config.Config, db *sql.DB, taskCache *cache.Cache) (http.Handler, *http.Server, error) {
+	if err := metrics.RegisterDB(db, cfg.DBDriver); err != nil {
+		slog.Error("Failed to register database metrics", "error", err)
+	}
+	if taskCache != nil {
+		if err := metrics.RegisterCache(taskCache); err != nil {
+			slog.Error("Failed to register cache metrics", "error", err)
+		}
+	}
+
+	handler := metrics.Handler(cfg.MetricsUsername, cfg.MetricsPassword)
+	if cfg.MetricsAddr == "" {
+		return handler, nil, nil
+	}
+
+	listener, err := net.Listen("tcp", cfg.MetricsAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	server := &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
+	go func() {
+		slog.Info("Serving metrics", "addr", cfg.MetricsAddr)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Metrics server stopped", err)
+		}
+	}()
+	return nil, server, nil
+}
+
+/*
+Проверки готовности для /readyz: база данных, Redis, если он подключён, и актуальность миграций
+Без доступных файлов миграций их проверка пропускается, чтобы не держать приложение неготовым
+*/
+func openHealthChecks(cfg *config.Config, db *sql.DB, client *redis.Client) *health.Checker {
+	checker := health.NewChecker(cfg.HealthCheckTimeout)
+	checker.Add("database", health.Database(db))
+	if client != nil {
+		checker.Add("redis", health.Redis(client))
+	}
+
+	sourceURL := cfg.MigrationsPath
+	if cfg.DBDriver == config.DriverSQLite {
+		sourceURL = cfg.SQLiteMigrationsPath
+	}
+	migrations, err := health.Migrations(db, sourceURL)
+	if err != nil {
+		slog.Warn("Migrations are not checked for readiness", "error", err)
+		return checker
+	}
+	checker.Add("migrations", migrations)
+	return checker
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/CAATHARSIS/task-tracking/internal/openapi"
+)
+
+// Генерирует docs/openapi.json по аннотациям обработчиков: go generate ./docs
+func main() {
+	root := flag.String("root", ".", "корень модуля")
+	out := flag.String("out", "docs/openapi.json", "файл для записи документа")
+	flag.Parse()
+
+	spec, err := openapi.Generate(*root)
+	if err != nil {
+		log.Fatalf("Failed to generate OpenAPI document: %v", err)
+	}
+
+	if err := os.WriteFile(*out, spec, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
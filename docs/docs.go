@@ -0,0 +1,33 @@
+// OpenAPI документ JSON API и страница Swagger UI
+package docs
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:generate go run ../cmd/openapi -root .. -out openapi.json
+
+// Сгенерирован из аннотаций обработчиков internal/handlers/api, вручную не редактируется
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+//go:embed swagger.html
+var SwaggerUI []byte
+
+/*
+Скрипт и стили Swagger UI из swagger-ui-dist (версия в swagger-ui/NOTICE)
+Хранятся в репозитории и встраиваются в бинарный файл, чтобы документация открывалась без доступа к CDN
+*/
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var swaggerAssets embed.FS
+
+// Файлы Swagger UI по именам без каталога
+func SwaggerAssets() fs.FS {
+	assets, err := fs.Sub(swaggerAssets, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}
@@ -0,0 +1,19 @@
+package docs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/CAATHARSIS/task-tracking/internal/openapi"
+)
+
+func TestOpenAPIUpToDate(t *testing.T) {
+	spec, err := openapi.Generate("..")
+	if err != nil {
+		t.Fatalf("failed to generate OpenAPI document: %v", err)
+	}
+
+	if !bytes.Equal(spec, OpenAPI) {
+		t.Fatal("docs/openapi.json is outdated, run go generate ./docs")
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Ошибки уровня приложения и их представление в ответах API
+
+Обработчики передают ошибки через c.Error, middleware.Errors превращает их в единый ответ:
+{"error": {"code": ..., "message": ..., "details": [...], "request_id": ...}}
+*/
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/go-playground/validator/v10"
+)
+
+type Code string
+
+const (
+	CodeBadRequest   Code = "bad_request"
+	CodeValidation   Code = "validation_failed"
+	CodeUnauthorized Code = "unauthorized"
+	CodeForbidden    Code = "forbidden"
+	CodeNotFound     Code = "not_found"
+	CodeConflict     Code = "conflict"
+	// Версия в If-Match не совпала с текущей, запись изменил кто-то другой
+	CodePreconditionFailed Code = "precondition_failed"
+	// Изменение записи без If-Match
+	CodePreconditionRequired Code = "precondition_required"
+	// Ключ идемпотентности уже использован для другого запроса
+	CodeIdempotencyKeyReused Code = "idempotency_key_reused"
+	// Тело запроса больше допустимого
+	CodePayloadTooLarge Code = "payload_too_large"
+	// Превышен лимит частоты запросов, повтор возможен через Retry-After секунд
+	CodeTooManyRequests Code = "too_many_requests"
+	CodeInternal        Code = "internal_error"
+	CodeTimeout         Code = "timeout"
+	CodeCanceled        Code = "canceled"
+)
+
+// Нестандартный статус nginx: клиент закрыл соединение до получения ответа
+const StatusClientClosedRequest = 499
+
+var statuses = map[Code]int{
+	CodeBadRequest:           http.StatusBadRequest,
+	CodeValidation:           http.StatusBadRequest,
+	CodeUnauthorized:         http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeNotFound:             http.StatusNotFound,
+	CodeConflict:             http.StatusConflict,
+	CodePreconditionFailed:   http.StatusPreconditionFailed,
+	CodePreconditionRequired: http.StatusPreconditionRequired,
+	CodeIdempotencyKeyReused: http.StatusUnprocessableEntity,
+	CodePayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	CodeTooManyRequests:      http.StatusTooManyRequests,
+	CodeInternal:             http.StatusInternalServerError,
+	CodeTimeout:              http.StatusGatewayTimeout,
+	CodeCanceled:             StatusClientClosedRequest,
+}
+
+// Ошибка проверки отдельного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	// Исходная ошибка, в ответ не попадает
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+func PreconditionFailed(message string) *Error {
+	return New(CodePreconditionFailed, message)
+}
+
+func PreconditionRequired(message string) *Error {
+	return New(CodePreconditionRequired, message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(CodeTooManyRequests, message)
+}
+
+// Ошибка проверки запроса, ошибки validator раскладываются по полям
+func Validation(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return &Error{Code: CodeValidation, Message: err.Error()}
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+
+	return InvalidFields(fields)
+}
+
+// Ошибка проверки запроса с уже собранными ошибками полей
+func InvalidFields(fields []FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: "Request validation failed", Fields: fields}
+}
+
+/*
+Приводит произвольную ошибку к *Error
+Ошибки репозиториев ErrNotFound и ErrConflict сохраняют свой текст, ErrStale становится precondition_failed,
+таймаут запроса к БД и отмена запроса клиентом получают отдельные коды, остальные становятся internal_error
+*/
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: err.Error(), Err: err}
+	case errors.Is(err, repository.ErrConflict):
+		return &Error{Code: CodeConflict, Message: err.Error(), Err: err}
+	case errors.Is(err, repository.ErrStale):
+		return &Error{Code: CodePreconditionFailed, Message: err.Error(), Err: err}
+	case errors.Is(err, repository.ErrTimeout):
+		return &Error{Code: CodeTimeout, Message: "Request timed out", Err: err}
+	case errors.Is(err, repository.ErrCanceled):
+		return &Error{Code: CodeCanceled, Message: "Request canceled", Err: err}
+	}
+
+	return &Error{Code: CodeInternal, Message: "Internal server error", Err: err}
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fe.Field() + " is required"
+	case "email":
+		return fe.Field() + " must be a valid email"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s%s", fe.Field(), fe.Param(), lengthUnit(fe))
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s%s", fe.Field(), fe.Param(), lengthUnit(fe))
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("%s failed %s=%s check", fe.Field(), fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("%s failed %s check", fe.Field(), fe.Tag())
+}
+
+func lengthUnit(fe validator.FieldError) string {
+	switch fe.Kind().String() {
+	case "string":
+		return " characters"
+	case "slice", "map", "array":
+		return " items"
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package apperr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+func TestFrom(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		err    error
+		code   apperr.Code
+		status int
+	}{
+		{"not found", fmt.Errorf("task 1: %w", repository.ErrNotFound), apperr.CodeNotFound, http.StatusNotFound},
+		{"conflict", repository.ErrConflict, apperr.CodeConflict, http.StatusConflict},
+		{"timeout", &repository.TimeoutError{Err: context.DeadlineExceeded}, apperr.CodeTimeout, http.StatusGatewayTimeout},
+		{"canceled", &repository.CanceledError{Err: context.Canceled}, apperr.CodeCanceled, apperr.StatusClientClosedRequest},
+		{"unexpected", errors.New("connection reset"), apperr.CodeInternal, http.StatusInternalServerError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := apperr.From(tt.err)
+			if err.Code != tt.code || err.Status() != tt.status {
+				t.Errorf("expected %s %d, got %s %d", tt.code, tt.status, err.Code, err.Status())
+			}
+		})
+	}
+}
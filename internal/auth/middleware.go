@@ -4,18 +4,28 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
 	"github.com/gin-gonic/gin"
 )
 
+/*
+Проверяет JWT и кладёт в контекст user_id
+Веб-страницы берут токен из cookie и при ошибке перенаправляют на /login,
+API принимает также заголовок Authorization: Bearer и отвечает 401 в формате ошибок API
+*/
 func (s *JWTService) JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Пропускаем статические файлы и API
-		if strings.HasPrefix(c.Request.URL.Path, "/static") ||
-			strings.HasPrefix(c.Request.URL.Path, "/api") {
+		// Пропускаем статические файлы
+		if strings.HasPrefix(c.Request.URL.Path, "/static") {
 			c.Next()
 			return
 		}
 
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			s.authenticateAPI(c)
+			return
+		}
+
 		// Публичные маршруты
 		publicRoutes := []string{"/", "/login", "/register"}
 		for _, route := range publicRoutes {
@@ -46,6 +56,29 @@ func (s *JWTService) JWTAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+func (s *JWTService) authenticateAPI(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		token, _ = c.Cookie("auth_token")
+	}
+
+	if token == "" {
+		c.Error(apperr.Unauthorized("Authentication required"))
+		c.Abort()
+		return
+	}
+
+	claims, err := s.ParseToken(token)
+	if err != nil {
+		c.Error(apperr.Unauthorized("Invalid or expired token"))
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Next()
+}
+
 func MethodOverride() gin.HandlerFunc {
     return func(c *gin.Context) {
         if c.Request.Method == "POST" {
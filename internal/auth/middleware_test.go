@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/auth"
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter(jwtService *auth.JWTService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(middleware.Errors())
+	protected := r.Group("")
+	protected.Use(jwtService.JWTAuthMiddleware())
+	protected.GET("/api/tasks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.MustGet("user_id").(int)})
+	})
+	protected.GET("/tasks", func(c *gin.Context) {
+		c.String(http.StatusOK, "tasks page")
+	})
+	return r
+}
+
+func TestJWTAuthMiddlewareAPI(t *testing.T) {
+	jwtService := auth.NewJWTService(&config.Config{JWTSecret: "test-secret", JWTExpiration: time.Hour})
+	r := newRouter(jwtService)
+
+	token, err := jwtService.GenerateJWT(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		cookie string
+		status int
+		body   string
+	}{
+		{name: "no token", status: http.StatusUnauthorized, body: `{"error":{"code":"unauthorized","message":"Authentication required"}}`},
+		{name: "invalid token", header: "Bearer garbage", status: http.StatusUnauthorized, body: `{"error":{"code":"unauthorized","message":"Invalid or expired token"}}`},
+		{name: "bearer token", header: "Bearer " + token, status: http.StatusOK, body: `{"user_id":42}`},
+		{name: "cookie", cookie: token, status: http.StatusOK, body: `{"user_id":42}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "auth_token", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status || rec.Body.String() != tt.body {
+				t.Errorf("expected %d %s, got %d %s", tt.status, tt.body, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJWTAuthMiddlewareWebRedirects(t *testing.T) {
+	r := newRouter(auth.NewJWTService(&config.Config{JWTSecret: "test-secret", JWTExpiration: time.Hour}))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/login" {
+		t.Errorf("expected redirect to /login, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
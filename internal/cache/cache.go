@@ -0,0 +1,144 @@
+/*
+Кэш списков задач для страниц досок и списков задач пользователя
+
+Значения читаются сквозь кэш: при промахе список загружается из репозиториев и сохраняется в JSON на срок ttl.
+Списки сбрасывают репозитории из Invalidating после каждого изменения задач, досок и их связей,
+поэтому срок хранения только страхует от гонки чтения с изменением
+*/
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Виды кэшируемых списков
+const (
+	// Задачи доски со значениями пользовательских полей
+	BoardTasks = "board_tasks"
+	// Задачи пользователя со значениями пользовательских полей
+	UserTasks = "user_tasks"
+)
+
+var kinds = []string{BoardTasks, UserTasks}
+
+// Хранилище значений кэша
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type Cache struct {
+	store Store
+	ttl   time.Duration
+	// Ограничение на одно обращение к хранилищу
+	timeout time.Duration
+	stats   map[string]*counters
+}
+
+type counters struct {
+	hits, misses atomic.Uint64
+}
+
+// Попадания и промахи одного вида списков с запуска приложения
+type Counters struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+func New(store Store, ttl, timeout time.Duration) *Cache {
+	c := &Cache{store: store, ttl: ttl, timeout: timeout, stats: make(map[string]*counters, len(kinds))}
+	for _, kind := range kinds {
+		c.stats[kind] = &counters{}
+	}
+	return c
+}
+
+func Key(kind string, id int) string {
+	return "cache:" + kind + ":" + strconv.Itoa(id)
+}
+
+/*
+Возвращает список вида kind с идентификатором id из кэша или загружает его через load
+Без кэша (c == nil) просто вызывает load. Сбой хранилища не мешает ответу: значение загружается заново
+*/
+func Load[T any](ctx context.Context, c *Cache, kind string, id int, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+
+	key := Key(kind, id)
+	if raw, ok := c.get(ctx, key); ok {
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			c.stats[kind].hits.Add(1)
+			return value, nil
+		}
+		slog.WarnContext(ctx, "cache: failed to decode value", "key", key)
+	}
+	c.stats[kind].misses.Add(1)
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.set(ctx, key, value)
+	return value, nil
+}
+
+func (c *Cache) get(ctx context.Context, key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	raw, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "cache: failed to read value", "key", key, "error", err)
+	}
+	return raw, ok
+}
+
+func (c *Cache) set(ctx context.Context, key string, value any) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	raw, err := json.Marshal(value)
+	if err == nil {
+		err = c.store.Set(ctx, key, raw, c.ttl)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "cache: failed to store value", "key", key, "error", err)
+	}
+}
+
+// Сбрасывает ключи после фиксации единицы работы, в которой вызван, или сразу вне её
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	repository.AfterCommit(ctx, func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+		defer cancel()
+		if err := c.store.Delete(ctx, keys...); err != nil {
+			slog.ErrorContext(ctx, "cache: failed to invalidate keys", "keys", keys, "error", err)
+		}
+	})
+}
+
+// Счётчики попаданий и промахов по видам списков, без кэша - пустые
+func (c *Cache) Stats() map[string]Counters {
+	stats := make(map[string]Counters, len(kinds))
+	if c == nil {
+		return stats
+	}
+	for kind, counters := range c.stats {
+		stats[kind] = Counters{Hits: counters.hits.Load(), Misses: counters.misses.Load()}
+	}
+	return stats
+}
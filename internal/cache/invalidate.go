@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Репозитории, через которые меняется содержимое кэшируемых списков
+type Repositories struct {
+	Tasks        repository.TaskRepository
+	Boards       repository.BoardRepository
+	BoardTasks   repository.BoardTaskRepository
+	CustomFields repository.CustomFieldRepository
+	Users        repository.UserRepository
+	TaskSeries   repository.TaskSeriesRepository
+}
+
+/*
+Оборачивает репозитории так, что каждое успешное изменение сбрасывает затронутые списки
+Затронутые списки определяются до изменения: после удаления задачи уже не узнать, на каких досках она была.
+Сброс выполняется после фиксации единицы работы, чтобы параллельный запрос не вернул в кэш старые данные
+*/
+func Invalidating(c *Cache, repos Repositories) Repositories {
+	inv := &invalidator{cache: c, repos: repos}
+	return Repositories{
+		Tasks:        &taskRepo{TaskRepository: repos.Tasks, inv: inv},
+		Boards:       &boardRepo{BoardRepository: repos.Boards, inv: inv},
+		BoardTasks:   &boardTaskRepo{BoardTaskRepository: repos.BoardTasks, inv: inv},
+		CustomFields: &customFieldRepo{CustomFieldRepository: repos.CustomFields, inv: inv},
+		Users:        &userRepo{UserRepository: repos.Users, inv: inv},
+		TaskSeries:   &taskSeriesRepo{TaskSeriesRepository: repos.TaskSeries, inv: inv},
+	}
+}
+
+type invalidator struct {
+	cache *Cache
+	// Исходные репозитории для поиска затронутых списков
+	repos Repositories
+}
+
+// Список владельца задачи и списки досок, на которых она лежит
+func (i *invalidator) taskKeys(ctx context.Context, task *models.Task) ([]string, error) {
+	boardIDs, err := i.repos.BoardTasks.GetBoards(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{Key(UserTasks, task.UserID)}
+	for _, boardID := range boardIDs {
+		keys = append(keys, Key(BoardTasks, boardID))
+	}
+	return keys, nil
+}
+
+func (i *invalidator) taskKeysByID(ctx context.Context, taskID int) ([]string, error) {
+	task, err := i.repos.Tasks.GetById(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return i.taskKeys(ctx, task)
+}
+
+// Список доски и списки владельцев её задач: при удалении доски или поля у задач пропадают значения полей
+func (i *invalidator) boardKeys(ctx context.Context, boardID int) ([]string, error) {
+	tasks, err := i.repos.BoardTasks.ListTasks(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{Key(BoardTasks, boardID)}
+	owners := make(map[int]bool)
+	for _, task := range tasks {
+		if !owners[task.UserID] {
+			owners[task.UserID] = true
+			keys = append(keys, Key(UserTasks, task.UserID))
+		}
+	}
+	return keys, nil
+}
+
+// Выполняет изменение и после успеха сбрасывает ключи, найденные до него
+func (i *invalidator) change(ctx context.Context, keys []string, err error, fn func() error) error {
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	i.cache.Invalidate(ctx, keys...)
+	return nil
+}
+
+type taskRepo struct {
+	repository.TaskRepository
+	inv *invalidator
+}
+
+// Новая задача ещё не лежит ни на одной доске
+func (r *taskRepo) Create(ctx context.Context, task *models.Task) error {
+	return r.inv.change(ctx, []string{Key(UserTasks, task.UserID)}, nil, func() error {
+		return r.TaskRepository.Create(ctx, task)
+	})
+}
+
+func (r *taskRepo) Update(ctx context.Context, task *models.Task) error {
+	keys, err := r.inv.taskKeys(ctx, task)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.TaskRepository.Update(ctx, task)
+	})
+}
+
+func (r *taskRepo) Delete(ctx context.Context, id, version int) error {
+	keys, err := r.inv.taskKeysByID(ctx, id)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.TaskRepository.Delete(ctx, id, version)
+	})
+}
+
+type boardRepo struct {
+	repository.BoardRepository
+	inv *invalidator
+}
+
+func (r *boardRepo) Delete(ctx context.Context, id, version int) error {
+	keys, err := r.inv.boardKeys(ctx, id)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.BoardRepository.Delete(ctx, id, version)
+	})
+}
+
+type boardTaskRepo struct {
+	repository.BoardTaskRepository
+	inv *invalidator
+}
+
+func (r *boardTaskRepo) AddTask(ctx context.Context, boardID, taskID int) error {
+	return r.inv.change(ctx, []string{Key(BoardTasks, boardID)}, nil, func() error {
+		return r.BoardTaskRepository.AddTask(ctx, boardID, taskID)
+	})
+}
+
+func (r *boardTaskRepo) RemoveTask(ctx context.Context, boardID, taskID int) error {
+	return r.inv.change(ctx, []string{Key(BoardTasks, boardID)}, nil, func() error {
+		return r.BoardTaskRepository.RemoveTask(ctx, boardID, taskID)
+	})
+}
+
+func (r *boardTaskRepo) MoveTask(ctx context.Context, fromBoardID, toBoardID, taskID int) error {
+	keys := []string{Key(BoardTasks, fromBoardID), Key(BoardTasks, toBoardID)}
+	return r.inv.change(ctx, keys, nil, func() error {
+		return r.BoardTaskRepository.MoveTask(ctx, fromBoardID, toBoardID, taskID)
+	})
+}
+
+type customFieldRepo struct {
+	repository.CustomFieldRepository
+	inv *invalidator
+}
+
+// Значения полей в списках задач содержат имя и тип поля
+func (r *customFieldRepo) Update(ctx context.Context, field *models.CustomField) error {
+	keys, err := r.fieldKeys(ctx, field.ID)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.CustomFieldRepository.Update(ctx, field)
+	})
+}
+
+func (r *customFieldRepo) Delete(ctx context.Context, id int) error {
+	keys, err := r.fieldKeys(ctx, id)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.CustomFieldRepository.Delete(ctx, id)
+	})
+}
+
+func (r *customFieldRepo) fieldKeys(ctx context.Context, id int) ([]string, error) {
+	field, err := r.CustomFieldRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.inv.boardKeys(ctx, field.BoardID)
+}
+
+func (r *customFieldRepo) SetValues(ctx context.Context, taskID int, values map[int]json.RawMessage) error {
+	keys, err := r.inv.taskKeysByID(ctx, taskID)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.CustomFieldRepository.SetValues(ctx, taskID, values)
+	})
+}
+
+type userRepo struct {
+	repository.UserRepository
+	inv *invalidator
+}
+
+// Вместе с пользователем удаляются его задачи и доски
+func (r *userRepo) Delete(ctx context.Context, id int) error {
+	keys, err := r.userKeys(ctx, id)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.UserRepository.Delete(ctx, id)
+	})
+}
+
+func (r *userRepo) userKeys(ctx context.Context, id int) ([]string, error) {
+	tasks, err := r.inv.repos.Tasks.ListByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	boards, err := r.inv.repos.Boards.ListByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{Key(UserTasks, id)}
+	for _, task := range tasks {
+		taskKeys, err := r.inv.taskKeys(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, taskKeys...)
+	}
+	for _, board := range boards {
+		boardKeys, err := r.inv.boardKeys(ctx, board.ID)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, boardKeys...)
+	}
+	return keys, nil
+}
+
+type taskSeriesRepo struct {
+	repository.TaskSeriesRepository
+	inv *invalidator
+}
+
+func (r *taskSeriesRepo) AttachTask(ctx context.Context, seriesID, taskID int) error {
+	keys, err := r.inv.taskKeysByID(ctx, taskID)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.TaskSeriesRepository.AttachTask(ctx, seriesID, taskID)
+	})
+}
+
+// Изменение серии переписывает название и описание незавершённых экземпляров
+func (r *taskSeriesRepo) Update(ctx context.Context, series *models.TaskSeries) error {
+	keys, err := r.seriesKeys(ctx, series.ID)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.TaskSeriesRepository.Update(ctx, series)
+	})
+}
+
+// Удаление серии отвязывает от неё экземпляры
+func (r *taskSeriesRepo) Delete(ctx context.Context, id int) error {
+	keys, err := r.seriesKeys(ctx, id)
+	return r.inv.change(ctx, keys, err, func() error {
+		return r.TaskSeriesRepository.Delete(ctx, id)
+	})
+}
+
+func (r *taskSeriesRepo) seriesKeys(ctx context.Context, id int) ([]string, error) {
+	series, err := r.TaskSeriesRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := r.inv.repos.Tasks.ListByUser(ctx, series.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{Key(UserTasks, series.UserID)}
+	for _, task := range tasks {
+		if task.SeriesID == nil || *task.SeriesID != id {
+			continue
+		}
+		taskKeys, err := r.inv.taskKeys(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, taskKeys...)
+	}
+	return keys, nil
+}
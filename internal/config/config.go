@@ -1,46 +1,148 @@
 package config
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
 	"time"
 
+	"github.com/CAATHARSIS/task-tracking/internal/ratelimit"
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 )
 
+// Поддерживаемые хранилища
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+/*
+Настройки приложения из переменных окружения
+Секреты (тег secret) можно передать файлом: переменная <ИМЯ>_FILE содержит путь к файлу со значением,
+как принято для Docker и Kubernetes secrets. В выводе config print их значения скрыты
+*/
 type Config struct {
 	// Общие настройки
 	AppEnv  string `envconfig:"APP_ENV" default:"development"`
 	AppPort string `envconfig:"APP_PORT" default:"8080"`
+	// Адреса и подсети обратных прокси через запятую, которым можно верить в X-Forwarded-For и X-Real-IP.
+	// Пусто - адрес клиента берётся из соединения, а эти заголовки игнорируются
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES" default:""`
+
+	// Плавная остановка: сколько /readyz отвечает 503 до закрытия порта, чтобы балансировщик убрал экземпляр,
+	// и сколько ждать завершения начатых запросов
+	ShutdownDelay   time.Duration `envconfig:"SHUTDOWN_DELAY" default:"5s"`
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"15s"`
+	// Таймаут каждой проверки зависимостей в /readyz
+	HealthCheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+
+	// Журнал: уровень debug, info, warn или error и формат json или text
+	LogLevel  slog.Level `envconfig:"LOG_LEVEL" default:"info"`
+	LogFormat string     `envconfig:"LOG_FORMAT" default:"json"`
 
-	// Настройки базы данных
+	// Метрики Prometheus на /metrics: отдельный адрес (например, ":9090") убирает их с основного порта,
+	// заданный логин включает basic auth
+	MetricsAddr     string `envconfig:"METRICS_ADDR" default:""`
+	MetricsUsername string `envconfig:"METRICS_USERNAME" default:""`
+	MetricsPassword string `envconfig:"METRICS_PASSWORD" default:"" secret:"true"`
+
+	// Трассировка OpenTelemetry: экспортёр none, otlp, stderr или file
+	TracingExporter string `envconfig:"TRACING_EXPORTER" default:"none"`
+	// Адрес OTLP/HTTP, например http://localhost:4318; без него используются переменные OTEL_EXPORTER_OTLP_*
+	OTLPEndpoint string `envconfig:"OTLP_ENDPOINT" default:""`
+	// Файл спанов для экспортёра file
+	TracingFile string `envconfig:"TRACING_FILE" default:"traces.json"`
+	// Доля новых трасс, которые записываются, от 0 до 1
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+
+	// Настройки базы данных: postgres или sqlite
+	DBDriver   string `envconfig:"DB_DRIVER" default:"postgres"`
 	DBHost     string `envconfig:"DB_HOST" default:"localhost"`
 	DBPort     string `envconfig:"DB_PORT" default:"5432"`
 	DBUser     string `envconfig:"DB_USER" default:"postgres"`
-	DBPassword string `envconfig:"DB_PASSWORD" default:"postgres"`
+	DBPassword string `envconfig:"DB_PASSWORD" default:"postgres" secret:"true"`
 	DBName     string `envconfig:"DB_NAME" default:"task-tracking"`
 	DBSSLMode  string `envconfig:"DBSSLMODE" default:"disable"`
+	// Полная строка подключения к PostgreSQL (postgres://... или key=value), заменяет поля DB_*
+	DatabaseURL string `envconfig:"DATABASE_URL" default:"" secret:"true"`
+	// Пул соединений PostgreSQL, 0 снимает ограничение числа открытых соединений и срока жизни;
+	// SQLite всегда работает через одно соединение
+	DBMaxOpenConns    int           `envconfig:"DB_MAX_OPEN_CONNS" default:"25"`
+	DBMaxIdleConns    int           `envconfig:"DB_MAX_IDLE_CONNS" default:"10"`
+	DBConnMaxLifetime time.Duration `envconfig:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	// Сколько ждать базу при запуске, повторяя подключение; 0 - одна попытка
+	DBConnectTimeout time.Duration `envconfig:"DB_CONNECT_TIMEOUT" default:"30s"`
+	// Файл базы SQLite
+	DBPath string `envconfig:"DB_PATH" default:"task-tracking.db"`
+	// Максимальное время выполнения одного метода репозитория
+	DBQueryTimeout time.Duration `envconfig:"DB_QUERY_TIMEOUT" default:"5s"`
 
 	// Настройки Redis
-	RedisHost     string `envcong:"REDIS_HOST" default:"localhost"`
+	RedisHost     string `envconfig:"REDIS_HOST" default:"localhost"`
 	RedisPort     string `envconfig:"REDIS_PORT" default:"6379"`
-	RedisPassword string `envconfig:"REDIS_PASSWORD" default:""`
+	RedisPassword string `envconfig:"REDIS_PASSWORD" default:"" secret:"true"`
 	RedisDB       string `envconfig:"REDIS_DB" default:"0"`
 
+	// Срок хранения списков задач в кэше Redis, 0 отключает кэш; без Redis кэш не используется
+	CacheTTL time.Duration `envconfig:"CACHE_TTL" default:"5m"`
+
+	// Срок хранения ключей идемпотентности и ответов на запросы с ними
+	IdempotencyTTL time.Duration `envconfig:"IDEMPOTENCY_TTL" default:"24h"`
+	// Срок резерва ключа, пока запрос выполняется; должен быть больше времени ответа самого долгого запроса
+	IdempotencyLease time.Duration `envconfig:"IDEMPOTENCY_LEASE" default:"1m"`
+	// Максимальный размер тела запроса с ключом идемпотентности в байтах
+	IdempotencyMaxBodySize int64 `envconfig:"IDEMPOTENCY_MAX_BODY_SIZE" default:"1048576"`
+
+	// Ограничение частоты запросов в формате "лимит/окно", лимит 0 снимает ограничение
+	// Вход и регистрация с одного IP-адреса
+	RateLimitAuthIP ratelimit.Rule `envconfig:"RATE_LIMIT_AUTH_IP" default:"20/1m"`
+	// Попытки входа в одну учётную запись
+	RateLimitLoginEmail ratelimit.Rule `envconfig:"RATE_LIMIT_LOGIN_EMAIL" default:"5/15m"`
+	// Запросы пользователя к API
+	RateLimitAPIUser ratelimit.Rule `envconfig:"RATE_LIMIT_API_USER" default:"300/1m"`
+
 	// Настройки JWT
-	JWTSecret     string        `envconfig:"JWT_SECRET" required:"true"`
+	JWTSecret     string        `envconfig:"JWT_SECRET" default:"" secret:"true"`
 	JWTExpiration time.Duration `envconfig:"JWT_EXPIRATION" default:"24h"`
 
+	// Настройки фоновых задач
+	RecurrenceCheckInterval time.Duration `envconfig:"RECURRENCE_CHECK_INTERVAL" default:"1m"`
+	// Очистка истёкших ключей идемпотентности в базе, в Redis они истекают сами
+	IdempotencyCleanupInterval time.Duration `envconfig:"IDEMPOTENCY_CLEANUP_INTERVAL" default:"1h"`
+
 	// Настройки миграций
-	MigrationsPath string `envconfig:"MIGRATIONS_PATH" default:"file://migrations"`
+	MigrationsPath       string `envconfig:"MIGRATIONS_PATH" default:"file://migrations"`
+	SQLiteMigrationsPath string `envconfig:"SQLITE_MIGRATIONS_PATH" default:"file://migrations/sqlite"`
 }
 
+/*
+Читает настройки из окружения (в разработке - и из .env), подставляет секреты из файлов и проверяет результат
+Вызывается один раз при запуске, дальше конфигурация передаётся зависимостям
+*/
 func Load() (*Config, error) {
+	cfg, err := Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// Читает настройки как Load, но не проверяет их: config print показывает и конфигурацию с ошибками
+func Read() (*Config, error) {
 	if env := os.Getenv("APP_ENV"); env == "" || env == "development" {
 		_ = godotenv.Load()
 	}
 
 	var cfg Config
-	err := envconfig.Process("", &cfg)
-	return &cfg, err
+	if err := envconfig.Process("", &cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.readSecretFiles(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
 }
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var envName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Поле с опечаткой в теге (envcong:"REDIS_HOST") молча читалось бы из переменной с именем поля
+func TestFieldTags(t *testing.T) {
+	seen := map[string]string{}
+	typ := reflect.TypeOf(Config{})
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		name := f.Tag.Get("envconfig")
+		if !envName.MatchString(name) {
+			t.Errorf("field %s has invalid envconfig tag %q", f.Name, name)
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("fields %s and %s share variable %s", other, f.Name, name)
+		}
+		seen[name] = f.Name
+
+		if _, ok := f.Tag.Lookup("default"); !ok {
+			t.Errorf("field %s has no default tag", f.Name)
+		}
+		if f.Tag.Get("secret") == "true" && f.Type.Kind() != reflect.String {
+			t.Errorf("secret field %s must be a string", f.Name)
+		}
+	}
+}
+
+// Окружение теста без .env и переменных приложения из внешнего окружения
+func setenv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, f := range (&Config{}).fields() {
+		for _, name := range []string{f.name, f.name + "_FILE"} {
+			if _, ok := os.LookupEnv(name); ok {
+				// t.Setenv запоминает значение, чтобы вернуть его после теста
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+		}
+	}
+	t.Setenv("APP_ENV", "test")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+func writeSecret(t *testing.T, value string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(value), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	setenv(t, map[string]string{
+		"JWT_SECRET_FILE":  writeSecret(t, "jwt-from-file\n"),
+		"DB_PASSWORD_FILE": writeSecret(t, "p@ss word\r\n"),
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.JWTSecret != "jwt-from-file" || cfg.DBPassword != "p@ss word" {
+		t.Errorf("expected secrets from files without trailing newline, got %q and %q", cfg.JWTSecret, cfg.DBPassword)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "secret and file",
+			env:  map[string]string{"JWT_SECRET": "a", "JWT_SECRET_FILE": writeSecret(t, "b")},
+			want: []string{"JWT_SECRET and JWT_SECRET_FILE are both set"},
+		},
+		{
+			name: "missing file",
+			env:  map[string]string{"JWT_SECRET_FILE": filepath.Join(t.TempDir(), "missing")},
+			want: []string{"failed to read JWT_SECRET_FILE"},
+		},
+		{
+			name: "invalid values",
+			env: map[string]string{
+				"APP_PORT":                     "http",
+				"DB_DRIVER":                    "mysql",
+				"TRACING_SAMPLE_RATIO":         "2",
+				"METRICS_USERNAME":             "prometheus",
+				"REDIS_DB":                     "first",
+				"IDEMPOTENCY_CLEANUP_INTERVAL": "0s",
+				"TRUSTED_PROXIES":              "10.0.0.0/8,proxy.local",
+			},
+			want: []string{
+				`APP_PORT: must be a port number, got "http"`,
+				`DB_DRIVER: must be one of postgres, sqlite, got "mysql"`,
+				"TRACING_SAMPLE_RATIO: must be between 0 and 1, got 2",
+				"METRICS_PASSWORD: is required when METRICS_USERNAME is set",
+				`REDIS_DB: must be a database number, got "first"`,
+				"JWT_SECRET: is required, set JWT_SECRET or JWT_SECRET_FILE",
+				"IDEMPOTENCY_CLEANUP_INTERVAL: must be positive, got 0s",
+				`TRUSTED_PROXIES: must be IP addresses or CIDR subnets, got "proxy.local"`,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, tt.env)
+
+			_, err := Load()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected error to contain %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	setenv(t, map[string]string{
+		"JWT_SECRET":         "jwt-secret",
+		"DATABASE_URL":       "postgres://app:db-secret@db/tasks",
+		"REDIS_PASSWORD":     "",
+		"RATE_LIMIT_AUTH_IP": "10/30s",
+	})
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"jwt-secret", "db-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("expected %q to be redacted, got\n%s", secret, out.String())
+		}
+	}
+	for _, line := range []string{"JWT_SECRET=[REDACTED]\n", "DATABASE_URL=[REDACTED]\n", "REDIS_PASSWORD=\n", "RATE_LIMIT_AUTH_IP=10/30s\n", "DB_DRIVER=postgres\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected output to contain %q, got\n%s", line, out.String())
+		}
+	}
+}
+
+// Read не проверяет значения, поэтому config print может показать конфигурацию с ошибкой
+func TestReadSkipsValidation(t *testing.T) {
+	setenv(t, map[string]string{"APP_PORT": "http", "JWT_SECRET": "jwt-secret"})
+
+	cfg, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "APP_PORT=http\n") {
+		t.Errorf("expected invalid value in the output, got\n%s", out.String())
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `APP_PORT: must be a port number, got "http"`) {
+		t.Errorf("expected APP_PORT error, got %v", err)
+	}
+}
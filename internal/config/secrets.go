@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Замена значения секрета в выводе конфигурации
+const redacted = "[REDACTED]"
+
+// Поле конфигурации с именем его переменной окружения
+type field struct {
+	name   string
+	secret bool
+	value  reflect.Value
+}
+
+func (c *Config) fields() []field {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	fields := make([]field, 0, t.NumField())
+	for i := range t.NumField() {
+		fields = append(fields, field{
+			name:   t.Field(i).Tag.Get("envconfig"),
+			secret: t.Field(i).Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return fields
+}
+
+/*
+Подставляет секреты из файлов по переменным <ИМЯ>_FILE
+Завершающий перевод строки отбрасывается: его оставляют редакторы и echo.
+Одновременно заданные <ИМЯ> и <ИМЯ>_FILE - ошибка, чтобы не гадать, какое значение действует
+*/
+func (c *Config) readSecretFiles() error {
+	for _, f := range c.fields() {
+		if !f.secret {
+			continue
+		}
+		path := os.Getenv(f.name + "_FILE")
+		if path == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(f.name); ok {
+			return fmt.Errorf("%s and %s_FILE are both set, use only one of them", f.name, f.name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_FILE: %w", f.name, err)
+		}
+		f.value.SetString(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// Выводит итоговую конфигурацию строками ИМЯ=значение; заданные секреты заменяются на [REDACTED]
+func (c *Config) Print(w io.Writer) error {
+	for _, f := range c.fields() {
+		value := fmt.Sprint(f.value.Interface())
+		// Списки выводятся в том же виде, в каком задаются в окружении
+		if list, ok := f.value.Interface().([]string); ok {
+			value = strings.Join(list, ",")
+		}
+		if f.secret && value != "" {
+			value = redacted
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", f.name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
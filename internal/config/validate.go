@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Проверяет значения, которые envconfig не может проверить по типу
+Возвращает все найденные ошибки сразу, по одной строке "ИМЯ: причина" на переменную
+*/
+func (c *Config) Validate() error {
+	var v validator
+
+	v.port("APP_PORT", c.AppPort)
+	for _, proxy := range c.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		v.check(err == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES", "must be IP addresses or CIDR subnets, got %q", proxy)
+	}
+	v.oneOf("LOG_FORMAT", c.LogFormat, "json", "text")
+
+	v.check(c.MetricsUsername == "" || c.MetricsPassword != "", "METRICS_PASSWORD", "is required when METRICS_USERNAME is set")
+
+	v.oneOf("TRACING_EXPORTER", c.TracingExporter, "none", "otlp", "stderr", "file")
+	v.check(c.TracingExporter != "file" || c.TracingFile != "", "TRACING_FILE", "is required for the file exporter")
+	v.check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO", "must be between 0 and 1, got %v", c.TracingSampleRatio)
+
+	v.oneOf("DB_DRIVER", c.DBDriver, DriverPostgres, DriverSQLite)
+	switch c.DBDriver {
+	case DriverPostgres:
+		if c.DatabaseURL == "" {
+			v.check(c.DBHost != "", "DB_HOST", "is required without DATABASE_URL")
+			v.port("DB_PORT", c.DBPort)
+			v.check(c.DBName != "", "DB_NAME", "is required without DATABASE_URL")
+		}
+	case DriverSQLite:
+		v.check(c.DBPath != "", "DB_PATH", "is required for SQLite")
+	}
+	v.check(c.DBMaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	v.check(c.DBMaxIdleConns >= 0, "DB_MAX_IDLE_CONNS", "must not be negative")
+	v.nonNegative("DB_CONN_MAX_LIFETIME", c.DBConnMaxLifetime)
+	v.nonNegative("DB_CONNECT_TIMEOUT", c.DBConnectTimeout)
+	v.positive("DB_QUERY_TIMEOUT", c.DBQueryTimeout)
+
+	v.port("REDIS_PORT", c.RedisPort)
+	db, err := strconv.Atoi(c.RedisDB)
+	v.check(err == nil && db >= 0, "REDIS_DB", "must be a database number, got %q", c.RedisDB)
+
+	v.nonNegative("CACHE_TTL", c.CacheTTL)
+	v.positive("IDEMPOTENCY_TTL", c.IdempotencyTTL)
+	v.positive("IDEMPOTENCY_LEASE", c.IdempotencyLease)
+	v.check(c.IdempotencyLease <= c.IdempotencyTTL, "IDEMPOTENCY_LEASE", "must not exceed IDEMPOTENCY_TTL, got %s", c.IdempotencyLease)
+	v.check(c.IdempotencyMaxBodySize > 0, "IDEMPOTENCY_MAX_BODY_SIZE", "must be positive, got %d", c.IdempotencyMaxBodySize)
+
+	v.check(c.JWTSecret != "", "JWT_SECRET", "is required, set JWT_SECRET or JWT_SECRET_FILE")
+	v.positive("JWT_EXPIRATION", c.JWTExpiration)
+
+	v.positive("RECURRENCE_CHECK_INTERVAL", c.RecurrenceCheckInterval)
+	v.positive("IDEMPOTENCY_CLEANUP_INTERVAL", c.IdempotencyCleanupInterval)
+	v.nonNegative("SHUTDOWN_DELAY", c.ShutdownDelay)
+	v.positive("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	v.positive("HEALTH_CHECK_TIMEOUT", c.HealthCheckTimeout)
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, name, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+	}
+}
+
+func (v *validator) oneOf(name, value string, allowed ...string) {
+	v.check(slices.Contains(allowed, value), name, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) port(name, value string) {
+	port, err := strconv.Atoi(value)
+	v.check(err == nil && port > 0 && port <= 65535, name, "must be a port number, got %q", value)
+}
+
+// Интервалы тикеров и таймауты: ноль или отрицательное значение останавливает работу
+func (v *validator) positive(name string, d time.Duration) {
+	v.check(d > 0, name, "must be positive, got %s", d)
+}
+
+func (v *validator) nonNegative(name string, d time.Duration) {
+	v.check(d >= 0, name, "must not be negative, got %s", d)
+}
@@ -3,140 +3,263 @@ package api
 import (
 	"net/http"
 	"strconv"
-	"time"
 
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
 	"github.com/CAATHARSIS/task-tracking/internal/models"
-	board_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type BoardHandler struct {
-	repo      *board_repo.BoardPostgresRepo
-	validator *validator.Validate
+	service *service.BoardService
 }
 
-func NewBoardHandler(repo *board_repo.BoardPostgresRepo) *BoardHandler {
-	v := validator.New()
-	return &BoardHandler{
-		repo:      repo,
-		validator: v,
-	}
+func NewBoardHandler(service *service.BoardService) *BoardHandler {
+	return &BoardHandler{service: service}
 }
 
+// @Summary Создать доску
+// @Tags boards
+// @Accept json
+// @Produce json
+// @Param request body models.BoardRequest true "Доска"
+// @Success 200 {object} models.Board
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards [post]
 func (h *BoardHandler) CreateBoard(c *gin.Context) {
 	var req models.BoardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-		return
-	}
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": c.Keys})
-		return
-	}
-
-	if err := h.validator.Struct(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.BadRequest("Invalid request"))
 		return
 	}
 
-	newBoard := models.Board{
-		Name:       req.Name,
-		UserID:     userID.(int),
-		CreatedAt:  time.Now(),
-		UpdateddAt: time.Now(),
-	}
-
-	if err := h.repo.Create(&newBoard); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	board, err := h.service.Create(c.Request.Context(), c.MustGet("user_id").(int), req)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, newBoard)
+	setETag(c, board.Version)
+	c.JSON(http.StatusOK, board)
 }
 
+// @Summary Получить доску
+// @Description Доступна участникам доски: владельцу и владельцам задач на ней.
+// @Description Заголовок ETag содержит версию доски, её передают в If-Match при изменении
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID доски"
+// @Success 200 {object} models.Board
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id} [get]
 func (h *BoardHandler) GetBoard(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
-	board, err := h.repo.GetById(id)
+	board, err := h.service.Get(c.Request.Context(), c.MustGet("user_id").(int), id)
 	if err != nil {
-		if err.Error() == "board not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	setETag(c, board.Version)
 	c.JSON(http.StatusOK, board)
 }
 
+// @Summary Изменить доску
+// @Description WIP-лимиты заменяются, только если переданы в запросе.
+// @Description Возвращает 412, если доску изменили после получения ETag из If-Match
+// @Tags boards
+// @Accept json
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param If-Match header string true "ETag доски"
+// @Param request body models.BoardRequest true "Доска"
+// @Success 200 {object} models.Board
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id} [put]
 func (h *BoardHandler) UpdateBoard(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
 	var req models.BoardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	board, err := h.service.Update(c.Request.Context(), c.MustGet("user_id").(int), id, version, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, board.Version)
+	c.JSON(http.StatusOK, board)
+}
+
+// @Summary Частично изменить доску
+// @Description JSON Merge Patch (RFC 7396): меняются только переданные поля.
+// @Description WIP-лимиты сливаются по статусам, null снимает лимит статуса или все лимиты сразу.
+// @Description Поля, которые клиент не меняет (user_id, version и т.п.), отклоняются с ошибкой read_only.
+// @Description Возвращает 412, если доску изменили после получения ETag из If-Match
+// @Tags boards
+// @Accept json,application/merge-patch+json
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param If-Match header string true "ETag доски"
+// @Param request body models.BoardRequest true "Изменяемые поля доски"
+// @Success 200 {object} models.Board
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id} [patch]
+func (h *BoardHandler) PatchBoard(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
-	currentBoard, err := h.repo.GetById(id)
+	patch, err := bindMergePatch(c)
 	if err != nil {
-		if err.Error() == "board not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	currentBoard.Name = req.Name
-	currentBoard.UpdateddAt = time.Now()
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	if err := h.repo.Update(currentBoard); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	board, err := h.service.Patch(c.Request.Context(), c.MustGet("user_id").(int), id, version, patch)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, currentBoard)
+	setETag(c, board.Version)
+	c.JSON(http.StatusOK, board)
 }
 
+// @Summary Удалить доску
+// @Description Возвращает 412, если доску изменили после получения ETag из If-Match
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param If-Match header string true "ETag доски"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id} [delete]
 func (h *BoardHandler) DeleteBoard(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(c.Request.Context(), c.MustGet("user_id").(int), id, version); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Доски пользователя
+// @Description Доступны только собственные доски
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {array} models.Board
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/user-tasks [get]
 func (h *BoardHandler) ListBoardByUser(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
-	boards, err := h.repo.ListByUser(userID)
+	boards, err := h.service.ListByUser(c.Request.Context(), c.MustGet("user_id").(int), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, boards)
 }
+
+// Итоги по колонкам статусов: количество задач, сумма оценок и WIP-лимит
+//
+// @Summary Итоги по колонкам доски
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID доски"
+// @Success 200 {object} models.BoardSummary
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/summary [get]
+func (h *BoardHandler) GetBoardSummary(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid board ID"))
+		return
+	}
+
+	summary, err := h.service.Summary(c.Request.Context(), c.MustGet("user_id").(int), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type CustomFieldHandler struct {
+	repo        repository.CustomFieldRepository
+	boardRepo   repository.BoardRepository
+	taskService *service.TaskService
+	validator   *validator.Validate
+}
+
+func NewCustomFieldHandler(
+	repo repository.CustomFieldRepository,
+	boardRepo repository.BoardRepository,
+	taskService *service.TaskService,
+) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		repo:        repo,
+		boardRepo:   boardRepo,
+		taskService: taskService,
+		validator:   service.NewValidator(),
+	}
+}
+
+// Загружает доску из параметра id, изменять поля может только владелец доски
+func (h *CustomFieldHandler) loadOwnBoard(c *gin.Context) (*models.Board, bool) {
+	ctx := c.Request.Context()
+	boardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid board ID"))
+		return nil, false
+	}
+
+	board, err := h.boardRepo.GetById(ctx, boardID)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	if board.UserID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return nil, false
+	}
+
+	return board, true
+}
+
+func (h *CustomFieldHandler) loadBoardField(c *gin.Context, board *models.Board) (*models.CustomField, bool) {
+	ctx := c.Request.Context()
+	fieldID, err := strconv.Atoi(c.Param("field_id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid field ID"))
+		return nil, false
+	}
+
+	field, err := h.repo.GetById(ctx, fieldID)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+	if field.BoardID != board.ID {
+		c.Error(repository.NotFound("custom field"))
+		return nil, false
+	}
+
+	return field, true
+}
+
+func (h *CustomFieldHandler) bindFieldRequest(c *gin.Context) (*models.CustomFieldRequest, bool) {
+	var req models.CustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.Error(apperr.Validation(err))
+		return nil, false
+	}
+
+	if req.Type.IsSelect() && len(req.Options) == 0 {
+		c.Error(apperr.BadRequest("Select fields require options"))
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// @Summary Создать пользовательское поле доски
+// @Tags fields
+// @Accept json
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param request body models.CustomFieldRequest true "Поле"
+// @Success 201 {object} models.CustomField
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/fields [post]
+func (h *CustomFieldHandler) CreateField(c *gin.Context) {
+	ctx := c.Request.Context()
+	board, ok := h.loadOwnBoard(c)
+	if !ok {
+		return
+	}
+
+	req, ok := h.bindFieldRequest(c)
+	if !ok {
+		return
+	}
+
+	field := models.CustomField{
+		BoardID:  board.ID,
+		Name:     req.Name,
+		Type:     req.Type,
+		Required: req.Required,
+	}
+	if req.Type.IsSelect() {
+		field.Options = req.Options
+	}
+
+	if err := h.repo.Create(ctx, &field); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, field)
+}
+
+// @Summary Пользовательские поля доски
+// @Tags fields
+// @Produce json
+// @Param id path int true "ID доски"
+// @Success 200 {array} models.CustomField
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/fields [get]
+func (h *CustomFieldHandler) ListFields(c *gin.Context) {
+	ctx := c.Request.Context()
+	boardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid board ID"))
+		return
+	}
+
+	isMember, err := h.boardRepo.IsMember(ctx, boardID, c.MustGet("user_id").(int))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !isMember {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	fields, err := h.repo.ListByBoard(ctx, boardID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+// @Summary Изменить пользовательское поле
+// @Description Тип поля изменить нельзя
+// @Tags fields
+// @Accept json
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param field_id path int true "ID поля"
+// @Param request body models.CustomFieldRequest true "Поле"
+// @Success 200 {object} models.CustomField
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/fields/{field_id} [put]
+func (h *CustomFieldHandler) UpdateField(c *gin.Context) {
+	ctx := c.Request.Context()
+	board, ok := h.loadOwnBoard(c)
+	if !ok {
+		return
+	}
+
+	field, ok := h.loadBoardField(c, board)
+	if !ok {
+		return
+	}
+
+	req, ok := h.bindFieldRequest(c)
+	if !ok {
+		return
+	}
+
+	if req.Type != field.Type {
+		c.Error(apperr.BadRequest("Field type cannot be changed"))
+		return
+	}
+
+	field.Name = req.Name
+	field.Required = req.Required
+	if field.Type.IsSelect() {
+		field.Options = req.Options
+	}
+
+	if err := h.repo.Update(ctx, field); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, field)
+}
+
+// @Summary Удалить пользовательское поле
+// @Tags fields
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param field_id path int true "ID поля"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/fields/{field_id} [delete]
+func (h *CustomFieldHandler) DeleteField(c *gin.Context) {
+	ctx := c.Request.Context()
+	board, ok := h.loadOwnBoard(c)
+	if !ok {
+		return
+	}
+
+	field, ok := h.loadBoardField(c, board)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(ctx, field.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+/*
+Задаёт значения пользовательских полей задачи
+Тело запроса - объект, где ключ - идентификатор поля, значение null удаляет значение поля
+Допустимы только поля досок, на которых находится задача
+*/
+// @Summary Задать значения пользовательских полей задачи
+// @Tags fields
+// @Accept json
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param request body map[string]any true "ID поля -> значение"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/fields [put]
+func (h *CustomFieldHandler) SetTaskValues(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid task ID"))
+		return
+	}
+
+	var req map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return
+	}
+
+	values := make(map[int]json.RawMessage, len(req))
+	for key, value := range req {
+		fieldID, err := strconv.Atoi(key)
+		if err != nil {
+			c.Error(apperr.Validation(fmt.Errorf("invalid field ID %q", key)))
+			return
+		}
+		values[fieldID] = value
+	}
+
+	task, err := h.taskService.SetCustomFields(c.Request.Context(), c.MustGet("user_id").(int), taskID, values)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// Добавляет к задачам значения их пользовательских полей
+func attachCustomFields(ctx context.Context, repo repository.CustomFieldRepository, tasks ...*models.Task) error {
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	values, err := repo.GetValuesForTasks(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		task.CustomFields = values[task.ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+// ETag задачи или доски - её версия в кавычках
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+/*
+Версия из заголовка If-Match, без которого задачи и доски не изменяются
+Принимается один ETag, в том числе слабый (W/"3"); "*" снимает проверку версии и возвращает 0
+*/
+func ifMatch(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, apperr.PreconditionRequired("If-Match header is required")
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, apperr.BadRequest("Invalid If-Match header")
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, apperr.BadRequest("Invalid If-Match header")
+	}
+	return version, nil
+}
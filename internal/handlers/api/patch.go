@@ -0,0 +1,17 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+// Тело JSON Merge Patch должно быть объектом: патч другого вида заменил бы запись целиком
+func bindMergePatch(c *gin.Context) (map[string]json.RawMessage, error) {
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil || patch == nil {
+		return nil, apperr.BadRequest("Request body must be a JSON object")
+	}
+	return patch, nil
+}
@@ -0,0 +1,400 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type SavedViewHandler struct {
+	repo            repository.SavedViewRepository
+	taskRepo        repository.TaskRepository
+	boardRepo       repository.BoardRepository
+	customFieldRepo repository.CustomFieldRepository
+	validator       *validator.Validate
+}
+
+func NewSavedViewHandler(
+	repo repository.SavedViewRepository,
+	taskRepo repository.TaskRepository,
+	boardRepo repository.BoardRepository,
+	customFieldRepo repository.CustomFieldRepository,
+) *SavedViewHandler {
+	return &SavedViewHandler{
+		repo:            repo,
+		taskRepo:        taskRepo,
+		boardRepo:       boardRepo,
+		customFieldRepo: customFieldRepo,
+		validator:       service.NewValidator(),
+	}
+}
+
+// Проверяет запрос и доступ пользователя к доске из фильтра
+func (h *SavedViewHandler) checkRequest(ctx context.Context, req *models.SavedViewRequest, userID int) error {
+	if err := h.validator.Struct(req); err != nil {
+		return apperr.Validation(err)
+	}
+
+	if req.Shared && req.Filter.BoardID == 0 {
+		return apperr.BadRequest("Only views with a board filter can be shared")
+	}
+
+	if req.Filter.BoardID != 0 {
+		isMember, err := h.boardRepo.IsMember(ctx, req.Filter.BoardID, userID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return apperr.Forbidden("Access denied")
+		}
+	}
+
+	return nil
+}
+
+// Представление доступно владельцу и, если оно расшарено, участникам доски
+func (h *SavedViewHandler) canRead(ctx context.Context, view *models.SavedView, userID int) (bool, error) {
+	if view.UserID == userID {
+		return true, nil
+	}
+	if !view.Shared {
+		return false, nil
+	}
+	return h.boardRepo.IsMember(ctx, view.Filter.BoardID, userID)
+}
+
+/*
+Выполнить фильтр можно, если представление доступно для чтения и пользователь всё ещё участник доски из фильтра:
+владелец, которого убрали с доски, не должен видеть её задачи через своё старое представление
+*/
+func (h *SavedViewHandler) canRun(ctx context.Context, view *models.SavedView, userID int) (bool, error) {
+	allowed, err := h.canRead(ctx, view, userID)
+	if err != nil || !allowed || view.Filter.BoardID == 0 {
+		return allowed, err
+	}
+	return h.boardRepo.IsMember(ctx, view.Filter.BoardID, userID)
+}
+
+func (h *SavedViewHandler) loadView(c *gin.Context) (*models.SavedView, bool) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid view ID"))
+		return nil, false
+	}
+
+	view, err := h.repo.GetById(ctx, id)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	return view, true
+}
+
+// @Summary Создать сохранённое представление
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param request body models.SavedViewRequest true "Представление"
+// @Success 201 {object} models.SavedView
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /views [post]
+func (h *SavedViewHandler) CreateView(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(int)
+	if err := h.checkRequest(ctx, &req, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	view := models.SavedView{
+		UserID: userID,
+		Name:   req.Name,
+		Filter: req.Filter,
+		Pinned: req.Pinned,
+		Shared: req.Shared,
+	}
+
+	if err := h.repo.Create(ctx, &view); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// @Summary Доступные представления
+// @Description Собственные представления и представления, расшаренные на доски пользователя
+// @Tags views
+// @Produce json
+// @Success 200 {array} models.SavedView
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /views [get]
+func (h *SavedViewHandler) ListViews(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("user_id").(int)
+
+	views, err := h.repo.ListAccessible(ctx, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// @Summary Получить представление
+// @Tags views
+// @Produce json
+// @Param id path int true "ID представления"
+// @Success 200 {object} models.SavedView
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /views/{id} [get]
+func (h *SavedViewHandler) GetView(c *gin.Context) {
+	ctx := c.Request.Context()
+	view, ok := h.loadView(c)
+	if !ok {
+		return
+	}
+
+	allowed, err := h.canRead(ctx, view, c.MustGet("user_id").(int))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !allowed {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// @Summary Изменить представление
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path int true "ID представления"
+// @Param request body models.SavedViewRequest true "Представление"
+// @Success 200 {object} models.SavedView
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /views/{id} [put]
+func (h *SavedViewHandler) UpdateView(c *gin.Context) {
+	ctx := c.Request.Context()
+	view, ok := h.loadView(c)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet("user_id").(int)
+	if view.UserID != userID {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.checkRequest(ctx, &req, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	view.Name = req.Name
+	view.Filter = req.Filter
+	view.Pinned = req.Pinned
+	view.Shared = req.Shared
+
+	if err := h.repo.Update(ctx, view); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// @Summary Удалить представление
+// @Tags views
+// @Produce json
+// @Param id path int true "ID представления"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /views/{id} [delete]
+func (h *SavedViewHandler) DeleteView(c *gin.Context) {
+	ctx := c.Request.Context()
+	view, ok := h.loadView(c)
+	if !ok {
+		return
+	}
+
+	if view.UserID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	if err := h.repo.Delete(ctx, view.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Выполняет фильтр представления от имени текущего пользователя
+//
+// @Summary Задачи по фильтру представления
+// @Tags views
+// @Produce json
+// @Param id path int true "ID представления"
+// @Success 200 {array} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /views/{id}/tasks [get]
+func (h *SavedViewHandler) GetViewTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	view, ok := h.loadView(c)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet("user_id").(int)
+	allowed, err := h.canRun(ctx, view, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !allowed {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	h.respondFiltered(c, userID, view.Filter)
+}
+
+/*
+Выборка задач по фильтру из параметров запроса без сохранения представления:
+status (можно несколько), board_id, search, only_mine и field.<id> для пользовательских полей
+*/
+// @Summary Поиск задач по фильтру
+// @Description Значения пользовательских полей фильтруются параметрами field.<id>=<значение>
+// @Tags tasks
+// @Produce json
+// @Param status query []string false "Статусы" Enums(todo, in_progress, done) collectionFormat(multi)
+// @Param board_id query int false "ID доски"
+// @Param search query string false "Подстрока в названии или описании"
+// @Param only_mine query bool false "Только свои задачи"
+// @Success 200 {array} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks [get]
+func (h *SavedViewHandler) FilterTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	filter := models.TaskFilter{
+		Search:   c.Query("search"),
+		OnlyMine: c.Query("only_mine") == "true",
+	}
+
+	for _, status := range c.QueryArray("status") {
+		filter.Statuses = append(filter.Statuses, models.TaskStatus(status))
+	}
+
+	if value := c.Query("board_id"); value != "" {
+		boardID, err := strconv.Atoi(value)
+		if err != nil {
+			c.Error(apperr.BadRequest("Invalid board ID"))
+			return
+		}
+		filter.BoardID = boardID
+	}
+
+	for key, values := range c.Request.URL.Query() {
+		name, found := strings.CutPrefix(key, "field.")
+		if !found || len(values) == 0 {
+			continue
+		}
+		fieldID, err := strconv.Atoi(name)
+		if err != nil {
+			c.Error(apperr.BadRequest("Invalid field ID"))
+			return
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[int]string)
+		}
+		filter.Fields[fieldID] = values[0]
+	}
+
+	if err := h.validator.Struct(filter); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(int)
+	if filter.BoardID != 0 {
+		isMember, err := h.boardRepo.IsMember(ctx, filter.BoardID, userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if !isMember {
+			c.Error(apperr.Forbidden("Access denied"))
+			return
+		}
+	}
+
+	h.respondFiltered(c, userID, filter)
+}
+
+func (h *SavedViewHandler) respondFiltered(c *gin.Context, userID int, filter models.TaskFilter) {
+	ctx := c.Request.Context()
+	tasks, err := h.taskRepo.ListByFilter(ctx, userID, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := attachCustomFields(ctx, h.customFieldRepo, tasks...); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
@@ -3,183 +3,281 @@ package api
 import (
 	"net/http"
 	"strconv"
-	"time"
 
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
 	"github.com/CAATHARSIS/task-tracking/internal/models"
-	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type TaskHandler struct {
-	repo      *task_repo.TaskPostgresRepo
-	validator *validator.Validate
+	service *service.TaskService
 }
 
-func NewTaskHandler(repo *task_repo.TaskPostgresRepo) *TaskHandler {
-	v := validator.New()
-	v.RegisterValidation("taskstatus", func(fl validator.FieldLevel) bool {
-		status := fl.Field().Interface().(models.TaskStatus)
-		return status.IsValid()
-	})
-
-	return &TaskHandler{
-		repo:      repo,
-		validator: v,
-	}
+func NewTaskHandler(service *service.TaskService) *TaskHandler {
+	return &TaskHandler{service: service}
 }
 
+// @Summary Создать задачу
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param request body models.TaskRequest true "Задача"
+// @Success 201 {object} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks [post]
 func (h *TaskHandler) CreateTask(c *gin.Context) {
-	var req models.TaskCreateRequest
+	var req models.TaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-		return
-	}
-
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": c.Keys})
+		c.Error(apperr.BadRequest("Invalid request"))
 		return
 	}
-	req.UserID = userID.(int)
-
-	if err := h.validator.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	newTask := models.Task{
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      req.Status,
-		UserID:      req.UserID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
 
-	if err := h.repo.Create(&newTask); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	task, err := h.service.Create(c.Request.Context(), c.MustGet("user_id").(int), req, nil)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, newTask)
+	setETag(c, task.Version)
+	c.JSON(http.StatusCreated, task)
 }
 
+// @Summary Получить задачу
+// @Description Заголовок ETag содержит версию задачи, её передают в If-Match при изменении
+// @Tags tasks
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id} [get]
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.Error(apperr.BadRequest("Invalid task ID"))
 		return
 	}
 
-	task, err := h.repo.GetById(id)
+	task, err := h.service.Get(c.Request.Context(), c.MustGet("user_id").(int), id)
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
+// @Summary Изменить статус задачи
+// @Description Возвращает 409, если новый статус превышает WIP-лимит одной из досок задачи,
+// @Description и 412, если задачу изменили после получения ETag из If-Match
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param If-Match header string true "ETag задачи"
+// @Param request body models.TaskStatusUpdate true "Новый статус"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/status [patch]
 func (h *TaskHandler) UpdateStatus(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.Error(apperr.BadRequest("Invalid task ID"))
 		return
 	}
 
 	var update models.TaskStatusUpdate
 	if err := c.ShouldBindJSON(&update); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.Error(apperr.BadRequest("Invalid request body"))
 		return
 	}
 
-	if err := h.validator.Struct(update); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	task, err := h.repo.GetById(id)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		c.Error(err)
 		return
 	}
 
-	task.Status = update.Status
-	task.UpdatedAt = time.Now()
-
-	if err := h.repo.Update(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	task, err := h.service.UpdateStatus(c.Request.Context(), c.MustGet("user_id").(int), id, version, update.Status)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
+// @Summary Изменить задачу
+// @Description Владелец задачи не меняется. Возвращает 409, если новый статус превышает WIP-лимит одной из досок задачи,
+// @Description и 412, если задачу изменили после получения ETag из If-Match
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param If-Match header string true "ETag задачи"
+// @Param request body models.TaskRequest true "Задача"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id} [put]
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.Error(apperr.BadRequest("Invalid task ID"))
 		return
 	}
 
-	var task models.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	var req models.TaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
 		return
 	}
 
-	task.ID = id
-	task.UpdatedAt = time.Now()
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-	if err := h.validator.Struct(task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	task, err := h.service.Update(c.Request.Context(), c.MustGet("user_id").(int), id, version, req, service.TaskUpdateOptions{})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
+// @Summary Частично изменить задачу
+// @Description JSON Merge Patch (RFC 7396): меняются только переданные поля, null очищает описание и оценку.
+// @Description Поля, которые клиент не меняет (user_id, series_id, version и т.п.), отклоняются с ошибкой read_only.
+// @Description Возвращает 409, если новый статус превышает WIP-лимит одной из досок задачи,
+// @Description и 412, если задачу изменили после получения ETag из If-Match
+// @Tags tasks
+// @Accept json,application/merge-patch+json
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param If-Match header string true "ETag задачи"
+// @Param request body models.TaskRequest true "Изменяемые поля задачи"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id} [patch]
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid task ID"))
 		return
 	}
 
-	if err := h.repo.Update(&task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	patch, err := bindMergePatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	updatedTask, err := h.repo.GetById(id)
+	version, err := ifMatch(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedTask)
+	task, err := h.service.Patch(c.Request.Context(), c.MustGet("user_id").(int), id, version, patch)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
 }
 
+// @Summary Удалить задачу
+// @Description Возвращает 412, если задачу изменили после получения ETag из If-Match
+// @Tags tasks
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param If-Match header string true "ETag задачи"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Failure 428 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id} [delete]
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.Error(apperr.BadRequest("Invalid task ID"))
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Delete(c.Request.Context(), c.MustGet("user_id").(int), id, version); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Задачи пользователя
+// @Description Доступны только собственные задачи
+// @Tags tasks
+// @Produce json
+// @Param user_id path int true "ID пользователя"
+// @Success 200 {array} models.Task
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/user/{user_id} [get]
 func (h *TaskHandler) ListTaskByUser(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("user_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperr.BadRequest("Invalid user ID"))
 		return
 	}
 
-	tasks, err := h.repo.ListByUser(userID)
+	tasks, err := h.service.ListByUser(c.Request.Context(), c.MustGet("user_id").(int), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Итог операции пакета, error заполнено у операции со статусом failed
+type BulkItemResponse struct {
+	Index  int                   `json:"index"`
+	Op     models.BulkOp         `json:"op"`
+	TaskID int                   `json:"task_id"`
+	Status models.BulkItemStatus `json:"status"`
+	Error  *middleware.ErrorBody `json:"error,omitempty"`
+}
+
+type BulkTaskResponse struct {
+	Mode      models.BulkMode    `json:"mode"`
+	Committed bool               `json:"committed"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []BulkItemResponse `json:"results"`
+}
+
+// @Summary Пакетные операции над задачами
+// @Description Операции set_status, add_to_board, remove_from_board, move, delete, label и unlabel выполняются в одной транзакции.
+// @Description В режиме atomic (по умолчанию) первая ошибка откатывает весь пакет: committed = false, выполненные операции получают статус rolled_back, оставшиеся - skipped.
+// @Description В режиме best_effort откатываются только неудачные операции.
+// @Description Ошибки операций возвращаются в results с тем же телом, что и у одиночных запросов
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param request body models.BulkTaskRequest true "Операции"
+// @Success 200 {object} api.BulkTaskResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/bulk [post]
+func (h *TaskHandler) BulkTasks(c *gin.Context) {
+	var req models.BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request"))
+		return
+	}
+
+	result, err := h.service.Bulk(c.Request.Context(), c.MustGet("user_id").(int), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newBulkTaskResponse(c, result))
+}
+
+func newBulkTaskResponse(c *gin.Context, result *service.BulkResult) BulkTaskResponse {
+	resp := BulkTaskResponse{
+		Mode:      result.Mode,
+		Committed: result.Committed,
+		Results:   make([]BulkItemResponse, 0, len(result.Items)),
+	}
+
+	for _, item := range result.Items {
+		itemResp := BulkItemResponse{Index: item.Index, Op: item.Op, TaskID: item.TaskID, Status: item.Status}
+		switch item.Status {
+		case models.BulkItemOK:
+			resp.Succeeded++
+		case models.BulkItemFailed:
+			resp.Failed++
+		}
+		if item.Err != nil {
+			body := middleware.NewErrorBody(c, apperr.From(item.Err))
+			itemResp.Error = &body
+		}
+		resp.Results = append(resp.Results, itemResp)
+	}
+
+	return resp
+}
@@ -1,93 +1,215 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
-	board_task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board_task"
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 type BoardTaskRelationHandler struct {
-	repo *board_task_repo.BoardTaskPostgresRepo
+	service *service.BoardService
 }
 
-func NewBoardTaskRealtionHandler(repo *board_task_repo.BoardTaskPostgresRepo) *BoardTaskRelationHandler {
-	return &BoardTaskRelationHandler{repo: repo}
+func NewBoardTaskRealtionHandler(service *service.BoardService) *BoardTaskRelationHandler {
+	return &BoardTaskRelationHandler{service: service}
 }
 
+// @Summary Добавить задачу на доску
+// @Description Владелец доски может добавить только свою задачу
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param task_id path int true "ID задачи"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/tasks/{task_id} [post]
 func (h *BoardTaskRelationHandler) AddTaskToBoard(c *gin.Context) {
 	boardID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
 	taskID, err := strconv.Atoi(c.Param("task_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.Error(apperr.BadRequest("Invalid task ID"))
 		return
 	}
 
-	if err := h.repo.AddTask(boardID, taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.AddTask(c.Request.Context(), c.MustGet("user_id").(int), boardID, taskID); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Убрать задачу с доски
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param task_id path int true "ID задачи"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/tasks/{task_id} [delete]
 func (h *BoardTaskRelationHandler) RemoveTaskFromBoard(c *gin.Context) {
 	boardID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
 	taskID, err := strconv.Atoi(c.Param("task_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.Error(apperr.BadRequest("Invalid task ID"))
 		return
 	}
 
-	if err := h.repo.RemoveTask(boardID, taskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.RemoveTask(c.Request.Context(), c.MustGet("user_id").(int), boardID, taskID); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+/*
+Задача доски с необязательными расширениями:
+board - доска, на которой лежит задача, labels - значения полей множественного выбора
+*/
+type BoardTaskResponse struct {
+	models.Task
+	Board  *models.Board `json:"board,omitempty"`
+	Labels []string      `json:"labels,omitempty"`
+}
+
+// @Summary Задачи доски
+// @Description Задачи доски со значениями пользовательских полей, от новых к старым.
+// @Description Параметр expand добавляет к задачам доску (board) и метки из полей множественного выбора (labels)
+// @Tags boards
+// @Produce json
+// @Param id path int true "ID доски"
+// @Param expand query []string false "Расширения через запятую" Enums(board, labels) collectionFormat(multi)
+// @Success 200 {array} api.BoardTaskResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/tasks [get]
 func (h *BoardTaskRelationHandler) GetBoardTasks(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("user_id").(int)
+
 	boardID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid board ID"})
+		c.Error(apperr.BadRequest("Invalid board ID"))
 		return
 	}
 
-	taskIDs, err := h.repo.GetTasks(boardID)
+	var expandBoard, expandLabels bool
+	for _, value := range c.QueryArray("expand") {
+		for _, name := range strings.Split(value, ",") {
+			switch strings.TrimSpace(name) {
+			case "board":
+				expandBoard = true
+			case "labels":
+				expandLabels = true
+			case "":
+			default:
+				c.Error(apperr.BadRequest("Unknown expand value: " + name))
+				return
+			}
+		}
+	}
+
+	tasks, err := h.service.Tasks(ctx, userID, boardID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, taskIDs)
+	var board *models.Board
+	if expandBoard {
+		if board, err = h.service.Get(ctx, userID, boardID); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	response := make([]BoardTaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		item := BoardTaskResponse{Task: *task, Board: board}
+		if expandLabels {
+			item.Labels = labels(task)
+		}
+		response = append(response, item)
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-func (h *BoardTaskRelationHandler) MoveTasksBeetwenBoards(c *gin.Context) {
-	var req struct {
-		FromBoardID int `json:"from_board_id" binding:"required"`
-		ToBoardID   int `json:"to_board_id" binding:"required"`
-		TaskID      int `json:"task_id" binding:"required"`
+// Значения полей множественного выбора задачи в порядке полей
+func labels(task *models.Task) []string {
+	var result []string
+	for _, field := range task.CustomFields {
+		if field.Type != models.FieldMultiSelect {
+			continue
+		}
+		var values []string
+		if err := json.Unmarshal(field.Value, &values); err != nil {
+			continue
+		}
+		result = append(result, values...)
 	}
+	return result
+}
+
+type MoveTaskRequest struct {
+	FromBoardID int `json:"from_board_id" binding:"required"`
+	ToBoardID   int `json:"to_board_id" binding:"required"`
+	TaskID      int `json:"task_id" binding:"required"`
+}
+
+// @Summary Перенести задачу между досками
+// @Description Обе доски должны принадлежать пользователю. Возвращает 409, если перенос превышает WIP-лимит целевой доски
+// @Tags boards
+// @Accept json
+// @Produce json
+// @Param request body api.MoveTaskRequest true "Доски и задача"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/tasks/move [patch]
+func (h *BoardTaskRelationHandler) MoveTasksBeetwenBoards(c *gin.Context) {
+	var req MoveTaskRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Validation(err))
 		return
 	}
 
-	if err := h.repo.MoveTask(req.FromBoardID, req.ToBoardID, req.TaskID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	err := h.service.MoveTask(c.Request.Context(), c.MustGet("user_id").(int), req.FromBoardID, req.ToBoardID, req.TaskID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
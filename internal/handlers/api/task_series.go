@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type TaskSeriesHandler struct {
+	repo      repository.TaskSeriesRepository
+	taskRepo  repository.TaskRepository
+	uow       repository.UnitOfWork
+	validator *validator.Validate
+}
+
+func NewTaskSeriesHandler(repo repository.TaskSeriesRepository, taskRepo repository.TaskRepository, uow repository.UnitOfWork) *TaskSeriesHandler {
+	return &TaskSeriesHandler{
+		repo:      repo,
+		taskRepo:  taskRepo,
+		uow:       uow,
+		validator: service.NewValidator(),
+	}
+}
+
+// Делает задачу первым экземпляром новой серии
+//
+// @Summary Сделать задачу повторяющейся
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param request body models.RecurrenceRule true "Правило повторения"
+// @Success 201 {object} models.TaskSeries
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/recurrence [post]
+func (h *TaskSeriesHandler) MakeRecurring(c *gin.Context) {
+	ctx := c.Request.Context()
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid task ID"))
+		return
+	}
+
+	var rule models.RecurrenceRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.validator.Struct(rule); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	task, err := h.taskRepo.GetById(ctx, taskID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if task.UserID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	if task.SeriesID != nil {
+		c.Error(apperr.Conflict("Task is already recurring"))
+		return
+	}
+
+	series := models.TaskSeries{
+		UserID:      task.UserID,
+		Title:       task.Title,
+		Description: task.Description,
+		Rule:        rule,
+		NextRunAt:   rule.Next(time.Now()),
+	}
+
+	err = h.uow.Do(ctx, func(ctx context.Context) error {
+		if err := h.repo.Create(ctx, &series); err != nil {
+			return err
+		}
+		return h.repo.AttachTask(ctx, series.ID, task.ID)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, series)
+}
+
+func (h *TaskSeriesHandler) loadSeries(c *gin.Context) (*models.TaskSeries, bool) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid series ID"))
+		return nil, false
+	}
+
+	series, err := h.repo.GetById(ctx, id)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	if series.UserID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return nil, false
+	}
+
+	return series, true
+}
+
+// @Summary Получить серию задач
+// @Tags series
+// @Produce json
+// @Param id path int true "ID серии"
+// @Success 200 {object} models.TaskSeries
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /series/{id} [get]
+func (h *TaskSeriesHandler) GetSeries(c *gin.Context) {
+	series, ok := h.loadSeries(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// Изменение серии затрагивает шаблон и все незавершённые экземпляры
+//
+// @Summary Изменить серию задач
+// @Tags series
+// @Accept json
+// @Produce json
+// @Param id path int true "ID серии"
+// @Param request body models.TaskSeriesUpdate true "Шаблон и правило"
+// @Success 200 {object} models.TaskSeries
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /series/{id} [put]
+func (h *TaskSeriesHandler) UpdateSeries(c *gin.Context) {
+	ctx := c.Request.Context()
+	series, ok := h.loadSeries(c)
+	if !ok {
+		return
+	}
+
+	var req models.TaskSeriesUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	if req.Rule.Frequency != series.Rule.Frequency || req.Rule.Interval != series.Rule.Interval {
+		series.NextRunAt = req.Rule.Next(time.Now())
+	}
+
+	series.Title = req.Title
+	series.Description = req.Description
+	series.Rule = req.Rule
+
+	if err := h.repo.Update(ctx, series); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// @Summary Удалить серию задач
+// @Tags series
+// @Produce json
+// @Param id path int true "ID серии"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /series/{id} [delete]
+func (h *TaskSeriesHandler) DeleteSeries(c *gin.Context) {
+	ctx := c.Request.Context()
+	series, ok := h.loadSeries(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(ctx, series.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,417 @@
+package api
+
+import (
+	"encoding/csv"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type TimeEntryHandler struct {
+	repo      repository.TimeEntryRepository
+	taskRepo  repository.TaskRepository
+	boardRepo repository.BoardRepository
+	validator *validator.Validate
+}
+
+func NewTimeEntryHandler(
+	repo repository.TimeEntryRepository,
+	taskRepo repository.TaskRepository,
+	boardRepo repository.BoardRepository,
+) *TimeEntryHandler {
+	return &TimeEntryHandler{
+		repo:      repo,
+		taskRepo:  taskRepo,
+		boardRepo: boardRepo,
+		validator: service.NewValidator(),
+	}
+}
+
+// Загружает задачу из параметра id и проверяет, что она принадлежит текущему пользователю
+func (h *TimeEntryHandler) loadOwnTask(c *gin.Context) (*models.Task, bool) {
+	ctx := c.Request.Context()
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid task ID"))
+		return nil, false
+	}
+
+	task, err := h.taskRepo.GetById(ctx, taskID)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	if task.UserID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return nil, false
+	}
+
+	return task, true
+}
+
+// @Summary Запустить таймер по задаче
+// @Tags time
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Success 201 {object} models.TimeEntry
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/timer/start [post]
+func (h *TimeEntryHandler) StartTimer(c *gin.Context) {
+	ctx := c.Request.Context()
+	task, ok := h.loadOwnTask(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.repo.StartTimer(ctx, task.UserID, task.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// @Summary Остановить текущий таймер
+// @Tags time
+// @Produce json
+// @Success 200 {object} models.TimeEntry
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /timer/stop [post]
+func (h *TimeEntryHandler) StopTimer(c *gin.Context) {
+	ctx := c.Request.Context()
+	entry, err := h.repo.StopTimer(ctx, c.MustGet("user_id").(int))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// @Summary Текущий таймер
+// @Tags time
+// @Produce json
+// @Success 200 {object} models.TimeEntry
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /timer [get]
+func (h *TimeEntryHandler) GetRunningTimer(c *gin.Context) {
+	ctx := c.Request.Context()
+	entry, err := h.repo.GetRunning(ctx, c.MustGet("user_id").(int))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// @Summary Добавить запись времени вручную
+// @Tags time
+// @Accept json
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Param request body models.WorklogRequest true "Запись времени"
+// @Success 201 {object} models.TimeEntry
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/worklogs [post]
+func (h *TimeEntryHandler) CreateWorklog(c *gin.Context) {
+	ctx := c.Request.Context()
+	task, ok := h.loadOwnTask(c)
+	if !ok {
+		return
+	}
+
+	var req models.WorklogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	endedAt := req.StartedAt.Add(time.Duration(req.Minutes) * time.Minute)
+	entry := models.TimeEntry{
+		UserID:    task.UserID,
+		TaskID:    task.ID,
+		StartedAt: req.StartedAt,
+		EndedAt:   &endedAt,
+		Note:      req.Note,
+	}
+
+	if err := h.repo.CreateManual(ctx, &entry); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// @Summary Записи времени по задаче
+// @Tags time
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Success 200 {array} models.TimeEntry
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/worklogs [get]
+func (h *TimeEntryHandler) ListWorklogs(c *gin.Context) {
+	ctx := c.Request.Context()
+	task, ok := h.loadOwnTask(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.repo.ListByTask(ctx, task.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// @Summary Удалить запись времени
+// @Tags time
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 204 "No Content"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /worklogs/{id} [delete]
+func (h *TimeEntryHandler) DeleteWorklog(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid time entry ID"))
+		return
+	}
+
+	entry, err := h.repo.GetById(ctx, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if entry.UserID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	if err := h.repo.Delete(ctx, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Суммарное время по задаче
+// @Tags time
+// @Produce json
+// @Param id path int true "ID задачи"
+// @Success 200 {object} models.TimeTotal
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /tasks/{id}/time [get]
+func (h *TimeEntryHandler) GetTaskTotal(c *gin.Context) {
+	ctx := c.Request.Context()
+	task, ok := h.loadOwnTask(c)
+	if !ok {
+		return
+	}
+
+	seconds, err := h.repo.TotalByTask(ctx, task.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TimeTotal{Seconds: seconds})
+}
+
+// @Summary Суммарное время по доске
+// @Tags time
+// @Produce json
+// @Param id path int true "ID доски"
+// @Success 200 {object} models.TimeTotal
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /boards/{id}/time [get]
+func (h *TimeEntryHandler) GetBoardTotal(c *gin.Context) {
+	ctx := c.Request.Context()
+	boardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid board ID"))
+		return
+	}
+
+	isMember, err := h.boardRepo.IsMember(ctx, boardID, c.MustGet("user_id").(int))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !isMember {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	seconds, err := h.repo.TotalByBoard(ctx, boardID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TimeTotal{Seconds: seconds})
+}
+
+// @Summary Суммарное время пользователя
+// @Tags time
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} models.TimeTotal
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /users/{id}/time [get]
+func (h *TimeEntryHandler) GetUserTotal(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.BadRequest("Invalid user ID"))
+		return
+	}
+
+	if userID != c.MustGet("user_id").(int) {
+		c.Error(apperr.Forbidden("Access denied"))
+		return
+	}
+
+	seconds, err := h.repo.TotalByUser(ctx, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TimeTotal{Seconds: seconds})
+}
+
+/*
+Отчёт по времени текущего пользователя, сгруппированный по дням и задачам
+Параметры from и to задаются в формате 2006-01-02, по умолчанию - последние 30 дней
+Параметр format=csv возвращает отчёт в CSV
+*/
+// @Summary Отчёт по времени
+// @Tags time
+// @Produce json,text/csv
+// @Param from query string false "Начало периода" Format(date)
+// @Param to query string false "Конец периода включительно" Format(date)
+// @Param format query string false "Формат ответа" Enums(json, csv)
+// @Success 200 {array} models.TimeReportRow
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /reports/time [get]
+func (h *TimeEntryHandler) GetReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	to := time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -30)
+
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			c.Error(apperr.BadRequest("Invalid from date"))
+			return
+		}
+		from = parsed
+	}
+
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			c.Error(apperr.BadRequest("Invalid to date"))
+			return
+		}
+		// Граница to включается в отчёт целым днём
+		to = parsed.AddDate(0, 0, 1)
+	}
+
+	if !from.Before(to) {
+		c.Error(apperr.BadRequest("from must be before to"))
+		return
+	}
+
+	report, err := h.repo.Report(ctx, c.MustGet("user_id").(int), from, to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="time-report.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"day", "task_id", "task_title", "seconds", "hours"})
+	for _, row := range report {
+		err := w.Write([]string{
+			row.Day.Format(time.DateOnly),
+			strconv.Itoa(row.TaskID),
+			row.TaskTitle,
+			strconv.FormatInt(row.Seconds, 10),
+			strconv.FormatFloat(float64(row.Seconds)/3600, 'f', 2, 64),
+		})
+		if err != nil {
+			break
+		}
+	}
+	w.Flush()
+
+	// Статус уже отправлен, поэтому оборванную выгрузку можно только записать в журнал
+	if err := w.Error(); err != nil {
+		slog.WarnContext(c.Request.Context(), "failed to write CSV report", "error", err)
+		c.Abort()
+	}
+}
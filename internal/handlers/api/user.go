@@ -4,128 +4,106 @@ import (
 	"net/http"
 	"strconv"
 
-	"github.com/CAATHARSIS/task-tracking/internal/auth"
-	"github.com/CAATHARSIS/task-tracking/internal/config"
-	"github.com/CAATHARSIS/task-tracking/internal/models"
-	user_repo "github.com/CAATHARSIS/task-tracking/internal/repository/user"
-	"github.com/CAATHARSIS/task-tracking/internal/utils"
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type UserHandler struct {
-	repo      *user_repo.UserPostgrtesRepo
-	validator *validator.Validate
+	service *service.UserService
 }
 
-func NewUserHandler(repo *user_repo.UserPostgrtesRepo) *UserHandler {
-	return &UserHandler{
-		repo:      repo,
-		validator: validator.New(),
-	}
+func NewUserHandler(service *service.UserService) *UserHandler {
+	return &UserHandler{service: service}
 }
 
-type BaseAuthRequest struct {
+type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
 }
 
-type RegisterRequest BaseAuthRequest
-type LoginRequest BaseAuthRequest
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
 
+// @Summary Регистрация пользователя
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body api.RegisterRequest true "Email и пароль"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Router /auth/register [post]
 func (h *UserHandler) Register(c *gin.Context) {
 	var req RegisterRequest
-
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
-
-	if err := h.validator.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.BadRequest("Invalid request body"))
 		return
 	}
 
-	_, err := h.repo.GetByEmail(req.Email)
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
-		return
-	}
-
-	hashedPassword, err := utils.HashPassword(req.Password)
+	user, err := h.service.Register(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
-	}
-
-	user := &models.User{
-		Email:        req.Email,
-		PasswordHash: hashedPassword,
-	}
-
-	if err := h.repo.Create(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"id": user.ID, "email": user.Email})
 }
 
+// @Summary Вход по email и паролю
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body api.LoginRequest true "Email и пароль"
+// @Success 200 {object} map[string]string "JWT токен в поле token"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Router /auth/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req LoginRequest
-
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
-
-	if err := h.validator.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	user, err := h.repo.GetByEmail(req.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid email or password"})
-		return
-	}
-
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		c.Error(apperr.BadRequest("Invalid request body"))
 		return
 	}
 
-	cfg, err := config.Load()
+	token, err := h.service.Login(c.Request.Context(), req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Failed to load config"})
-		return
-	}
-
-	s := auth.NewJWTService(cfg)
-	token, err := s.GenerateJWT(user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
 
+// @Summary Получить пользователя
+// @Tags users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} models.User
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperr.BadRequest("Invalid user ID"))
 		return
 	}
 
-	user, err := h.repo.GetById(id)
+	user, err := h.service.Get(c.Request.Context(), c.MustGet("user_id").(int), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(err)
 		return
 	}
 
-	user.PasswordHash = ""
 	c.JSON(http.StatusOK, user)
 }
 
@@ -134,62 +112,60 @@ type UpdateUserRequest struct {
 	Password string `json:"password" validate:"omitempty,min=6"`
 }
 
+// @Summary Изменить email или пароль пользователя
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param request body api.UpdateUserRequest true "Новые email и/или пароль"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"user": "Invalid user ID"})
+		c.Error(apperr.BadRequest("Invalid user ID"))
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
-
-	if err := h.validator.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	user, err := h.repo.GetById(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(apperr.BadRequest("Invalid request body"))
 		return
 	}
 
-	if req.Email != "" {
-		user.Email = req.Email
-	}
-
-	if req.Password != "" {
-		hashedPassword, err := utils.HashPassword(req.Password)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-			return
-		}
-		user.PasswordHash = hashedPassword
-	}
-
-	if err := h.repo.Update(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Update(c.Request.Context(), c.MustGet("user_id").(int), id, req.Email, req.Password); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User updated`"})
 }
 
+// @Summary Удалить пользователя
+// @Tags users
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 500 {object} middleware.ErrorResponse
+// @Security CookieAuth
+// @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apperr.BadRequest("Invalid user ID"))
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	if err := h.service.Delete(c.Request.Context(), c.MustGet("user_id").(int), id); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -1,61 +1,57 @@
 package web
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
 	"github.com/CAATHARSIS/task-tracking/internal/models"
-	board_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board"
-	board_task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board_task"
-	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type BoardHandler struct {
-	repo          *board_repo.BoardPostgresRepo
-	boardTaskRepo *board_task_repo.BoardTaskPostgresRepo
-	taskRepo      *task_repo.TaskPostgresRepo
-	validator     *validator.Validate
+	service     *service.BoardService
+	taskService *service.TaskService
 }
 
-func NewBoardHandler(repo *board_repo.BoardPostgresRepo,
-	boardTaskRepo *board_task_repo.BoardTaskPostgresRepo,
-	taskRepo *task_repo.TaskPostgresRepo) *BoardHandler {
-	v := validator.New()
+func NewBoardHandler(service *service.BoardService, taskService *service.TaskService) *BoardHandler {
 	return &BoardHandler{
-		repo:          repo,
-		boardTaskRepo: boardTaskRepo,
-		taskRepo:      taskRepo,
-		validator:     v,
+		service:     service,
+		taskService: taskService,
 	}
 }
 
 func (h *BoardHandler) ListBoardsPage(c *gin.Context) {
-	userID := c.MustGet("user_id")
+	userID := c.MustGet("user_id").(int)
 
-	boards, err := h.repo.ListByUser(userID.(int))
+	boards, err := h.service.ListByUser(c.Request.Context(), userID, userID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
 			"TemplateName": "boards-list",
-			"error":        err.Error(),
+			"error":        message,
 		})
 		return
 	}
 
-	c.HTML(http.StatusOK, "boards-list.html", gin.H{
+	c.HTML(http.StatusOK, "boards-list.html", pageData(c, gin.H{
 		"TemplateName":    "boards-list",
 		"Boards":          boards,
 		"IsAuthenticated": true,
-	})
+	}))
 }
 
 func (h *BoardHandler) HandleBoardForm(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("user_id").(int)
+	idStr := c.Param("id")
+	isNew := idStr == "" || idStr == "new"
+
 	if c.Request.Method == http.MethodGet {
-		idStr := c.Param("id")
-		if idStr == "" || idStr == "new" {
+		if isNew {
 			c.HTML(http.StatusOK, "boards-form.html", gin.H{
 				"TemplateName": "boards-form",
 				"IsNew":        true,
@@ -69,70 +65,64 @@ func (h *BoardHandler) HandleBoardForm(c *gin.Context) {
 			return
 		}
 
-		task, err := h.repo.GetById(id)
+		board, err := h.service.Get(ctx, userID, id)
 		if err != nil {
-			c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "Board not found"})
+			status, message := errorMessage(err)
+			c.HTML(status, "error.html", gin.H{"error": message})
 			return
 		}
 
 		c.HTML(http.StatusOK, "boards-form.html", gin.H{
 			"TemplateName": "boards-form",
-			"Board":        task,
+			"Board":        board,
+			"WIPLimits":    wipFormValues(board.WIPLimits),
 			"IsNew":        false,
 		})
 		return
 	}
 
-	idStr := c.Param("id")
-	userID := c.MustGet("user_id").(int)
-	isNew := idStr == "" || idStr == "new"
-
-	name := strings.TrimSpace(c.PostForm("name"))
-
-	if name == "" || len(name) > 50 {
-		c.HTML(http.StatusBadRequest, "boards-form.html", gin.H{
-			"error": "Название доски обязательно (макс. 50 символов)",
-			"Task":  &models.Board{Name: name},
-			"IsNew": idStr == "" || idStr == "new",
-		})
-		return
-	}
-
-	if isNew {
-		newBoard := models.Board{
-			Name:      name,
-			UserID:    userID,
-			CreatedAt: time.Now(),
-		}
-
-		if err := h.repo.Create(&newBoard); err != nil {
-			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
-			return
+	req := models.BoardRequest{
+		Name:         c.PostForm("name"),
+		EstimateUnit: models.EstimateUnit(c.PostForm("estimate_unit")),
+	}
+
+	// Форма всегда передаёт все лимиты, пустое поле снимает лимит со статуса
+	var id int
+	limits, err := parseWIPForm(c)
+	if err == nil {
+		req.WIPLimits = limits
+		if isNew {
+			_, err = h.service.Create(ctx, userID, req)
+		} else {
+			if id, err = strconv.Atoi(idStr); err != nil {
+				err = apperr.BadRequest("Invalid board ID")
+			} else {
+				_, err = h.service.Update(ctx, userID, id, formVersion(c), req)
+			}
 		}
-
-		board, err := h.repo.GetById(id)
-		if err != nil {
-			c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "Task not found"})
-			return
+	}
+	if err != nil {
+		status, message := errorMessage(err)
+		formBoard := &models.Board{ID: id, Name: req.Name, EstimateUnit: req.EstimateUnit}
+		data := gin.H{
+			"TemplateName": "boards-form",
+			"error":        message,
+			"Board":        formBoard,
+			"WIPLimits":    wipFormValues(limits),
+			"IsNew":        isNew,
 		}
 
-		if board.UserID != userID {
-			c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
-			return
+		// Доску изменили, пока форма была открыта: показываем текущую версию рядом с введёнными значениями
+		if isStale(err) {
+			if current, err := h.service.Get(ctx, userID, id); err == nil {
+				formBoard.Version = current.Version
+				data["Conflict"] = current
+				data["ConflictWIPLimits"] = wipFormValues(current.WIPLimits)
+			}
 		}
 
-		board.Name = name
-
-		if err := h.repo.Update(board); err != nil {
-			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
-			return
-		}
+		c.HTML(status, "boards-form.html", data)
+		return
 	}
 
 	c.Redirect(http.StatusFound, "/boards")
@@ -143,30 +133,15 @@ func (h *BoardHandler) DeleteBoardWeb(c *gin.Context) {
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
 			"TemplateName": "erorr",
-			"error":        "Invalid task ID",
+			"error":        "Invalid board ID",
 		})
 		return
 	}
 
-	userID := c.MustGet("user_id").(int)
-	board, err := h.repo.GetById(id)
-	if err != nil {
-		c.HTML(http.StatusNotFound, "error.html", gin.H{
-			"error": "Task not found",
-		})
-		return
-	}
-
-	if board.UserID != userID {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{
-			"error": "Access denied",
-		})
-		return
-	}
-
-	if err := h.repo.Delete(id); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": "Failed with deleting",
+	if err := h.service.Delete(c.Request.Context(), c.MustGet("user_id").(int), id, formVersion(c)); err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
+			"error": message,
 		})
 		return
 	}
@@ -175,6 +150,7 @@ func (h *BoardHandler) DeleteBoardWeb(c *gin.Context) {
 }
 
 func (h *BoardHandler) GetBoardPage(c *gin.Context) {
+	ctx := c.Request.Context()
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{
@@ -184,56 +160,55 @@ func (h *BoardHandler) GetBoardPage(c *gin.Context) {
 		return
 	}
 
-	board, err := h.repo.GetById(id)
-	if err != nil {
-		c.HTML(http.StatusNotFound, "error.html", gin.H{
+	userID := c.MustGet("user_id").(int)
+	renderError := func(err error) {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
 			"TemplateName": "boards-view",
-			"error":        err.Error(),
+			"error":        message,
 		})
 	}
 
-	userID := c.MustGet("user_id")
-	if board.UserID != userID.(int) {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{
-			"TemplateName": "boards-view",
-			"error":        "Access denied",
-		})
+	board, err := h.service.Get(ctx, userID, id)
+	if err != nil {
+		renderError(err)
 		return
 	}
 
-	tasksIDs, err := h.boardTaskRepo.GetTasks(id)
+	tasks, err := h.service.Tasks(ctx, userID, id)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"TemplateName": "boards-view",
-			"error":        "Failed to load task",
-		})
+		renderError(err)
+		return
 	}
 
-	var tasks []models.Task
-	for _, taskID := range tasksIDs {
-		task, err := h.taskRepo.GetById(taskID)
-		if err != nil {
-			continue
-		}
-		tasks = append(tasks, *task)
+	userTasks, err := h.taskService.ListByUser(ctx, userID, userID)
+	if err != nil {
+		renderError(err)
+		return
 	}
 
-	userTasks, err := h.taskRepo.ListByUser(userID.(int))
+	summary, err := h.service.Summary(ctx, userID, id)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"TemplateName": "boards-view",
-			"error":        "Failed to load user tasks",
-		})
+		renderError(err)
+		return
+	}
+
+	fields, err := h.service.Fields(ctx, userID, id)
+	if err != nil {
+		renderError(err)
 		return
 	}
 
-	c.HTML(http.StatusOK, "boards-view.html", gin.H{
+	c.HTML(http.StatusOK, "boards-view.html", pageData(c, gin.H{
 		"TemplateName":    "boards-view",
 		"Board":           board,
 		"Tasks":           tasks,
 		"UserTasks":       userTasks,
+		"Summary":         summary.Columns,
+		"BoardFields":     fields,
+		"CustomFields":    customFieldInputs(fields, nil),
 		"IsAuthenticated": true,
-	})
+	}))
 }
 
 func (h *BoardHandler) AddTaskToBoard(c *gin.Context) {
@@ -249,22 +224,9 @@ func (h *BoardHandler) AddTaskToBoard(c *gin.Context) {
 		return
 	}
 
-	userID := c.MustGet("user_id").(int)
-
-	board, err := h.repo.GetById(boardID)
-	if err != nil || board.UserID != userID {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
-		return
-	}
-
-	task, err := h.taskRepo.GetById(taskID)
-	if err != nil || task.UserID != userID {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
-		return
-	}
-
-	if err := h.boardTaskRepo.AddTask(boardID, taskID); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+	if err := h.service.AddTask(c.Request.Context(), c.MustGet("user_id").(int), boardID, taskID); err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{"error": message})
 		return
 	}
 
@@ -272,6 +234,7 @@ func (h *BoardHandler) AddTaskToBoard(c *gin.Context) {
 }
 
 func (h *BoardHandler) CreateAndAddTaskToBoard(c *gin.Context) {
+	ctx := c.Request.Context()
 	boardID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Invalid board ID"})
@@ -280,47 +243,28 @@ func (h *BoardHandler) CreateAndAddTaskToBoard(c *gin.Context) {
 
 	userID := c.MustGet("user_id").(int)
 
-	board, err := h.repo.GetById(boardID)
-	if err != nil || board.UserID != userID {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
-		return
-	}
-
-	title := strings.TrimSpace(c.PostForm("title"))
-	description := strings.TrimSpace(c.PostForm("description"))
-	status := c.PostForm("status")
-
-	if title == "" || len(title) > 100 {
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Название задачи обязательно (макс. 100 символов)"})
-		return
-	}
-
-	if len(description) > 500 {
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Описание не должно превышать 500 символов"})
-		return
+	req := models.TaskRequest{
+		Title:       c.PostForm("title"),
+		Description: c.PostForm("description"),
+		Status:      models.TaskStatus(c.PostForm("status")),
 	}
 
-	if !models.TaskStatus(status).IsValid() {
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Недопустимый статус задачи"})
+	req.Estimate, err = parseEstimate(c.PostForm("estimate"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Оценка должна быть числом"})
 		return
 	}
 
-	newTask := models.Task{
-		Title:       title,
-		Description: description,
-		Status:      models.TaskStatus(status),
-		UserID:      userID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	if err := h.taskRepo.Create(&newTask); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
-		return
+	fields, err := h.service.Fields(ctx, userID, boardID)
+	if err == nil {
+		var values map[int]json.RawMessage
+		if values, err = parseCustomFieldForm(c, fields); err == nil {
+			_, err = h.taskService.CreateOnBoard(ctx, userID, boardID, req, values)
+		}
 	}
-
-	if err := h.boardTaskRepo.AddTask(boardID, newTask.ID); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+	if err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{"error": message})
 		return
 	}
 
@@ -340,18 +284,38 @@ func (h *BoardHandler) RemoveTaskFromBoard(c *gin.Context) {
 		return
 	}
 
-	userID := c.MustGet("user_id").(int)
-
-	board, err := h.repo.GetById(boardID)
-	if err != nil || board.UserID != userID {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
+	if err := h.service.RemoveTask(c.Request.Context(), c.MustGet("user_id").(int), boardID, taskID); err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{"error": message})
 		return
 	}
 
-	if err := h.boardTaskRepo.RemoveTask(boardID, taskID); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
-		return
+	c.Redirect(http.StatusFound, "/boards/"+strconv.Itoa(boardID))
+}
+
+// Читает WIP-лимиты из полей wip_<status> формы
+func parseWIPForm(c *gin.Context) (map[models.TaskStatus]int, error) {
+	limits := make(map[models.TaskStatus]int)
+	for _, status := range models.TaskStatuses {
+		value := strings.TrimSpace(c.PostForm("wip_" + string(status)))
+		if value == "" {
+			continue
+		}
+
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, apperr.BadRequest("WIP-лимит должен быть положительным числом")
+		}
+		limits[status] = limit
 	}
+	return limits, nil
+}
 
-	c.Redirect(http.StatusFound, "/boards/"+strconv.Itoa(boardID))
+// Значения WIP-лимитов для полей формы, ключи - строковые статусы
+func wipFormValues(limits map[models.TaskStatus]int) map[string]int {
+	values := make(map[string]int, len(limits))
+	for status, limit := range limits {
+		values[string(status)] = limit
+	}
+	return values
 }
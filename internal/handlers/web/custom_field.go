@@ -0,0 +1,198 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type CustomFieldHandler struct {
+	repo      repository.CustomFieldRepository
+	boardRepo repository.BoardRepository
+}
+
+func NewCustomFieldHandler(repo repository.CustomFieldRepository, boardRepo repository.BoardRepository) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		repo:      repo,
+		boardRepo: boardRepo,
+	}
+}
+
+func (h *CustomFieldHandler) CreateField(c *gin.Context) {
+	ctx := c.Request.Context()
+	boardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Invalid board ID"})
+		return
+	}
+
+	board, err := h.boardRepo.GetById(ctx, boardID)
+	if err != nil || board.UserID != c.MustGet("user_id").(int) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	fieldType := models.CustomFieldType(c.PostForm("type"))
+
+	var options []string
+	for _, option := range strings.Split(c.PostForm("options"), ",") {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+
+	if name == "" || len(name) > 50 {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Название поля обязательно (макс. 50 символов)"})
+		return
+	}
+
+	switch fieldType {
+	case models.FieldText, models.FieldNumber, models.FieldDate, models.FieldUser:
+		options = nil
+	case models.FieldSingleSelect, models.FieldMultiSelect:
+		if len(options) == 0 {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Для поля выбора нужны варианты через запятую"})
+			return
+		}
+	default:
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Недопустимый тип поля"})
+		return
+	}
+
+	field := models.CustomField{
+		BoardID:  boardID,
+		Name:     name,
+		Type:     fieldType,
+		Options:  options,
+		Required: c.PostForm("required") == "on",
+	}
+
+	if err := h.repo.Create(ctx, &field); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/boards/"+strconv.Itoa(boardID))
+}
+
+func (h *CustomFieldHandler) DeleteField(c *gin.Context) {
+	ctx := c.Request.Context()
+	boardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Invalid board ID"})
+		return
+	}
+
+	fieldID, err := strconv.Atoi(c.Param("field_id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Invalid field ID"})
+		return
+	}
+
+	board, err := h.boardRepo.GetById(ctx, boardID)
+	if err != nil || board.UserID != c.MustGet("user_id").(int) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
+		return
+	}
+
+	field, err := h.repo.GetById(ctx, fieldID)
+	if err != nil || field.BoardID != boardID {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "Field not found"})
+		return
+	}
+
+	if err := h.repo.Delete(ctx, fieldID); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/boards/"+strconv.Itoa(boardID))
+}
+
+// Поле формы задачи вместе с текущим значением
+type customFieldInput struct {
+	Field    *models.CustomField
+	Value    string
+	Selected map[string]bool
+}
+
+func customFieldInputs(fields []*models.CustomField, values []models.CustomFieldValue) []customFieldInput {
+	current := make(map[int]json.RawMessage, len(values))
+	for _, value := range values {
+		current[value.FieldID] = value.Value
+	}
+
+	inputs := make([]customFieldInput, 0, len(fields))
+	for _, field := range fields {
+		input := customFieldInput{Field: field, Selected: make(map[string]bool)}
+		if raw, ok := current[field.ID]; ok {
+			if field.Type == models.FieldMultiSelect {
+				var selected []string
+				json.Unmarshal(raw, &selected)
+				for _, option := range selected {
+					input.Selected[option] = true
+				}
+			} else {
+				var value interface{}
+				json.Unmarshal(raw, &value)
+				input.Value = fmt.Sprint(value)
+				input.Selected[input.Value] = true
+			}
+		}
+		inputs = append(inputs, input)
+	}
+
+	return inputs
+}
+
+/*
+Читает значения пользовательских полей из формы (поля cf_<id>)
+Здесь проверяется только формат чисел и ID пользователей, остальные правила применяет сервис задач
+*/
+func parseCustomFieldForm(c *gin.Context, fields []*models.CustomField) (map[int]json.RawMessage, error) {
+	values := make(map[int]json.RawMessage, len(fields))
+	for _, field := range fields {
+		key := "cf_" + strconv.Itoa(field.ID)
+		value := strings.TrimSpace(c.PostForm(key))
+
+		var raw interface{}
+		switch field.Type {
+		case models.FieldMultiSelect:
+			raw = c.PostFormArray(key)
+		case models.FieldNumber:
+			if value != "" {
+				number, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, apperr.Validation(fmt.Errorf("поле %q должно быть числом", field.Name))
+				}
+				raw = number
+			}
+		case models.FieldUser:
+			if value != "" {
+				userID, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, apperr.Validation(fmt.Errorf("поле %q должно содержать ID пользователя", field.Name))
+				}
+				raw = userID
+			}
+		default:
+			raw = value
+		}
+
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		values[field.ID] = encoded
+	}
+
+	return values, nil
+}
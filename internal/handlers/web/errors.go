@@ -0,0 +1,37 @@
+package web
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+// HTTP-статус и текст ошибки сервиса для страницы, ошибки полей выводятся одной строкой
+func errorMessage(err error) (int, string) {
+	appErr := apperr.From(err)
+	if len(appErr.Fields) == 0 {
+		return appErr.Status(), appErr.Message
+	}
+
+	messages := make([]string, 0, len(appErr.Fields))
+	for _, field := range appErr.Fields {
+		messages = append(messages, field.Message)
+	}
+	return appErr.Status(), strings.Join(messages, "; ")
+}
+
+// Запись изменили после того, как форма была открыта
+func isStale(err error) bool {
+	return apperr.From(err).Code == apperr.CodePreconditionFailed
+}
+
+// Версия записи из скрытого поля формы, 0 - форма отправлена без версии и не проверяется
+func formVersion(c *gin.Context) int {
+	version, err := strconv.Atoi(c.PostForm("version"))
+	if err != nil {
+		return 0
+	}
+	return version
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type SavedViewHandler struct {
+	repo      repository.SavedViewRepository
+	taskRepo  repository.TaskRepository
+	boardRepo repository.BoardRepository
+}
+
+func NewSavedViewHandler(
+	repo repository.SavedViewRepository,
+	taskRepo repository.TaskRepository,
+	boardRepo repository.BoardRepository,
+) *SavedViewHandler {
+	return &SavedViewHandler{
+		repo:      repo,
+		taskRepo:  taskRepo,
+		boardRepo: boardRepo,
+	}
+}
+
+// Загружает закреплённые представления пользователя для навигации
+func (h *SavedViewHandler) PinnedViewsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := c.Get("user_id"); ok {
+			if views, err := h.repo.ListPinned(c.Request.Context(), userID.(int)); err == nil {
+				c.Set("PinnedViews", views)
+			}
+		}
+		c.Next()
+	}
+}
+
+func (h *SavedViewHandler) GetViewPage(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": "Invalid view ID"})
+		return
+	}
+
+	renderError := func(err error) {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
+			"TemplateName": "tasks-list",
+			"error":        message,
+		})
+	}
+
+	// 404 только для отсутствующего представления, сбой базы остаётся ошибкой сервера
+	view, err := h.repo.GetById(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "View not found"})
+		return
+	}
+	if err != nil {
+		renderError(err)
+		return
+	}
+
+	// Фильтр с доской выполняется, только пока пользователь остаётся её участником, даже для владельца
+	userID := c.MustGet("user_id").(int)
+	allowed := view.UserID == userID || view.Shared
+	if allowed && view.Filter.BoardID != 0 {
+		allowed, err = h.boardRepo.IsMember(ctx, view.Filter.BoardID, userID)
+		if err != nil {
+			renderError(err)
+			return
+		}
+	}
+	if !allowed {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
+		return
+	}
+
+	tasks, err := h.taskRepo.ListByFilter(ctx, userID, view.Filter)
+	if err != nil {
+		renderError(err)
+		return
+	}
+
+	c.HTML(http.StatusOK, "tasks-list.html", pageData(c, gin.H{
+		"TemplateName":    "tasks-list",
+		"View":            view,
+		"Tasks":           tasks,
+		"IsAuthenticated": true,
+	}))
+}
+
+// Дополняет данные шаблона общими для всех страниц значениями из контекста
+func pageData(c *gin.Context, data gin.H) gin.H {
+	if views, ok := c.Get("PinnedViews"); ok {
+		data["PinnedViews"] = views
+	}
+	return data
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type failingSavedViews struct {
+	repository.SavedViewRepository
+	err error
+}
+
+func (r failingSavedViews) GetById(context.Context, int) (*models.SavedView, error) {
+	return nil, r.err
+}
+
+func TestGetViewPageErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{"not found", fmt.Errorf("saved view 1: %w", repository.ErrNotFound), http.StatusNotFound, "View not found"},
+		{"database failure", errors.New("connection refused"), http.StatusInternalServerError, "Internal server error"},
+		{"timeout", &repository.TimeoutError{Err: context.DeadlineExceeded}, http.StatusGatewayTimeout, "Request timed out"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewSavedViewHandler(failingSavedViews{err: tt.err}, nil, nil)
+
+			r := gin.New()
+			r.SetHTMLTemplate(template.Must(template.New("error.html").Parse("{{.error}}")))
+			r.GET("/views/:id", func(c *gin.Context) { c.Set("user_id", 1) }, h.GetViewPage)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/views/1", nil))
+			if rec.Code != tt.status || rec.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
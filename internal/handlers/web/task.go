@@ -1,52 +1,126 @@
 package web
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/CAATHARSIS/task-tracking/internal/models"
-	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type TaskHandler struct {
-	repo      *task_repo.TaskPostgresRepo
-	validator *validator.Validate
+	service      *service.TaskService
+	boardService *service.BoardService
 }
 
-func NewTaskHandler(repo *task_repo.TaskPostgresRepo) *TaskHandler {
-	v := validator.New()
-	v.RegisterValidation("taskstatus", func(fl validator.FieldLevel) bool {
-		status := fl.Field().Interface().(models.TaskStatus)
-		return status.IsValid()
-	})
-
+func NewTaskHandler(service *service.TaskService, boardService *service.BoardService) *TaskHandler {
 	return &TaskHandler{
-		repo:      repo,
-		validator: v,
+		service:      service,
+		boardService: boardService,
 	}
 }
 
 func (h *TaskHandler) ListTasksPage(c *gin.Context) {
-	userID := c.MustGet("user_id")
+	h.renderTaskList(c, http.StatusOK, nil)
+}
 
-	tasks, err := h.repo.ListByUser(userID.(int))
+// Список задач с панелью действий над отмеченными задачами и ошибками последнего пакета
+func (h *TaskHandler) renderTaskList(c *gin.Context, status int, bulkErrors []string) {
+	userID := c.MustGet("user_id").(int)
+
+	tasks, err := h.service.ListByUser(c.Request.Context(), userID, userID)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
 			"TemplateName": "tasks-list",
-			"error":        err.Error(),
+			"error":        message,
 		})
 		return
 	}
 
-	c.HTML(http.StatusOK, "tasks-list.html", gin.H{
+	boards, err := h.boardService.ListByUser(c.Request.Context(), userID, userID)
+	if err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
+			"TemplateName": "tasks-list",
+			"error":        message,
+		})
+		return
+	}
+
+	c.HTML(status, "tasks-list.html", pageData(c, gin.H{
 		"TemplateName":    "tasks-list",
 		"Tasks":           tasks,
+		"Boards":          boards,
+		"BulkActions":     true,
+		"BulkErrors":      bulkErrors,
 		"IsAuthenticated": true,
+	}))
+}
+
+/*
+Действие над задачами, отмеченными в списке: смена статуса, добавление на доску, удаление с доски или удаление задач
+Выполняется одним пакетом в режиме best_effort: отмеченные задачи, с которыми действие не удалось,
+перечисляются над списком, остальные изменения сохраняются
+*/
+func (h *TaskHandler) BulkTasksWeb(c *gin.Context) {
+	op := models.BulkOp(c.PostForm("op"))
+	boardID, _ := strconv.Atoi(c.PostForm("board_id"))
+
+	var operations []models.BulkOperation
+	for _, value := range c.PostFormArray("task_ids") {
+		taskID, err := strconv.Atoi(value)
+		if err != nil {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{
+				"error": "Invalid task ID",
+			})
+			return
+		}
+		operations = append(operations, models.BulkOperation{
+			Op:      op,
+			TaskID:  taskID,
+			Status:  models.TaskStatus(c.PostForm("status")),
+			BoardID: boardID,
+		})
+	}
+	if len(operations) == 0 {
+		h.renderTaskList(c, http.StatusBadRequest, []string{"Отметьте хотя бы одну задачу"})
+		return
+	}
+
+	result, err := h.service.Bulk(c.Request.Context(), c.MustGet("user_id").(int), models.BulkTaskRequest{
+		Mode:       models.BulkBestEffort,
+		Operations: operations,
 	})
+	if err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
+			"error": message,
+		})
+		return
+	}
+
+	status := http.StatusOK
+	var bulkErrors []string
+	for _, item := range result.Items {
+		if item.Err == nil {
+			continue
+		}
+		itemStatus, message := errorMessage(item.Err)
+		if len(bulkErrors) == 0 {
+			status = itemStatus
+		}
+		bulkErrors = append(bulkErrors, fmt.Sprintf("Задача #%d: %s", item.TaskID, message))
+	}
+	if len(bulkErrors) > 0 {
+		h.renderTaskList(c, status, bulkErrors)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/tasks")
 }
 
 func (h *TaskHandler) GetTaskPage(c *gin.Context) {
@@ -59,35 +133,31 @@ func (h *TaskHandler) GetTaskPage(c *gin.Context) {
 		return
 	}
 
-	task, err := h.repo.GetById(id)
+	task, err := h.service.Get(c.Request.Context(), c.MustGet("user_id").(int), id)
 	if err != nil {
-		c.HTML(http.StatusNotFound, "error.html", gin.H{
-			"TemplateName": "tasks-view",
-			"error":        "Failed to load task",
-		})
-		return
-	}
-
-	userID, _ := c.Get("user_id")
-	if task.UserID != userID.(int) {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
 			"TemplateName": "tasks-view",
-			"error":        "Access is unavailable",
+			"error":        message,
 		})
 		return
 	}
 
-	c.HTML(http.StatusOK, "tasks-view.html", gin.H{
+	c.HTML(http.StatusOK, "tasks-view.html", pageData(c, gin.H{
 		"TemplateName":    "tasks-view",
 		"Task":            task,
 		"IsAuthenticated": true,
-	})
+	}))
 }
 
 func (h *TaskHandler) HandleTaskForm(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("user_id").(int)
+	idStr := c.Param("id")
+	isNew := idStr == "" || idStr == "new"
+
 	if c.Request.Method == http.MethodGet {
-		idStr := c.Param("id")
-		if idStr == "" || idStr == "new" {
+		if isNew {
 			c.HTML(http.StatusOK, "tasks-form.html", gin.H{
 				"TemplateName": "tasks-form",
 				"IsNew":        true,
@@ -101,96 +171,111 @@ func (h *TaskHandler) HandleTaskForm(c *gin.Context) {
 			return
 		}
 
-		task, err := h.repo.GetById(id)
+		task, err := h.service.Get(ctx, userID, id)
+		if err != nil {
+			status, message := errorMessage(err)
+			c.HTML(status, "error.html", gin.H{"error": message})
+			return
+		}
+
+		fields, err := h.service.Fields(ctx, userID, id)
 		if err != nil {
-			c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "Task not found"})
+			status, message := errorMessage(err)
+			c.HTML(status, "error.html", gin.H{"error": message})
 			return
 		}
 
 		c.HTML(http.StatusOK, "tasks-form.html", gin.H{
 			"TemplateName": "tasks-form",
 			"Task":         task,
+			"CustomFields": customFieldInputs(fields, task.CustomFields),
 			"IsNew":        false,
 		})
 		return
 	}
 
-	idStr := c.Param("id")
-	userID := c.MustGet("user_id").(int)
-	isNew := idStr == "" || idStr == "new"
-
-	title := strings.TrimSpace(c.PostForm("title"))
-	description := strings.TrimSpace(c.PostForm("description"))
-	status := c.PostForm("status")
-
-	if title == "" || len(title) > 100 {
-		c.HTML(http.StatusBadRequest, "tasks-form.html", gin.H{
-			"error": "Название задачи обязательно (макс. 100 символов)",
-			"Task":  &models.Task{Title: title, Description: description, Status: models.TaskStatus(status)},
-			"IsNew": idStr == "" || idStr == "new",
-		})
-		return
+	req := models.TaskRequest{
+		Title:       c.PostForm("title"),
+		Description: c.PostForm("description"),
+		Status:      models.TaskStatus(c.PostForm("status")),
 	}
+	formTask := &models.Task{Title: req.Title, Description: req.Description, Status: req.Status}
 
-	if len(description) > 500 {
-		c.HTML(http.StatusBadRequest, "tasks-form.html", gin.H{
-			"error": "Описание не должно превышать 500 символов",
-			"Task":  &models.Task{Title: title, Description: description, Status: models.TaskStatus(status)},
-			"IsNew": idStr == "" || idStr == "new",
-		})
-		return
-	}
-
-	if !models.TaskStatus(status).IsValid() {
+	estimate, err := parseEstimate(c.PostForm("estimate"))
+	if err != nil {
 		c.HTML(http.StatusBadRequest, "tasks-form.html", gin.H{
-			"error": "Недопустимый статус задачи",
-			"Task":  &models.Task{Title: title, Description: description, Status: models.TaskStatus(status)},
-			"IsNew": idStr == "" || idStr == "new",
+			"error": "Оценка должна быть числом",
+			"Task":  formTask,
+			"IsNew": isNew,
 		})
 		return
 	}
+	req.Estimate = estimate
+	formTask.Estimate = estimate
 
 	if isNew {
-		newTask := models.Task{
-			Title:       title,
-			Description: description,
-			Status:      models.TaskStatus(status),
-			UserID:      userID,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+		var rule *models.RecurrenceRule
+		if frequency := models.RecurrenceFrequency(c.PostForm("recurrence")); frequency.IsValid() {
+			rule = &models.RecurrenceRule{Frequency: frequency, Interval: 1}
 		}
 
-		if err := h.repo.Create(&newTask); err != nil {
-			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+		if _, err := h.service.Create(ctx, userID, req, rule); err != nil {
+			status, message := errorMessage(err)
+			c.HTML(status, "tasks-form.html", gin.H{
+				"error": message,
+				"Task":  formTask,
+				"IsNew": true,
+			})
 			return
 		}
 
-		task, err := h.repo.GetById(id)
-		if err != nil {
-			c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "Task not found"})
-			return
-		}
+		c.Redirect(http.StatusFound, "/tasks")
+		return
+	}
 
-		if task.UserID != userID {
-			c.HTML(http.StatusForbidden, "error.html", gin.H{"error": "Access denied"})
-			return
-		}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{"error": err.Error()})
+		return
+	}
 
-		task.Title = title
-		task.Description = description
-		task.Status = models.TaskStatus(status)
-		task.UpdatedAt = time.Now()
+	fields, err := h.service.Fields(ctx, userID, id)
+	if err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{"error": message})
+		return
+	}
 
-		if err := h.repo.Update(task); err != nil {
-			c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error()})
-			return
+	values, err := parseCustomFieldForm(c, fields)
+	if err == nil {
+		_, err = h.service.Update(ctx, userID, id, formVersion(c), req, service.TaskUpdateOptions{
+			CustomFields:  values,
+			ApplyToSeries: c.PostForm("scope") == "series",
+		})
+	}
+	if err != nil {
+		status, message := errorMessage(err)
+		formTask.ID = id
+		data := gin.H{
+			"TemplateName": "tasks-form",
+			"error":        message,
+			"Task":         formTask,
+			"CustomFields": customFieldInputs(fields, nil),
+			"IsNew":        false,
 		}
+
+		// Задачу изменили, пока форма была открыта: рядом с введёнными значениями
+		// показывается текущая версия, повторная отправка формы сохраняет изменения поверх неё
+		if isStale(err) {
+			if current, err := h.service.Get(ctx, userID, id); err == nil {
+				formTask.SeriesID = current.SeriesID
+				formTask.Version = current.Version
+				data["Conflict"] = current
+			}
+		}
+
+		c.HTML(status, "tasks-form.html", data)
+		return
 	}
 
 	c.Redirect(http.StatusFound, "/tasks")
@@ -206,28 +291,28 @@ func (h *TaskHandler) DeleteTaskWeb(c *gin.Context) {
 		return
 	}
 
-	userID := c.MustGet("user_id").(int)
-	task, err := h.repo.GetById(id)
-	if err != nil {
-		c.HTML(http.StatusNotFound, "error.html", gin.H{
-			"error": "Task not found",
+	if err := h.service.Delete(c.Request.Context(), c.MustGet("user_id").(int), id, formVersion(c)); err != nil {
+		status, message := errorMessage(err)
+		c.HTML(status, "error.html", gin.H{
+			"error": message,
 		})
 		return
 	}
 
-	if task.UserID != userID {
-		c.HTML(http.StatusForbidden, "error.html", gin.H{
-			"error": "Access denied",
-		})
-		return
+	c.Redirect(http.StatusFound, "/tasks")
+}
+
+// Пустая оценка означает, что задача не оценена, допустимый диапазон проверяет сервис задач
+func parseEstimate(value string) (*float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
 	}
 
-	if err := h.repo.Delete(id); err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": "Failed with deleting",
-		})
-		return
+	estimate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
 	}
 
-	c.Redirect(http.StatusFound, "/tasks")
+	return &estimate, nil
 }
@@ -0,0 +1,197 @@
+/*
+Проверки готовности приложения для /readyz: база данных, Redis и актуальность миграций
+
+Каждая проверка выполняется с собственным таймаутом, отчёт содержит результат по каждой зависимости.
+Во время плавной остановки приложение сообщает о неготовности, чтобы балансировщик перестал слать запросы
+*/
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/redis/go-redis/v9"
+)
+
+// Состояния отчёта и отдельных проверок
+const (
+	StatusOK           = "ok"
+	StatusFailed       = "failed"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Проверка зависимости: ошибка означает неготовность, details попадают в отчёт
+type Check func(ctx context.Context) (details map[string]any, err error)
+
+type CheckReport struct {
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	DurationMS float64        `json:"duration_ms"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckReport `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type Checker struct {
+	timeout      time.Duration
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Добавляет проверку, вызывается до начала обслуживания запросов
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Переводит приложение в состояние остановки: дальше Ready сообщает о неготовности
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+/*
+Выполняет проверки параллельно и собирает отчёт
+nil-проверяющий готов всегда; во время остановки проверки всё равно выполняются,
+чтобы отчёт показывал состояние зависимостей
+*/
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: map[string]CheckReport{}}
+	if c == nil {
+		return report
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.run(ctx, nc.check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFailed
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckReport {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := check(ctx)
+	result := CheckReport{
+		Status:     StatusOK,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:    details,
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Доступность базы данных
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, db.PingContext(ctx)
+	}
+}
+
+func Redis(client *redis.Client) Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, client.Ping(ctx).Err()
+	}
+}
+
+/*
+Версия схемы в таблице schema_migrations не ниже последней миграции из sourceURL
+и последняя миграция применена без ошибок (dirty = false).
+Более новая схема не ошибка: при поэтапном обновлении новый экземпляр уже применил свои миграции,
+а старые экземпляры продолжают обслуживать запросы, пока их не заменят.
+Последняя версия определяется один раз: файлы миграций не меняются во время работы приложения
+*/
+func Migrations(db *sql.DB, sourceURL string) (Check, error) {
+	latest, err := latestVersion(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return func(ctx context.Context) (map[string]any, error) {
+		var (
+			version uint
+			dirty   bool
+		)
+		err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return map[string]any{"expected": latest}, errors.New("no migrations applied")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		details := map[string]any{"version": version, "expected": latest, "dirty": dirty}
+		switch {
+		case dirty:
+			return details, fmt.Errorf("migration %d failed and left the schema dirty", version)
+		case version < latest:
+			return details, fmt.Errorf("schema version %d, expected at least %d", version, latest)
+		}
+		return details, nil
+	}, nil
+}
+
+func latestVersion(sourceURL string) (uint, error) {
+	driver, err := source.Open(sourceURL)
+	if err != nil {
+		return 0, err
+	}
+	defer driver.Close()
+
+	version, err := driver.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := driver.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
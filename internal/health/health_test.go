@@ -0,0 +1,103 @@
+package health_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/health"
+	"github.com/CAATHARSIS/task-tracking/pkg/database"
+)
+
+const migrationsPath = "file://../../migrations/sqlite"
+
+func TestDatabaseAndMigrations(t *testing.T) {
+	db, err := database.NewSQLiteDB(&config.Config{
+		DBPath:               filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsPath: migrationsPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := health.Migrations(db, migrationsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", health.Database(db))
+	checker.Add("migrations", migrations)
+
+	report := checker.Ready(context.Background())
+	if report.Status != health.StatusOK {
+		t.Fatalf("expected ready report, got %+v", report)
+	}
+	if details := report.Checks["migrations"].Details; details["version"] != uint(3) || details["expected"] != uint(3) {
+		t.Errorf("expected schema version 3, got %v", details)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		update string
+		want   string
+	}{
+		{"behind", `UPDATE schema_migrations SET version = 2`, "schema version 2, expected at least 3"},
+		// Схему уже обновил экземпляр новой версии приложения
+		{"ahead", `UPDATE schema_migrations SET version = 4`, ""},
+		{"dirty", `UPDATE schema_migrations SET version = 3, dirty = 1`, "migration 3 failed and left the schema dirty"},
+		{"empty", `DELETE FROM schema_migrations`, "no migrations applied"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.Exec(tt.update); err != nil {
+				t.Fatal(err)
+			}
+			report := checker.Ready(context.Background())
+			want := health.StatusFailed
+			if tt.want == "" {
+				want = health.StatusOK
+			}
+			if report.Status != want {
+				t.Errorf("expected %s report, got %q", want, report.Status)
+			}
+			if got := report.Checks["migrations"].Error; got != tt.want {
+				t.Errorf("expected error %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	db.Close()
+	if check := checker.Ready(context.Background()).Checks["database"]; check.Status != health.StatusFailed {
+		t.Errorf("expected closed database to fail the check, got %+v", check)
+	}
+}
+
+func TestCheckerShutdown(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("dependency", func(context.Context) (map[string]any, error) { return nil, nil })
+
+	if report := checker.Ready(context.Background()); report.Status != health.StatusOK {
+		t.Fatalf("expected ready report, got %q", report.Status)
+	}
+	checker.Shutdown()
+	report := checker.Ready(context.Background())
+	if report.Status != health.StatusShuttingDown {
+		t.Errorf("expected shutting down report, got %q", report.Status)
+	}
+	if report.Checks["dependency"].Status != health.StatusOK {
+		t.Errorf("expected dependency state in the report, got %+v", report.Checks)
+	}
+
+	var nilChecker *health.Checker
+	if report := nilChecker.Ready(context.Background()); report.Status != health.StatusOK {
+		t.Errorf("expected nil checker to be ready, got %q", report.Status)
+	}
+}
+
+func TestMigrationsUnknownSource(t *testing.T) {
+	if _, err := health.Migrations(nil, "file://does-not-exist"); err == nil {
+		t.Fatal("expected error for missing migrations")
+	}
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestRequestIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelInfo, FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	logger.With("component", "test").ErrorContext(ctx, "query failed", "error", "boom")
+	logger.DebugContext(ctx, "below level")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != "abc123" || entry["component"] != "test" || entry["level"] != "ERROR" {
+		t.Fatalf("unexpected record %v", entry)
+	}
+
+	buf.Reset()
+	logger.Info("no request")
+	entry = nil
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entry["request_id"]; ok {
+		t.Fatalf("expected no request_id outside a request, got %v", entry)
+	}
+
+	if _, err := New(&buf, slog.LevelInfo, "xml"); err == nil {
+		t.Fatal("expected unknown format to be rejected")
+	}
+}
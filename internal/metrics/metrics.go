@@ -0,0 +1,128 @@
+/*
+Метрики Prometheus: длительность HTTP-запросов, состояние пула соединений с базой,
+попадания в кэш и бизнес-счётчики (созданные задачи, смены статусов, входы)
+
+Коллекторы регистрируются в собственном реестре пакета, который отдаёт Handler
+*/
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "task_tracking"
+
+// Исходы входа для счётчика Logins
+const (
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// Длительность HTTP-запросов по методу, шаблону маршрута и статусу ответа
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	TasksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_created_total",
+		Help:      "Tasks created by users and by the recurrence scheduler.",
+	})
+
+	TaskStatusTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_status_transitions_total",
+		Help:      "Task status changes by previous and new status.",
+	}, []string{"from", "to"})
+
+	// Входы по исходу: succeeded или failed (неверный email или пароль)
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		TasksCreated,
+		TaskStatusTransitions,
+		Logins,
+	)
+	// Оба исхода видны с нулевым значением до первого входа
+	Logins.WithLabelValues(LoginSucceeded)
+	Logins.WithLabelValues(LoginFailed)
+}
+
+// Статистика пула соединений из db.Stats(), name попадает в метку db_name
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Попадания и промахи кэша списков задач из cache.Stats()
+func RegisterCache(c *cache.Cache) error {
+	return registry.Register(cacheCollector{cache: c})
+}
+
+/*
+Отдаёт метрики в формате Prometheus
+Если задан username, запрос должен содержать те же логин и пароль в заголовке basic auth
+*/
+func Handler(username, password string) http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if username == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || !equal(user, username) || !equal(pass, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+var cacheRequests = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "cache", "requests_total"),
+	"Task list cache lookups by list kind and result.",
+	[]string{"kind", "result"}, nil,
+)
+
+// Счётчики уже ведёт сам кэш, коллектор только читает их при сборе метрик
+type cacheCollector struct {
+	cache *cache.Cache
+}
+
+func (c cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheRequests
+}
+
+func (c cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for kind, counters := range c.cache.Stats() {
+		ch <- prometheus.MustNewConstMetric(cacheRequests, prometheus.CounterValue, float64(counters.Hits), kind, "hit")
+		ch <- prometheus.MustNewConstMetric(cacheRequests, prometheus.CounterValue, float64(counters.Misses), kind, "miss")
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+)
+
+func TestCacheStats(t *testing.T) {
+	c := cache.New(cache.NewMemoryStore(), time.Hour, time.Second)
+	if err := RegisterCache(c); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func() ([]int, error) { return []int{1}, nil }
+	for range 3 {
+		if _, err := cache.Load(context.Background(), c, cache.UserTasks, 1, load); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body := scrape(t, Handler("", ""))
+	for _, line := range []string{
+		`task_tracking_cache_requests_total{kind="user_tasks",result="hit"} 2`,
+		`task_tracking_cache_requests_total{kind="user_tasks",result="miss"} 1`,
+		`task_tracking_cache_requests_total{kind="board_tasks",result="miss"} 0`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected metrics to contain %q", line)
+		}
+	}
+}
+
+func TestHandlerBasicAuth(t *testing.T) {
+	handler := Handler("prometheus", "secret")
+
+	for _, tt := range []struct {
+		name, username, password string
+		status                   int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"wrong password", "prometheus", "guess", http.StatusUnauthorized},
+		{"valid credentials", "prometheus", "secret", http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+type ErrorBody struct {
+	Code      apperr.Code         `json:"code"`
+	Message   string              `json:"message"`
+	Details   []apperr.FieldError `json:"details,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+// Единый формат ошибки API
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+/*
+Превращает последнюю ошибку, переданную обработчиком через c.Error, в ответ ErrorResponse
+Внутренние ошибки и таймауты логируются, клиент получает только общий текст
+*/
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		respondErrors(c)
+	}
+}
+
+// Отвечает последней ошибкой из c.Errors, если обработчик ещё ничего не записал
+func respondErrors(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	err := apperr.From(c.Errors.Last().Err)
+	if err.Code == apperr.CodeInternal || err.Code == apperr.CodeTimeout {
+		slog.ErrorContext(c.Request.Context(), "request failed",
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"code", err.Code,
+			"error", err.Err,
+		)
+	}
+
+	RespondError(c, err)
+}
+
+func RespondError(c *gin.Context, err *apperr.Error) {
+	c.AbortWithStatusJSON(err.Status(), ErrorResponse{Error: NewErrorBody(c, err)})
+}
+
+// Тело ошибки для ответов, которые сообщают об ошибках отдельных элементов запроса
+func NewErrorBody(c *gin.Context, err *apperr.Error) ErrorBody {
+	return ErrorBody{
+		Code:      err.Code,
+		Message:   err.Message,
+		Details:   err.Fields,
+		RequestID: c.GetString(RequestIDKey),
+	}
+}
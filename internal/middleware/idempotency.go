@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// Выставляется в ответе, повторённом по ключу идемпотентности
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Заголовки ответа, которые сохраняются вместе с телом для повтора
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Сроки ключей идемпотентности и ограничение тела запроса с ключом
+type IdempotencyOptions struct {
+	// Срок хранения сохранённого ответа
+	TTL time.Duration
+	// Срок резерва ключа на время выполнения запроса: если экземпляр приложения упал, не сохранив ответ,
+	// ключ освобождается по его истечении, а не через TTL
+	Lease time.Duration
+	// Тело запроса читается в память целиком, большее отклоняется с 413
+	MaxBodySize int64
+}
+
+/*
+Повтор запроса POST, PATCH или DELETE с тем же заголовком Idempotency-Key получает сохранённый ответ,
+а сам запрос второй раз не выполняется. Ключ принадлежит пользователю из токена,
+поэтому middleware подключается после JWT. Тот же ключ с другим методом, путём или телом отклоняется с 422,
+повтор, пришедший до окончания первого запроса, - с 409.
+Ответы 5xx и паника обработчика не сохраняются: ключ освобождается, и запрос можно повторить
+*/
+func Idempotency(keys repository.IdempotencyRepository, opts IdempotencyOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			RespondError(c, apperr.BadRequest("Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, opts.MaxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				RespondError(c, apperr.New(apperr.CodePayloadTooLarge,
+					fmt.Sprintf("Request body with Idempotency-Key must be at most %d bytes", tooLarge.Limit)))
+				return
+			}
+			RespondError(c, apperr.BadRequest("Failed to read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := &models.IdempotencyRecord{
+			UserID:      c.MustGet("user_id").(int),
+			Key:         key,
+			Fingerprint: fingerprint(c.Request, body),
+			ExpiresAt:   time.Now().Add(opts.Lease),
+		}
+		existing, err := keys.Reserve(c.Request.Context(), record)
+		if err != nil {
+			if apperr.From(err).Code == apperr.CodeConflict {
+				err = apperr.Conflict("A request with this Idempotency-Key is still being processed")
+			}
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			replay(c, record, existing)
+			return
+		}
+
+		// Клиент мог уйти, не дождавшись ответа, а ключ всё равно нужно сохранить или освободить
+		ctx := context.WithoutCancel(c.Request.Context())
+		release := func() {
+			if err := keys.Release(ctx, record.UserID, record.Key); err != nil {
+				slog.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+			}
+		}
+		// Панику обрабатывает Recovery выше по цепочке, здесь только снимается резерв
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		// Ошибку обработчика нужно записать здесь, иначе в сохранённый ответ не попадёт её тело
+		respondErrors(c)
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == apperr.StatusClientClosedRequest {
+			release()
+			return
+		}
+
+		record.StatusCode = status
+		record.Header = make(map[string]string, len(replayedHeaders))
+		for _, name := range replayedHeaders {
+			if value := writer.Header().Get(name); value != "" {
+				record.Header[name] = value
+			}
+		}
+		record.Body = writer.body.Bytes()
+		record.ExpiresAt = time.Now().Add(opts.TTL)
+		if err := keys.Complete(ctx, record); err != nil {
+			slog.ErrorContext(ctx, "failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+func replay(c *gin.Context, record, existing *models.IdempotencyRecord) {
+	switch {
+	case existing.Fingerprint != record.Fingerprint:
+		RespondError(c, apperr.New(apperr.CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request"))
+	case !existing.Completed:
+		RespondError(c, apperr.Conflict("A request with this Idempotency-Key is still being processed"))
+	default:
+		for name, value := range existing.Header {
+			c.Header(name, value)
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Writer.WriteHeader(existing.StatusCode)
+		c.Writer.WriteHeaderNow()
+		c.Writer.Write(existing.Body)
+		c.Abort()
+	}
+}
+
+func isMutating(method string) bool {
+	return method == http.MethodPost || method == http.MethodPatch || method == http.MethodDelete
+}
+
+// Хеш метода, пути с параметрами и тела запроса
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Копирует тело ответа, чтобы сохранить его для повтора
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
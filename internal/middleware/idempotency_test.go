@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	memory_repo "github.com/CAATHARSIS/task-tracking/internal/repository/memory"
+	"github.com/gin-gonic/gin"
+)
+
+func newIdempotencyTest(t *testing.T, opts IdempotencyOptions, handler gin.HandlerFunc) (*gin.Engine, *memory_repo.IdempotencyMemoryRepo, int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory_repo.NewStore()
+	user := &models.User{Email: "alice@example.com"}
+	if err := memory_repo.NewUserMemoryRepo(store).Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	keys := memory_repo.NewIdempotencyMemoryRepo(store)
+
+	r := gin.New()
+	r.Use(Recovery(), func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, Idempotency(keys, opts))
+	r.POST("/tasks", handler)
+	return r, keys, user.ID
+}
+
+func postWithKey(r *gin.Engine, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(`{"title":"Buy milk"}`))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+// Паника обработчика снимает резерв, и повтор с тем же ключом выполняется заново
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	calls := 0
+	r, _, _ := newIdempotencyTest(t, IdempotencyOptions{TTL: time.Hour, Lease: time.Minute, MaxBodySize: 1 << 10}, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	if rec := postWithKey(r, "create-1"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected panic to be answered with 500, got %d", rec.Code)
+	}
+	if rec := postWithKey(r, "create-1"); rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("expected retry after panic to be executed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if calls != 2 {
+		t.Fatalf("expected handler to run twice, got %d", calls)
+	}
+}
+
+// Резерв действует Lease, сохранённый ответ - TTL
+func TestIdempotencyLeaseAndTTL(t *testing.T) {
+	var keys *memory_repo.IdempotencyMemoryRepo
+	var userID int
+	var pending *models.IdempotencyRecord
+	r, keys, userID := newIdempotencyTest(t, IdempotencyOptions{TTL: time.Hour, Lease: time.Minute, MaxBodySize: 1 << 10}, func(c *gin.Context) {
+		// Второй запрос с тем же ключом видит резерв первого
+		existing, err := keys.Reserve(c.Request.Context(), &models.IdempotencyRecord{UserID: userID, Key: "create-1", ExpiresAt: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Error(err)
+		}
+		pending = existing
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	start := time.Now()
+	if rec := postWithKey(r, "create-1"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected task to be created, got %d", rec.Code)
+	}
+	if pending == nil || pending.Completed {
+		t.Fatalf("expected pending reservation, got %+v", pending)
+	}
+	if lease := pending.ExpiresAt.Sub(start); lease < time.Minute || lease > 2*time.Minute {
+		t.Errorf("expected reservation to expire after the lease, got %s", lease)
+	}
+
+	completed, err := keys.Reserve(context.Background(), &models.IdempotencyRecord{UserID: userID, Key: "create-1", ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed == nil || !completed.Completed || completed.ExpiresAt.Sub(start) < time.Hour {
+		t.Fatalf("expected stored response to be kept for the TTL, got %+v", completed)
+	}
+}
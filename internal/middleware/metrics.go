@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Запросы к неизвестным адресам сводятся в одну метку, чтобы сканеры не раздували число рядов
+const unmatchedRoute = "unmatched"
+
+// Замеряет длительность запроса по шаблону маршрута (/api/tasks/:id), а не по фактическому пути
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// Правила ограничения частоты запросов, нулевое правило или отсутствие хранилища ничего не ограничивает
+type RateLimits struct {
+	Store ratelimit.Store
+	// Вход и регистрация с одного IP-адреса
+	AuthIP ratelimit.Rule
+	// Попытки входа в одну учётную запись
+	LoginEmail ratelimit.Rule
+	// Запросы пользователя к защищённому API
+	APIUser ratelimit.Rule
+}
+
+// Отвечает на отклонённый запрос: JSON для API, страница для веб-форм
+type ErrorResponder func(c *gin.Context, err *apperr.Error)
+
+func (l RateLimits) ByIP(respond ErrorResponder) gin.HandlerFunc {
+	return RateLimit(l.Store, "auth-ip", l.AuthIP, clientIP, respond)
+}
+
+func (l RateLimits) ByLoginEmail(respond ErrorResponder) gin.HandlerFunc {
+	return RateLimit(l.Store, "login-email", l.LoginEmail, loginEmail, respond)
+}
+
+// Подключается после JWT, которое кладёт в контекст user_id
+func (l RateLimits) ByUser(respond ErrorResponder) gin.HandlerFunc {
+	return RateLimit(l.Store, "api-user", l.APIUser, func(c *gin.Context) string {
+		return strconv.Itoa(c.MustGet("user_id").(int))
+	}, respond)
+}
+
+/*
+Пропускает не больше rule.Limit запросов с одним ключом за скользящее окно rule.Window
+Ключ запроса возвращает key, пустой ключ не учитывается. Ответ получает заголовки RateLimit-*
+по самому строгому из сработавших правил, отклонённый запрос - 429 с Retry-After.
+Если хранилище недоступно, запрос пропускается: сбой Redis не должен закрывать вход в приложение
+*/
+func RateLimit(store ratelimit.Store, name string, rule ratelimit.Rule, key func(c *gin.Context) string, respond ErrorResponder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil || !rule.Enabled() {
+			c.Next()
+			return
+		}
+		value := key(c)
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		result, err := store.Allow(c.Request.Context(), name+":"+value, rule)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "rate limit is not checked", "rule", name, "error", err)
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, result)
+		if !result.Allowed {
+			c.Header(RetryAfterHeader, strconv.Itoa(seconds(result)))
+			respond(c, apperr.TooManyRequests("Too many requests, try again later"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Заголовки уже выставлены более строгим правилом, если у него осталось меньше запросов
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	if current := c.Writer.Header().Get(RateLimitRemainingHeader); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining < result.Remaining {
+			return
+		}
+	}
+	c.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+	c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	c.Header(RateLimitResetHeader, strconv.Itoa(seconds(result)))
+}
+
+// Время до сброса в целых секундах с округлением вверх, чтобы повтор не пришёл раньше срока
+func seconds(result ratelimit.Result) int {
+	return max(int(math.Ceil(result.Reset.Seconds())), 1)
+}
+
+func clientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// Email из JSON или формы входа; тело JSON возвращается в запрос для обработчика
+func loginEmail(c *gin.Context) string {
+	if c.ContentType() != "application/json" {
+		return normalizeEmail(c.PostForm("email"))
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	json.Unmarshal(body, &req)
+	return normalizeEmail(req.Email)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
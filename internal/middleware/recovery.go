@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/gin-gonic/gin"
+)
+
+// Перехватывает панику обработчика и записывает её со стеком в журнал, для API отвечает в формате ErrorResponse
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "panic recovered",
+			"panic", fmt.Sprint(recovered),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"stack", string(debug.Stack()),
+		)
+
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			RespondError(c, apperr.New(apperr.CodeInternal, "Internal server error"))
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// Ответ на неизвестный маршрут API в формате ErrorResponse
+func NotFound() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			RespondError(c, apperr.NotFound("Route not found"))
+			return
+		}
+		c.String(http.StatusNotFound, "404 page not found")
+	}
+}
@@ -2,16 +2,29 @@ package models
 
 import "time"
 
+type EstimateUnit string
+
+// Единицы оценки задач на доске
+const (
+	EstimatePoints EstimateUnit = "points"
+	EstimateHours  EstimateUnit = "hours"
+)
+
 /*
 Поле name обязательно для заполнения и должно быть длиной от 3 до 50 символов
 Поле userId - внешний ключ для связи с пользователем
+Поле wipLimits - максимальное число задач доски в каждом статусе
+Поле version растёт при каждом изменении и служит ETag для оптимистичной блокировки
 */
 type Board struct {
-	ID         int       `json:"id"`
-	Name       string    `json:"name" validate:"required,min=3,max=50"`
-	UserID     int       `json:"user_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdateddAt time.Time `json:"updated_at"`
+	ID           int                `json:"id"`
+	Name         string             `json:"name" validate:"required,min=3,max=50"`
+	UserID       int                `json:"user_id"`
+	EstimateUnit EstimateUnit       `json:"estimate_unit"`
+	WIPLimits    map[TaskStatus]int `json:"wip_limits,omitempty"`
+	Version      int                `json:"version"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdateddAt   time.Time          `json:"updated_at"`
 }
 
 // Отношение многие ко многим
@@ -21,5 +34,24 @@ type BoardTask struct {
 }
 
 type BoardRequest struct {
-	Name       string    `json:"name" validate:"required,min=3,max=50"`
-}
\ No newline at end of file
+	Name         string             `json:"name" validate:"required,min=3,max=50"`
+	EstimateUnit EstimateUnit       `json:"estimate_unit" validate:"omitempty,oneof=points hours"`
+	WIPLimits    map[TaskStatus]int `json:"wip_limits" validate:"dive,keys,oneof=todo in_progress done,endkeys,min=1"`
+}
+
+// Итоги по колонке статуса доски
+type BoardColumnSummary struct {
+	Status   TaskStatus `json:"status"`
+	Tasks    int        `json:"tasks"`
+	Estimate float64    `json:"estimate"`
+	WIPLimit int        `json:"wip_limit,omitempty"`
+}
+
+type BoardSummary struct {
+	EstimateUnit EstimateUnit          `json:"estimate_unit"`
+	Columns      []*BoardColumnSummary `json:"columns"`
+}
+
+func (u EstimateUnit) IsValid() bool {
+	return u == EstimatePoints || u == EstimateHours
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+type BulkOp string
+
+// Операции над задачами, доступные в пакетном запросе
+const (
+	BulkSetStatus       BulkOp = "set_status"
+	BulkAddToBoard      BulkOp = "add_to_board"
+	BulkRemoveFromBoard BulkOp = "remove_from_board"
+	BulkMove            BulkOp = "move"
+	BulkDelete          BulkOp = "delete"
+	BulkLabel           BulkOp = "label"
+	BulkUnlabel         BulkOp = "unlabel"
+)
+
+type BulkMode string
+
+const (
+	// Либо выполняются все операции, либо ни одна
+	BulkAtomic BulkMode = "atomic"
+	// Неудачные операции откатываются по отдельности, остальные сохраняются
+	BulkBestEffort BulkMode = "best_effort"
+)
+
+/*
+Одна операция пакетного запроса над задачей taskId
+Поле status нужно для set_status, boardId - для add_to_board и remove_from_board,
+fromBoardId и toBoardId - для move, fieldId и labels - для label и unlabel (поле типа multi_select).
+Поле version для set_status и delete работает как If-Match: 0 - версия не проверяется
+*/
+type BulkOperation struct {
+	Op          BulkOp     `json:"op"`
+	TaskID      int        `json:"task_id"`
+	Version     int        `json:"version,omitempty"`
+	Status      TaskStatus `json:"status,omitempty"`
+	BoardID     int        `json:"board_id,omitempty"`
+	FromBoardID int        `json:"from_board_id,omitempty"`
+	ToBoardID   int        `json:"to_board_id,omitempty"`
+	FieldID     int        `json:"field_id,omitempty"`
+	Labels      []string   `json:"labels,omitempty"`
+}
+
+// Пакет операций выполняется в одной транзакции, по умолчанию в режиме atomic
+type BulkTaskRequest struct {
+	Mode       BulkMode        `json:"mode,omitempty" validate:"omitempty,oneof=atomic best_effort"`
+	Operations []BulkOperation `json:"operations" validate:"required,min=1,max=200"`
+}
+
+type BulkItemStatus string
+
+// Итог отдельной операции пакета
+const (
+	BulkItemOK     BulkItemStatus = "ok"
+	BulkItemFailed BulkItemStatus = "failed"
+	// Операция выполнилась, но пакет atomic откатился из-за другой операции
+	BulkItemRolledBack BulkItemStatus = "rolled_back"
+	// Операция не выполнялась: пакет atomic остановился на предыдущей ошибке
+	BulkItemSkipped BulkItemStatus = "skipped"
+)
+
+/*
+Значение поля меток после добавления или удаления меток, nil - меток не осталось
+Повторы убирает нормализация значения по полю
+*/
+func MergeLabels(current json.RawMessage, labels []string, remove bool) json.RawMessage {
+	var values []string
+	json.Unmarshal(current, &values)
+
+	result := make([]string, 0, len(values)+len(labels))
+	for _, value := range values {
+		if !remove || !slices.Contains(labels, value) {
+			result = append(result, value)
+		}
+	}
+	if !remove {
+		result = append(result, labels...)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+
+	raw, _ := json.Marshal(result)
+	return raw
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type CustomFieldType string
+
+// Типы пользовательских полей доски
+const (
+	FieldText         CustomFieldType = "text"
+	FieldNumber       CustomFieldType = "number"
+	FieldDate         CustomFieldType = "date"
+	FieldSingleSelect CustomFieldType = "single_select"
+	FieldMultiSelect  CustomFieldType = "multi_select"
+	FieldUser         CustomFieldType = "user"
+)
+
+/*
+Пользовательское поле, определённое на доске
+Поле options обязательно для single_select и multi_select и задаёт допустимые значения
+Значение поля типа user - идентификатор пользователя
+*/
+type CustomField struct {
+	ID        int             `json:"id"`
+	BoardID   int             `json:"board_id"`
+	Name      string          `json:"name"`
+	Type      CustomFieldType `json:"type"`
+	Options   []string        `json:"options,omitempty"`
+	Required  bool            `json:"required"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CustomFieldRequest struct {
+	Name     string          `json:"name" validate:"required,min=1,max=50"`
+	Type     CustomFieldType `json:"type" validate:"required,oneof=text number date single_select multi_select user"`
+	Options  []string        `json:"options" validate:"max=50,dive,required,max=50"`
+	Required bool            `json:"required"`
+}
+
+// Значение пользовательского поля задачи вместе с описанием поля
+type CustomFieldValue struct {
+	FieldID int             `json:"field_id"`
+	Name    string          `json:"name"`
+	Type    CustomFieldType `json:"type"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// Значение в виде текста для отображения в шаблонах
+func (v CustomFieldValue) Display() string {
+	var value interface{}
+	if err := json.Unmarshal(v.Value, &value); err != nil {
+		return string(v.Value)
+	}
+
+	if list, ok := value.([]interface{}); ok {
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	return fmt.Sprint(value)
+}
+
+func (t CustomFieldType) IsSelect() bool {
+	return t == FieldSingleSelect || t == FieldMultiSelect
+}
+
+/*
+Проверяет значение по типу поля и возвращает его в каноническом виде
+Пустое значение (null, пустая строка или пустой список) возвращается как nil
+*/
+func (f *CustomField) Normalize(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return f.empty()
+	}
+
+	switch f.Type {
+	case FieldText:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, f.errorf("must be a string")
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return f.empty()
+		}
+		if len(value) > 500 {
+			return nil, f.errorf("must be at most 500 characters")
+		}
+		return json.Marshal(value)
+
+	case FieldNumber:
+		var value float64
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, f.errorf("must be a number")
+		}
+		return json.Marshal(value)
+
+	case FieldDate:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, f.errorf("must be a date in YYYY-MM-DD format")
+		}
+		if value == "" {
+			return f.empty()
+		}
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return nil, f.errorf("must be a date in YYYY-MM-DD format")
+		}
+		return json.Marshal(value)
+
+	case FieldSingleSelect:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, f.errorf("must be one of the options")
+		}
+		if value == "" {
+			return f.empty()
+		}
+		if !slices.Contains(f.Options, value) {
+			return nil, f.errorf("must be one of: %s", strings.Join(f.Options, ", "))
+		}
+		return json.Marshal(value)
+
+	case FieldMultiSelect:
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, f.errorf("must be a list of options")
+		}
+		if len(values) == 0 {
+			return f.empty()
+		}
+		for _, value := range values {
+			if !slices.Contains(f.Options, value) {
+				return nil, f.errorf("must contain only: %s", strings.Join(f.Options, ", "))
+			}
+		}
+		slices.Sort(values)
+		return json.Marshal(slices.Compact(values))
+
+	case FieldUser:
+		var value int
+		if err := json.Unmarshal(raw, &value); err != nil || value <= 0 {
+			return nil, f.errorf("must be a user ID")
+		}
+		return json.Marshal(value)
+	}
+
+	return nil, f.errorf("has unknown type %q", f.Type)
+}
+
+func (f *CustomField) empty() (json.RawMessage, error) {
+	if f.Required {
+		return nil, f.errorf("is required")
+	}
+	return nil, nil
+}
+
+func (f *CustomField) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("field %q "+format, append([]interface{}{f.Name}, args...)...)
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+/*
+Ключ идемпотентности изменяющего запроса API
+Ключ принадлежит пользователю; fingerprint - хеш метода, пути и тела запроса, по нему повтор отличается от нового запроса.
+Пока запрос обрабатывается, запись только резервирует ключ, после обработки в ней сохраняется ответ
+*/
+type IdempotencyRecord struct {
+	UserID      int
+	Key         string
+	Fingerprint string
+	Completed   bool
+	StatusCode  int
+	// Заголовки ответа, которые нужны при повторе: Content-Type, ETag, Location
+	Header    map[string]string
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
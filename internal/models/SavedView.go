@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+/*
+Сохранённое представление - именованный фильтр задач пользователя
+Поле pinned выводит представление в навигацию веб-интерфейса
+Поле shared делает представление доступным участникам доски из фильтра
+*/
+type SavedView struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Name      string     `json:"name" validate:"required,min=3,max=50"`
+	Filter    TaskFilter `json:"filter"`
+	Pinned    bool       `json:"pinned"`
+	Shared    bool       `json:"shared"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type SavedViewRequest struct {
+	Name   string     `json:"name" validate:"required,min=3,max=50"`
+	Filter TaskFilter `json:"filter"`
+	Pinned bool       `json:"pinned"`
+	Shared bool       `json:"shared"`
+}
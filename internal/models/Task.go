@@ -16,6 +16,9 @@ const (
 Поле description может быть либо пустым, либо иметь максимальный размер до 500 символов
 Поле status должно принимать одно из трёх константных значений
 Поле userId - внешний ключ для связи с пользователем
+Поле seriesId заполнено у экземпляров повторяющейся задачи
+Поле estimate - оценка в единицах доски (story points или часы)
+Поле version растёт при каждом изменении и служит ETag для оптимистичной блокировки
 */
 type Task struct {
 	ID          int        `json:"id"`
@@ -23,21 +26,30 @@ type Task struct {
 	Description string     `json:"description,omitempty" validate:"max=500"`
 	Status      TaskStatus `json:"status" validate:"oneof=todo in_progress done"`
 	UserID      int        `json:"user_id" validate:"required"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	SeriesID    *int       `json:"series_id,omitempty"`
+	Estimate    *float64   `json:"estimate,omitempty" validate:"omitempty,min=0,max=1000"`
+	// Значения пользовательских полей досок, заполняются обработчиками
+	CustomFields []CustomFieldValue `json:"custom_fields,omitempty"`
+	Version      int                `json:"version"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
 }
 
 type TaskStatusUpdate struct {
 	Status TaskStatus `json:"status" validate:"required,oneof=todo in_progress done"`
 }
 
-type TaskCreateRequest struct {
+// Поля задачи, которые задаёт пользователь; владелец задачи берётся из токена и не меняется
+type TaskRequest struct {
 	Title       string     `json:"title" validate:"required,min=3,max=100"`
 	Description string     `json:"description,omitempty" validate:"max=500"`
 	Status      TaskStatus `json:"status" validate:"oneof=todo in_progress done"`
-	UserID      int        `json:"user_id" validate:"required"`
+	Estimate    *float64   `json:"estimate,omitempty" validate:"omitempty,min=0,max=1000"`
 }
 
+// Статусы в порядке колонок доски
+var TaskStatuses = []TaskStatus{StatusToDo, StatusInProgres, StatusDone}
+
 func (s TaskStatus) IsValid() bool {
 	switch s {
 	case StatusToDo, StatusInProgres, StatusDone:
@@ -0,0 +1,15 @@
+package models
+
+/*
+Фильтр задач, используется как в запросах к API, так и в сохранённых представлениях
+Поле boardId ограничивает выборку задачами доски, onlyMine - задачами текущего пользователя
+Без boardId выборка всегда ограничена задачами текущего пользователя
+Поле fields отбирает задачи по значениям пользовательских полей: идентификатор поля - значение
+*/
+type TaskFilter struct {
+	Statuses []TaskStatus   `json:"statuses,omitempty" validate:"dive,oneof=todo in_progress done"`
+	BoardID  int            `json:"board_id,omitempty" validate:"min=0"`
+	Search   string         `json:"search,omitempty" validate:"max=100"`
+	OnlyMine bool           `json:"only_mine,omitempty"`
+	Fields   map[int]string `json:"fields,omitempty" validate:"dive,max=500"`
+}
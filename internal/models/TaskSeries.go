@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+type RecurrenceFrequency string
+
+// Поддерживаемые шаблоны повторения
+const (
+	FrequencyDaily   RecurrenceFrequency = "daily"
+	FrequencyWeekly  RecurrenceFrequency = "weekly"
+	FrequencyMonthly RecurrenceFrequency = "monthly"
+)
+
+/*
+Правило повторения: задача повторяется каждые interval дней, недель или месяцев
+Поле until ограничивает время, после которого новые экземпляры не создаются
+*/
+type RecurrenceRule struct {
+	Frequency RecurrenceFrequency `json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	Interval  int                 `json:"interval" validate:"min=0,max=365"`
+	Until     *time.Time          `json:"until,omitempty"`
+}
+
+/*
+Серия повторяющейся задачи - шаблон, по которому создаются экземпляры
+Поле nextRunAt - время, когда фоновая задача создаст следующий экземпляр
+*/
+type TaskSeries struct {
+	ID          int            `json:"id"`
+	UserID      int            `json:"user_id"`
+	Title       string         `json:"title" validate:"required,min=3,max=100"`
+	Description string         `json:"description,omitempty" validate:"max=500"`
+	Rule        RecurrenceRule `json:"rule"`
+	NextRunAt   time.Time      `json:"next_run_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	// Последний созданный экземпляр, от него наследуется принадлежность к доскам
+	LastTaskID int `json:"-"`
+}
+
+type TaskSeriesUpdate struct {
+	Title       string         `json:"title" validate:"required,min=3,max=100"`
+	Description string         `json:"description,omitempty" validate:"max=500"`
+	Rule        RecurrenceRule `json:"rule"`
+}
+
+func (f RecurrenceFrequency) IsValid() bool {
+	switch f {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+		return true
+	default:
+		return false
+	}
+}
+
+// Время следующего срабатывания правила после from
+func (r RecurrenceRule) Next(from time.Time) time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch r.Frequency {
+	case FrequencyWeekly:
+		return from.AddDate(0, 0, 7*interval)
+	case FrequencyMonthly:
+		return from.AddDate(0, interval, 0)
+	default:
+		return from.AddDate(0, 0, interval)
+	}
+}
+
+// Правило исчерпано, если время t позже ограничения until
+func (r RecurrenceRule) Expired(t time.Time) bool {
+	return r.Until != nil && t.After(*r.Until)
+}
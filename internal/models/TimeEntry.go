@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+/*
+Запись о затраченном времени по задаче
+Запись таймера без endedAt считается запущенной, у пользователя может быть только один запущенный таймер
+Ручные записи создаются сразу с временем начала и окончания
+*/
+type TimeEntry struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	TaskID    int        `json:"task_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Seconds   int64      `json:"seconds"`
+	Note      string     `json:"note,omitempty" validate:"max=255"`
+	Manual    bool       `json:"manual"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type WorklogRequest struct {
+	StartedAt time.Time `json:"started_at" validate:"required"`
+	Minutes   int       `json:"minutes" validate:"required,min=1,max=1440"`
+	Note      string    `json:"note,omitempty" validate:"max=255"`
+}
+
+// Суммарное время по задаче, доске или пользователю
+type TimeTotal struct {
+	Seconds int64 `json:"seconds"`
+}
+
+// Строка отчёта: время по задаче за один день
+type TimeReportRow struct {
+	Day       time.Time `json:"day"`
+	TaskID    int       `json:"task_id"`
+	TaskTitle string    `json:"task_title"`
+	Seconds   int64     `json:"seconds"`
+}
@@ -0,0 +1,224 @@
+/*
+Генератор OpenAPI 3 документа по swag-аннотациям
+
+Аннотации разбирает swaggo/swag: общая информация (@title, @version, @BasePath, @securityDefinitions)
+читается из cmd/app/main.go, операции - из комментариев обработчиков internal/handlers/api,
+схемы - из структур пакетов searchDirs. swag строит документ Swagger 2.0, и он переводится в OpenAPI 3:
+параметры body становятся requestBody, схемы ответов раскладываются по типам из @Produce,
+определения переезжают в components/schemas
+*/
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/swaggo/swag"
+)
+
+// Файл с общей информацией об API и каталоги, в которых swag ищет обработчики и типы
+const mainFile = "main.go"
+
+var searchDirs = []string{
+	"cmd/app",
+	"internal/handlers/api",
+	"internal/models",
+	"internal/apperr",
+	"internal/middleware",
+}
+
+// Типы, которые swag не умеет разбирать: значения пользовательских полей - произвольный JSON
+var overrides = map[string]string{
+	"json.RawMessage": "interface{}",
+}
+
+// Ключи параметра Swagger 2.0, которые в OpenAPI 3 описываются схемой параметра
+var paramSchemaKeys = []string{
+	"type", "format", "items", "enum", "default",
+	"maximum", "minimum", "maxLength", "minLength", "pattern",
+}
+
+// Строит документ по исходникам в каталоге root (корень модуля)
+func Generate(root string) ([]byte, error) {
+	dirs := make([]string, len(searchDirs))
+	for i, dir := range searchDirs {
+		dirs[i] = filepath.Join(root, dir)
+	}
+
+	parser := swag.New(
+		swag.SetOverrides(overrides),
+		swag.SetDebugger(log.New(io.Discard, "", 0)),
+	)
+	if err := parser.ParseAPIMultiSearchDir(dirs, mainFile, 100); err != nil {
+		return nil, err
+	}
+
+	// Дальше документ обрабатывается как дерево JSON: так проще переложить его в другую структуру
+	data, err := json.Marshal(parser.GetSwagger())
+	if err != nil {
+		return nil, err
+	}
+	var swagger map[string]any
+	if err := json.Unmarshal(data, &swagger); err != nil {
+		return nil, err
+	}
+
+	doc, err := convert(swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = json.MarshalIndent(renameRefs(doc), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func convert(swagger map[string]any) (map[string]any, error) {
+	info, _ := swagger["info"].(map[string]any)
+	if info["title"] == nil || info["version"] == nil {
+		return nil, fmt.Errorf("%s: @title and @version are required", filepath.Join(searchDirs[0], mainFile))
+	}
+
+	basePath, _ := swagger["basePath"].(string)
+	if basePath == "" {
+		basePath = "/"
+	}
+
+	paths, _ := swagger["paths"].(map[string]any)
+	for path, item := range paths {
+		for method, op := range item.(map[string]any) {
+			if err := convertOperation(op.(map[string]any)); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+
+	components := map[string]any{}
+	if definitions, ok := swagger["definitions"]; ok {
+		components["schemas"] = definitions
+	}
+	if schemes, ok := swagger["securityDefinitions"]; ok {
+		components["securitySchemes"] = schemes
+	}
+
+	return map[string]any{
+		"openapi":    "3.0.3",
+		"info":       info,
+		"servers":    []any{map[string]any{"url": basePath}},
+		"paths":      paths,
+		"components": components,
+	}, nil
+}
+
+func convertOperation(op map[string]any) error {
+	consumes := mimeTypes(op["consumes"])
+	produces := mimeTypes(op["produces"])
+	delete(op, "consumes")
+	delete(op, "produces")
+
+	var params []any
+	list, _ := op["parameters"].([]any)
+	for _, p := range list {
+		param := p.(map[string]any)
+		switch param["in"] {
+		case "body":
+			op["requestBody"] = map[string]any{
+				"description": param["description"],
+				"required":    param["required"] == true,
+				"content":     content(consumes, param["schema"]),
+			}
+		case "path", "query", "header", "cookie":
+			params = append(params, convertParam(param))
+		default:
+			return fmt.Errorf("unsupported parameter location %q", param["in"])
+		}
+	}
+	delete(op, "parameters")
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	responses, _ := op["responses"].(map[string]any)
+	if len(responses) == 0 {
+		return fmt.Errorf("no responses documented")
+	}
+	for _, r := range responses {
+		response := r.(map[string]any)
+		if schema, ok := response["schema"]; ok {
+			response["content"] = content(produces, schema)
+			delete(response, "schema")
+		}
+		if headers, ok := response["headers"].(map[string]any); ok {
+			for name, h := range headers {
+				headers[name] = convertParam(h.(map[string]any))
+			}
+		}
+	}
+	return nil
+}
+
+// Тип, формат и ограничения параметра переносятся в его схему, список через повтор параметра - в explode
+func convertParam(param map[string]any) map[string]any {
+	schema := map[string]any{}
+	for _, key := range paramSchemaKeys {
+		if value, ok := param[key]; ok {
+			schema[key] = value
+			delete(param, key)
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		delete(items, "collectionFormat")
+	}
+	if param["collectionFormat"] == "multi" {
+		param["explode"] = true
+	}
+	delete(param, "collectionFormat")
+
+	param["schema"] = schema
+	return param
+}
+
+func content(mimes []string, schema any) map[string]any {
+	result := make(map[string]any, len(mimes))
+	for _, mime := range mimes {
+		result[mime] = map[string]any{"schema": schema}
+	}
+	return result
+}
+
+func mimeTypes(value any) []string {
+	list, _ := value.([]any)
+	if len(list) == 0 {
+		return []string{"application/json"}
+	}
+	mimes := make([]string, len(list))
+	for i, mime := range list {
+		mimes[i], _ = mime.(string)
+	}
+	return mimes
+}
+
+// Ссылки на определения Swagger 2.0 указывают на components/schemas
+func renameRefs(node any) any {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, value := range n {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				n[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				continue
+			}
+			n[key] = renameRefs(value)
+		}
+	case []any:
+		for i, value := range n {
+			n[i] = renameRefs(value)
+		}
+	}
+	return node
+}
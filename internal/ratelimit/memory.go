@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Скользящее окно в памяти процесса: для одного экземпляра приложения, тестов и работы без Redis
+type MemoryStore struct {
+	mu sync.Mutex
+	// Время учтённых запросов по ключу, от старых к новым
+	hits map[string][]time.Time
+	// Запросов с последней очистки пустых ключей
+	calls int
+	now   func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hits: make(map[string][]time.Time), now: time.Now}
+}
+
+// Ключи, по которым давно не было запросов, удаляются раз в cleanupEvery вызовов
+const cleanupEvery = 1000
+
+func (s *MemoryStore) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	hits := prune(s.hits[key], now.Add(-rule.Window))
+
+	allowed := len(hits) < rule.Limit
+	if allowed {
+		hits = append(hits, now)
+	}
+	s.hits[key] = hits
+
+	s.calls++
+	if s.calls >= cleanupEvery {
+		s.calls = 0
+		s.cleanup(now, rule.Window)
+	}
+
+	return result(allowed, rule, len(hits), hits[0], now), nil
+}
+
+// Оставляет запросы, сделанные позже since
+func prune(hits []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(since) {
+		i++
+	}
+	return hits[i:]
+}
+
+// Окна разных правил различаются, поэтому удаляются только ключи без запросов за окно текущего правила
+func (s *MemoryStore) cleanup(now time.Time, window time.Duration) {
+	for key, hits := range s.hits {
+		if len(hits) == 0 || !hits[len(hits)-1].After(now.Add(-window)) {
+			delete(s.hits, key)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Ограничение частоты запросов скользящим окном
+
+Store хранит время последних запросов по ключу и пропускает не больше Rule.Limit запросов за Rule.Window.
+Хранилище в Redis общее для всех экземпляров приложения, хранилище в памяти считает запросы только своего процесса
+*/
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Не больше Limit запросов за Window; Limit 0 снимает ограничение
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Разбирает правило в формате "limit/window", например "5/15m"
+func ParseRule(value string) (Rule, error) {
+	limit, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit %q must look like 10/1m", value)
+	}
+
+	var rule Rule
+	var err error
+	if rule.Limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil || rule.Limit < 0 {
+		return Rule{}, fmt.Errorf("rate limit %q has invalid limit", value)
+	}
+	if rule.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil || rule.Window <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q has invalid window", value)
+	}
+	return rule, nil
+}
+
+// Разбор правила из переменной окружения для envconfig
+func (r *Rule) Decode(value string) error {
+	rule, err := ParseRule(value)
+	if err != nil {
+		return err
+	}
+	*r = rule
+	return nil
+}
+
+// Правило в том же формате "limit/window", что и в переменной окружения
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s", r.Limit, r.Window)
+}
+
+func (r Rule) Enabled() bool {
+	return r.Limit > 0
+}
+
+// Итог проверки одного запроса
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Через сколько освободится место в окне; для отклонённого запроса это время до повтора
+	Reset time.Duration
+}
+
+type Store interface {
+	// Учитывает запрос по ключу, если он укладывается в правило
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+func result(allowed bool, rule Rule, count int, oldest, now time.Time) Result {
+	reset := oldest.Add(rule.Window).Sub(now)
+	if reset < 0 {
+		reset = 0
+	}
+	return Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: max(rule.Limit-count, 0),
+		Reset:     reset,
+	}
+}
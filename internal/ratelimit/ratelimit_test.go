@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("5/15m")
+	if err != nil || rule != (Rule{Limit: 5, Window: 15 * time.Minute}) {
+		t.Fatalf("expected 5 per 15m, got %+v, %v", rule, err)
+	}
+	if rule, err := ParseRule("0/1m"); err != nil || rule.Enabled() {
+		t.Fatalf("expected disabled rule, got %+v, %v", rule, err)
+	}
+	for _, value := range []string{"", "5", "five/1m", "-1/1m", "5/0s", "5/soon"} {
+		if _, err := ParseRule(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	rule := Rule{Limit: 2, Window: time.Minute}
+
+	allow := func(key string) Result {
+		t.Helper()
+		result, err := store.Allow(ctx, key, rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if r := allow("a"); !r.Allowed || r.Remaining != 1 || r.Reset != time.Minute {
+		t.Fatalf("expected first request to pass, got %+v", r)
+	}
+	now = now.Add(30 * time.Second)
+	if r := allow("a"); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("expected second request to pass, got %+v", r)
+	}
+	// Место освободится, когда из окна выйдет первый запрос
+	if r := allow("a"); r.Allowed || r.Reset != 30*time.Second {
+		t.Fatalf("expected third request to wait 30s, got %+v", r)
+	}
+	if r := allow("b"); !r.Allowed {
+		t.Fatalf("expected other key to be counted separately, got %+v", r)
+	}
+
+	// Отклонённый запрос не продлевает окно
+	now = now.Add(30 * time.Second)
+	if r := allow("a"); !r.Allowed || r.Remaining != 0 || r.Reset != 30*time.Second {
+		t.Fatalf("expected request to pass once the first one left the window, got %+v", r)
+	}
+	if r := allow("a"); r.Allowed {
+		t.Fatalf("expected window to stay full, got %+v", r)
+	}
+}
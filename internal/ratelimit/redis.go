@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Скользящее окно в Redis: общий счётчик для всех экземпляров приложения
+Запросы по ключу хранятся в отсортированном множестве ratelimit:<ключ> с временем запроса в миллисекундах.
+Скрипт удаляет запросы старше окна и добавляет новый, только если лимит не исчерпан, поэтому
+отклонённые запросы окно не продлевают
+*/
+type RedisStore struct {
+	client  *redis.Client
+	timeout time.Duration
+	// Отличает запросы одного процесса, пришедшие в одну миллисекунду
+	seq atomic.Uint64
+}
+
+func NewRedisStore(client *redis.Client, timeout time.Duration) *RedisStore {
+	return &RedisStore{client: client, timeout: timeout}
+}
+
+// Возвращает {разрешён, запросов в окне, время самого старого запроса}
+var slidingWindow = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	redis.call("PEXPIRE", KEYS[1], window)
+	count = count + 1
+	allowed = 1
+end
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return {allowed, count, oldest[2]}
+`)
+
+func (s *RedisStore) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatUint(s.seq.Add(1), 36)
+	values, err := slidingWindow.Run(ctx, s.client, []string{"ratelimit:" + key},
+		now.UnixMilli(), rule.Window.Milliseconds(), rule.Limit, member).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	count, _ := values[1].(int64)
+	oldestMilli, err := strconv.ParseFloat(fmt.Sprint(values[2]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	return result(allowed == 1, rule, int(count), time.UnixMilli(int64(oldestMilli)), now), nil
+}
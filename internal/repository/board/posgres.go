@@ -1,36 +1,46 @@
 package board_repo
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
 )
 
 type BoardPostgresRepo struct {
-	db *sql.DB
+	db      *repository.DB
+	timeout time.Duration
 }
 
-func NewBoardPostgresRepo(db *sql.DB) *BoardPostgresRepo {
-	return &BoardPostgresRepo{db: db}
+func NewBoardPostgresRepo(db *sql.DB, timeout time.Duration) *BoardPostgresRepo {
+	return &BoardPostgresRepo{db: repository.NewDB(db), timeout: timeout}
 }
 
-func (r *BoardPostgresRepo) Create(board *models.Board) error {
+func (r *BoardPostgresRepo) Create(ctx context.Context, board *models.Board) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		INSERT INTO boards (name, user_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		INSERT INTO boards (name, user_id, estimate_unit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, version
 	`
 
+	if !board.EstimateUnit.IsValid() {
+		board.EstimateUnit = models.EstimatePoints
+	}
+
 	now := time.Now()
-	err := r.db.QueryRow(
+	err = r.db.QueryRowContext(ctx,
 		query,
 		board.Name,
 		board.UserID,
+		board.EstimateUnit,
 		now,
 		now,
-	).Scan(&board.ID)
+	).Scan(&board.ID, &board.Version)
 
 	if err != nil {
 		return err
@@ -39,26 +49,31 @@ func (r *BoardPostgresRepo) Create(board *models.Board) error {
 	return nil
 }
 
-func (r *BoardPostgresRepo) GetById(id int) (*models.Board, error) {
+func (r *BoardPostgresRepo) GetById(ctx context.Context, id int) (_ *models.Board, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		SELECT id, name, user_id, created_at, updated_at
+		SELECT id, name, user_id, estimate_unit, version, created_at, updated_at
 		FROM boards
 		WHERE id = $1
 	`
 
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id)
 	board := &models.Board{}
-	err := row.Scan(
+	err = row.Scan(
 		&board.ID,
 		&board.Name,
 		&board.UserID,
+		&board.EstimateUnit,
+		&board.Version,
 		&board.CreatedAt,
 		&board.UpdateddAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("board not found")
+			return nil, repository.NotFound("board")
 		}
 
 		return nil, err
@@ -67,38 +82,66 @@ func (r *BoardPostgresRepo) GetById(id int) (*models.Board, error) {
 	return board, nil
 }
 
-func (r *BoardPostgresRepo) Update(board *models.Board) error {
+func (r *BoardPostgresRepo) Update(ctx context.Context, board *models.Board) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		UPDATE boards
 		SET name = $1,
-			updated_at = $2
-		WHERE id = $3
+			estimate_unit = $2,
+			updated_at = $3,
+			version = version + 1
+		WHERE id = $4 AND version = $5
 	`
 
-	_, err := r.db.Exec(
+	if !board.EstimateUnit.IsValid() {
+		board.EstimateUnit = models.EstimatePoints
+	}
+
+	result, err := r.db.ExecContext(ctx,
 		query,
 		board.Name,
+		board.EstimateUnit,
 		board.UpdateddAt,
 		board.ID,
+		board.Version,
 	)
 
-	return err
+	if err != nil {
+		return err
+	}
+	if err := repository.CheckVersion(ctx, r.db, result, "boards", board.ID, "board"); err != nil {
+		return err
+	}
+
+	board.Version++
+	return nil
 }
 
-func (r *BoardPostgresRepo) Delete(id int) error {
-	query := `DELETE FROM boards WHERE id = $1`
-	_, err := r.db.Exec(query, id)
-	return err
+func (r *BoardPostgresRepo) Delete(ctx context.Context, id, version int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM boards WHERE id = $1 AND version = $2`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+	return repository.CheckVersion(ctx, r.db, result, "boards", id, "board")
 }
 
-func (r *BoardPostgresRepo) ListByUser(user_id int) ([]*models.Board, error) {
+func (r *BoardPostgresRepo) ListByUser(ctx context.Context, user_id int) (_ []*models.Board, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		SELECT id, name, user_id, created_at
+		SELECT id, name, user_id, estimate_unit, version, created_at
 		FROM boards
 		WHERE user_id = $1
 	`
 
-	rows, err := r.db.Query(query, user_id)
+	rows, err := r.db.QueryContext(ctx, query, user_id)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +154,8 @@ func (r *BoardPostgresRepo) ListByUser(user_id int) ([]*models.Board, error) {
 			&board.ID,
 			&board.Name,
 			&board.UserID,
+			&board.EstimateUnit,
+			&board.Version,
 			&board.CreatedAt,
 		)
 		if err != nil {
@@ -125,3 +170,128 @@ func (r *BoardPostgresRepo) ListByUser(user_id int) ([]*models.Board, error) {
 
 	return boards, nil
 }
+
+// Участник доски - её владелец или пользователь, чьи задачи добавлены на доску
+func (r *BoardPostgresRepo) IsMember(ctx context.Context, boardID, userID int) (_ bool, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM boards WHERE id = $1 AND user_id = $2
+		) OR EXISTS (
+			SELECT 1
+			FROM board_tasks bt
+			JOIN tasks t ON t.id = bt.task_id
+			WHERE bt.board_id = $1 AND t.user_id = $2
+		)
+	`
+
+	var isMember bool
+	err = r.db.QueryRowContext(ctx, query, boardID, userID).Scan(&isMember)
+	if err != nil {
+		return false, err
+	}
+
+	return isMember, nil
+}
+
+func (r *BoardPostgresRepo) GetWIPLimits(ctx context.Context, boardID int) (_ map[models.TaskStatus]int, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT status, max_tasks
+		FROM board_wip_limits
+		WHERE board_id = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make(map[models.TaskStatus]int)
+	for rows.Next() {
+		var status models.TaskStatus
+		var limit int
+		if err := rows.Scan(&status, &limit); err != nil {
+			return nil, err
+		}
+		limits[status] = limit
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// Заменяет все лимиты доски, статусы без лимита не ограничены
+func (r *BoardPostgresRepo) SetWIPLimits(ctx context.Context, boardID int, limits map[models.TaskStatus]int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM board_wip_limits WHERE board_id = $1`, boardID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for status, limit := range limits {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO board_wip_limits (board_id, status, max_tasks) VALUES ($1, $2, $3)`,
+			boardID,
+			status,
+			limit,
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Количество задач, сумма оценок и лимит для каждого статуса доски
+func (r *BoardPostgresRepo) Summary(ctx context.Context, boardID int) (_ []*models.BoardColumnSummary, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT s.status, COUNT(t.id), COALESCE(SUM(t.estimate), 0), COALESCE(l.max_tasks, 0)
+		FROM unnest(enum_range(NULL::task_status)) AS s(status)
+		LEFT JOIN board_tasks bt ON bt.board_id = $1
+		LEFT JOIN tasks t ON t.id = bt.task_id AND t.status = s.status
+		LEFT JOIN board_wip_limits l ON l.board_id = $1 AND l.status = s.status
+		GROUP BY s.status, l.max_tasks
+		ORDER BY s.status
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []*models.BoardColumnSummary
+	for rows.Next() {
+		column := &models.BoardColumnSummary{}
+		if err := rows.Scan(&column.Status, &column.Tasks, &column.Estimate, &column.WIPLimit); err != nil {
+			return nil, err
+		}
+		summary = append(summary, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
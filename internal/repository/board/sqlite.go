@@ -0,0 +1,308 @@
+package board_repo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type BoardSQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewBoardSQLiteRepo(db *sql.DB, timeout time.Duration) *BoardSQLiteRepo {
+	return &BoardSQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+func (r *BoardSQLiteRepo) Create(ctx context.Context, board *models.Board) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO boards (name, user_id, estimate_unit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, version
+	`
+
+	if !board.EstimateUnit.IsValid() {
+		board.EstimateUnit = models.EstimatePoints
+	}
+
+	now := time.Now().UTC()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		board.Name,
+		board.UserID,
+		board.EstimateUnit,
+		now,
+		now,
+	).Scan(&board.ID, &board.Version)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *BoardSQLiteRepo) GetById(ctx context.Context, id int) (_ *models.Board, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, name, user_id, estimate_unit, version, created_at, updated_at
+		FROM boards
+		WHERE id = $1
+	`
+
+	board := &models.Board{}
+	err = r.db.QueryRowContext(ctx, query, id).Scan(
+		&board.ID,
+		&board.Name,
+		&board.UserID,
+		&board.EstimateUnit,
+		&board.Version,
+		&board.CreatedAt,
+		&board.UpdateddAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("board")
+		}
+		return nil, err
+	}
+
+	return board, nil
+}
+
+func (r *BoardSQLiteRepo) Update(ctx context.Context, board *models.Board) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE boards
+		SET name = $1,
+			estimate_unit = $2,
+			updated_at = $3,
+			version = version + 1
+		WHERE id = $4 AND version = $5
+	`
+
+	if !board.EstimateUnit.IsValid() {
+		board.EstimateUnit = models.EstimatePoints
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		query,
+		board.Name,
+		board.EstimateUnit,
+		board.UpdateddAt.UTC(),
+		board.ID,
+		board.Version,
+	)
+
+	if err != nil {
+		return err
+	}
+	if err := repository.CheckVersion(ctx, r.db, result, "boards", board.ID, "board"); err != nil {
+		return err
+	}
+
+	board.Version++
+	return nil
+}
+
+func (r *BoardSQLiteRepo) Delete(ctx context.Context, id, version int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM boards WHERE id = $1 AND version = $2`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+	return repository.CheckVersion(ctx, r.db, result, "boards", id, "board")
+}
+
+func (r *BoardSQLiteRepo) ListByUser(ctx context.Context, userID int) (_ []*models.Board, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, name, user_id, estimate_unit, version, created_at
+		FROM boards
+		WHERE user_id = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var boards []*models.Board
+	for rows.Next() {
+		board := &models.Board{}
+		err := rows.Scan(
+			&board.ID,
+			&board.Name,
+			&board.UserID,
+			&board.EstimateUnit,
+			&board.Version,
+			&board.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, board)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return boards, nil
+}
+
+// Участник доски - её владелец или пользователь, чьи задачи добавлены на доску
+func (r *BoardSQLiteRepo) IsMember(ctx context.Context, boardID, userID int) (_ bool, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM boards WHERE id = $1 AND user_id = $2
+		) OR EXISTS (
+			SELECT 1
+			FROM board_tasks bt
+			JOIN tasks t ON t.id = bt.task_id
+			WHERE bt.board_id = $1 AND t.user_id = $2
+		)
+	`
+
+	var isMember bool
+	err = r.db.QueryRowContext(ctx, query, boardID, userID).Scan(&isMember)
+	if err != nil {
+		return false, err
+	}
+
+	return isMember, nil
+}
+
+func (r *BoardSQLiteRepo) GetWIPLimits(ctx context.Context, boardID int) (_ map[models.TaskStatus]int, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT status, max_tasks
+		FROM board_wip_limits
+		WHERE board_id = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make(map[models.TaskStatus]int)
+	for rows.Next() {
+		var status models.TaskStatus
+		var limit int
+		if err := rows.Scan(&status, &limit); err != nil {
+			return nil, err
+		}
+		limits[status] = limit
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// Заменяет все лимиты доски, статусы без лимита не ограничены
+func (r *BoardSQLiteRepo) SetWIPLimits(ctx context.Context, boardID int, limits map[models.TaskStatus]int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM board_wip_limits WHERE board_id = $1`, boardID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for status, limit := range limits {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO board_wip_limits (board_id, status, max_tasks) VALUES ($1, $2, $3)`,
+			boardID,
+			status,
+			limit,
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+/*
+Количество задач, сумма оценок и лимит для каждого статуса доски
+В SQLite нет перечислений, список статусов подставляется в запрос из модели
+*/
+func (r *BoardSQLiteRepo) Summary(ctx context.Context, boardID int) (_ []*models.BoardColumnSummary, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	args := []interface{}{boardID}
+	values := make([]string, 0, len(models.TaskStatuses))
+	for i, status := range models.TaskStatuses {
+		args = append(args, status)
+		values = append(values, "("+strconv.Itoa(i)+", $"+strconv.Itoa(len(args))+")")
+	}
+
+	query := `
+		WITH s(position, status) AS (VALUES ` + strings.Join(values, ", ") + `)
+		SELECT s.status, COUNT(t.id), COALESCE(SUM(t.estimate), 0), COALESCE(l.max_tasks, 0)
+		FROM s
+		LEFT JOIN board_tasks bt ON bt.board_id = $1
+		LEFT JOIN tasks t ON t.id = bt.task_id AND t.status = s.status
+		LEFT JOIN board_wip_limits l ON l.board_id = $1 AND l.status = s.status
+		GROUP BY s.position, s.status, l.max_tasks
+		ORDER BY s.position
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []*models.BoardColumnSummary
+	for rows.Next() {
+		column := &models.BoardColumnSummary{}
+		if err := rows.Scan(&column.Status, &column.Tasks, &column.Estimate, &column.WIPLimit); err != nil {
+			return nil, err
+		}
+		summary = append(summary, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
@@ -1,41 +1,78 @@
 package board_task_repo
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
 
 type BoardTaskPostgresRepo struct {
-	db *sql.DB
+	db      *repository.DB
+	timeout time.Duration
 }
 
-func NewBoardTaskPostgresRepo(db *sql.DB) *BoardTaskPostgresRepo {
-	return &BoardTaskPostgresRepo{db: db}
+func NewBoardTaskPostgresRepo(db *sql.DB, timeout time.Duration) *BoardTaskPostgresRepo {
+	return &BoardTaskPostgresRepo{db: repository.NewDB(db), timeout: timeout}
 }
 
-func (r *BoardTaskPostgresRepo) AddTask(boardID, taskID int) error {
-	query := `
+// Задача не добавляется, если с ней колонка доски превысит WIP-лимит
+func (r *BoardTaskPostgresRepo) AddTask(ctx context.Context, boardID, taskID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
 		INSERT INTO board_tasks (board_id, task_id)
 		VALUES ($1, $2)
 		ON CONFLICT (board_id, task_id) DO NOTHING
-	`
+	`, boardID, taskID)
+	if err != nil {
+		tx.Rollback()
+		return repository.TranslatePQ(err, "board or task")
+	}
 
-	_, err := r.db.Exec(query, boardID, taskID)
-	return err
+	// Задача уже была на доске, колонка не изменилась
+	if added, err := result.RowsAffected(); err != nil || added == 0 {
+		tx.Rollback()
+		return err
+	}
+
+	if err := r.checkLimits(ctx, tx, taskID, boardID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *BoardTaskPostgresRepo) RemoveTask(boardId, taskID int) error {
+func (r *BoardTaskPostgresRepo) RemoveTask(ctx context.Context, boardId, taskID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `DELETE FROM board_tasks WHERE board_id = $1 AND task_id = $2`
 
-	_, err := r.db.Exec(query, boardId, taskID)
+	_, err = r.db.ExecContext(ctx, query, boardId, taskID)
 	return err
 }
 
-func (r *BoardTaskPostgresRepo) GetTasks(boardID int) ([]int, error) {
+func (r *BoardTaskPostgresRepo) GetTasks(ctx context.Context, boardID int) (_ []int, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		SELECT task_id
 		FROM board_tasks
 		WHERE board_id = $1
 	`
 
-	rows, err := r.db.Query(query, boardID)
+	rows, err := r.db.QueryContext(ctx, query, boardID)
 
 	if err != nil {
 		return nil, err
@@ -54,26 +91,112 @@ func (r *BoardTaskPostgresRepo) GetTasks(boardID int) ([]int, error) {
 	return taskIDs, nil
 }
 
-func (r *BoardTaskPostgresRepo) MoveTask(fromBoardID, toBoardID, taskID int) error {
-	tx, err := r.db.Begin()
+// Задачи доски одним запросом с соединением, от новых к старым
+func (r *BoardTaskPostgresRepo) ListTasks(ctx context.Context, boardID int) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT t.id, t.title, t.description, t.status, t.user_id, t.series_id, t.estimate, t.version, t.created_at, t.updated_at
+		FROM board_tasks bt
+		JOIN tasks t ON t.id = bt.task_id
+		WHERE bt.board_id = $1
+		ORDER BY t.created_at DESC, t.id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	for rows.Next() {
+		task := &models.Task{}
+		err := rows.Scan(
+			&task.ID,
+			&task.Title,
+			&task.Description,
+			&task.Status,
+			&task.UserID,
+			&task.SeriesID,
+			&task.Estimate,
+			&task.Version,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func (r *BoardTaskPostgresRepo) GetBoards(ctx context.Context, taskID int) (_ []int, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT board_id
+		FROM board_tasks
+		WHERE task_id = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var boardIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		boardIDs = append(boardIDs, id)
+	}
+
+	return boardIDs, rows.Err()
+}
+
+// Задача, которой нет на исходной доске, не переносится (NotFound)
+func (r *BoardTaskPostgresRepo) MoveTask(ctx context.Context, fromBoardID, toBoardID, taskID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	if _, err := tx.Exec(
+	result, err := tx.ExecContext(ctx,
 		`DELETE FROM board_tasks WHERE board_id = $1 AND task_id = $2`,
 		fromBoardID,
 		taskID,
-	); err != nil {
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	// Переносится только задача, которая лежит на исходной доске
+	if err := repository.CheckAffected(result, "task on the board"); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	if _, err := tx.Exec(
+	if _, err := tx.ExecContext(ctx,
 		`INSERT INTO board_tasks (board_id, task_id) VALUES ($1, $2)`,
 		toBoardID,
 		taskID,
 	); err != nil {
+		tx.Rollback()
+		return repository.TranslatePQ(err, "board or task")
+	}
+
+	if err := r.checkLimits(ctx, tx, taskID, toBoardID); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -81,7 +204,73 @@ func (r *BoardTaskPostgresRepo) MoveTask(fromBoardID, toBoardID, taskID int) err
 	return tx.Commit()
 }
 
-func (r *BoardTaskPostgresRepo) Exists(boardID, taskID int) (bool, error) {
+/*
+Проверяет WIP-лимиты всех досок задачи для её текущего статуса
+Вызывается после смены статуса в той же единице работы, что и изменение задачи, и откатывает её при превышении
+*/
+func (r *BoardTaskPostgresRepo) CheckLimits(ctx context.Context, taskID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	return r.checkLimits(ctx, r.db, taskID, 0)
+}
+
+/*
+Проверяет WIP-лимиты досок задачи для её текущего статуса после изменения в транзакции q; boardID 0 - все доски задачи
+Строки лимитов блокируются до конца транзакции, поэтому параллельная транзакция, меняющая ту же колонку,
+дожидается фиксации этой и считает задачи уже вместе с её изменением
+*/
+func (r *BoardTaskPostgresRepo) checkLimits(ctx context.Context, q queryer, taskID, boardID int) error {
+	rows, err := q.QueryContext(ctx, `
+		SELECT l.board_id, l.status, l.max_tasks
+		FROM board_tasks bt
+		JOIN tasks t ON t.id = bt.task_id
+		JOIN board_wip_limits l ON l.board_id = bt.board_id AND l.status = t.status
+		WHERE bt.task_id = $1 AND ($2 = 0 OR bt.board_id = $2)
+		ORDER BY l.board_id
+		FOR UPDATE OF l
+	`, taskID, boardID)
+	if err != nil {
+		return err
+	}
+
+	var limits []repository.WIPLimitError
+	for rows.Next() {
+		var limit repository.WIPLimitError
+		if err := rows.Scan(&limit.BoardID, &limit.Status, &limit.Limit); err != nil {
+			rows.Close()
+			return err
+		}
+		limits = append(limits, limit)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, limit := range limits {
+		var count int
+		err := q.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM board_tasks bt
+			JOIN tasks t ON t.id = bt.task_id
+			WHERE bt.board_id = $1 AND t.status = $2
+		`, limit.BoardID, limit.Status).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > limit.Limit {
+			return &limits[i]
+		}
+	}
+
+	return nil
+}
+
+func (r *BoardTaskPostgresRepo) Exists(ctx context.Context, boardID, taskID int) (_ bool, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		SELECT COUNT(*)
 		FROM board_tasks
@@ -89,9 +278,9 @@ func (r *BoardTaskPostgresRepo) Exists(boardID, taskID int) (bool, error) {
 	`
 
 	var count int
-	err := r.db.QueryRow(query, boardID, taskID).Scan(&count)
+	err = r.db.QueryRowContext(ctx, query, boardID, taskID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,12 @@
+package board_task_repo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Запросы вне транзакции репозитория (*repository.DB) или внутри неё (*repository.Tx)
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
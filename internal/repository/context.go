@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+	"runtime"
+	"time"
+)
+
+// Запрос прерван по таймауту или отменён вызывающей стороной (например, клиент закрыл соединение)
+var (
+	ErrTimeout  = errors.New("database query timed out")
+	ErrCanceled = errors.New("database query canceled")
+)
+
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return ErrTimeout.Error() + ": " + e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout || target == context.DeadlineExceeded
+}
+
+type CanceledError struct {
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return ErrCanceled.Error() + ": " + e.Err.Error()
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CanceledError) Is(target error) bool {
+	return target == ErrCanceled || target == context.Canceled
+}
+
+/*
+Ограничивает время выполнения метода репозитория
+Функция done освобождает контекст и, если ошибка вызвана истечением таймаута или отменой,
+заменяет её на TimeoutError или CanceledError:
+
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+*/
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, func(*error)) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	return ctx, func(errp *error) {
+		if errp != nil && *errp != nil {
+			*errp = contextError(ctx, *errp)
+			logError(ctx, *errp)
+		}
+		cancel()
+	}
+}
+
+/*
+Записывает в журнал непредвиденную ошибку репозитория вместе с методом, в котором она возникла
+Ожидаемые ошибки (нет записи, конфликт, устаревшая версия, отмена клиентом) обрабатываются выше и не пишутся.
+Запись получает request_id из контекста запроса
+*/
+func logError(ctx context.Context, err error) {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrStale) || errors.Is(err, ErrCanceled) {
+		return
+	}
+
+	attrs := []any{"error", err}
+	// 0 - logError, 1 - функция done, 2 - метод репозитория, отложивший её вызов
+	if pc, _, _, ok := runtime.Caller(2); ok {
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			attrs = append(attrs, "method", path.Base(fn.Name()))
+		}
+	}
+	slog.ErrorContext(ctx, "repository error", attrs...)
+}
+
+func contextError(ctx context.Context, err error) error {
+	var timeoutErr *TimeoutError
+	var canceledErr *CanceledError
+	if errors.As(err, &timeoutErr) || errors.As(err, &canceledErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &TimeoutError{Err: err}
+	case errors.Is(ctx.Err(), context.Canceled):
+		return &CanceledError{Err: err}
+	}
+	return err
+}
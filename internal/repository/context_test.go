@@ -0,0 +1,100 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/logging"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
+	"github.com/CAATHARSIS/task-tracking/pkg/database"
+)
+
+// Запрос к базе, который ждёт отмены контекста, как драйвер при долгом запросе
+func blockingQuery(ctx context.Context, timeout time.Duration) (err error) {
+	ctx, done := repository.WithTimeout(ctx, timeout)
+	defer done(&err)
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWithTimeout(t *testing.T) {
+	err := blockingQuery(context.Background(), time.Millisecond)
+	var timeoutErr *repository.TimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, repository.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected TimeoutError, got %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = blockingQuery(ctx, time.Minute)
+	var canceledErr *repository.CanceledError
+	if !errors.As(err, &canceledErr) || !errors.Is(err, repository.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected CanceledError, got %#v", err)
+	}
+}
+
+// Ошибки, не связанные с контекстом, и успешные вызовы проходят без изменений
+func TestWithTimeoutKeepsOtherErrors(t *testing.T) {
+	query := func(result error) (err error) {
+		_, done := repository.WithTimeout(context.Background(), time.Minute)
+		defer done(&err)
+		return result
+	}
+
+	if err := query(repository.ErrNotFound); err != repository.ErrNotFound {
+		t.Errorf("expected ErrNotFound unchanged, got %#v", err)
+	}
+	if err := query(nil); err != nil {
+		t.Errorf("expected nil, got %#v", err)
+	}
+}
+
+// Непредвиденные ошибки репозиториев пишутся в журнал с идентификатором запроса, ожидаемые - нет
+func TestRepositoryErrorsAreLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	db, err := database.NewSQLiteDB(&config.Config{
+		DBPath:               filepath.Join(t.TempDir(), "test.db"),
+		SQLiteMigrationsPath: "file://../../migrations/sqlite",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := task_repo.NewTaskSQLiteRepo(db, contractTimeout)
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	_, err = tasks.GetById(ctx, 1)
+	expectErr(t, err, repository.ErrNotFound)
+	if buf.Len() != 0 {
+		t.Fatalf("expected not found to stay out of the log, got %s", buf.String())
+	}
+
+	db.Close()
+	if _, err := tasks.GetById(ctx, 1); err == nil {
+		t.Fatal("expected error from closed database")
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "repository error" || entry["request_id"] != "req-1" || entry["method"] != "task.(*TaskSQLiteRepo).GetById" {
+		t.Fatalf("unexpected record %v", entry)
+	}
+}
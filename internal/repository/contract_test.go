@@ -0,0 +1,1346 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	board_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board"
+	board_task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/board_task"
+	custom_field_repo "github.com/CAATHARSIS/task-tracking/internal/repository/custom_field"
+	idempotency_repo "github.com/CAATHARSIS/task-tracking/internal/repository/idempotency"
+	memory_repo "github.com/CAATHARSIS/task-tracking/internal/repository/memory"
+	refresh_token_repo "github.com/CAATHARSIS/task-tracking/internal/repository/refresh_token"
+	saved_view_repo "github.com/CAATHARSIS/task-tracking/internal/repository/saved_view"
+	task_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task"
+	task_series_repo "github.com/CAATHARSIS/task-tracking/internal/repository/task_series"
+	time_entry_repo "github.com/CAATHARSIS/task-tracking/internal/repository/time_entry"
+	user_repo "github.com/CAATHARSIS/task-tracking/internal/repository/user"
+	"github.com/CAATHARSIS/task-tracking/pkg/database"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/redis/go-redis/v9"
+)
+
+// Набор репозиториев одного хранилища
+type backend struct {
+	users         repository.UserRepository
+	boards        repository.BoardRepository
+	tasks         repository.TaskRepository
+	boardTasks    repository.BoardTaskRepository
+	refreshTokens repository.RefreshTokenRepository
+	savedViews    repository.SavedViewRepository
+	taskSeries    repository.TaskSeriesRepository
+	timeEntries   repository.TimeEntryRepository
+	customFields  repository.CustomFieldRepository
+	idempotency   repository.IdempotencyRepository
+	uow           repository.UnitOfWork
+}
+
+func TestMemoryContract(t *testing.T) {
+	runContract(t, func(t *testing.T) *backend {
+		store := memory_repo.NewStore()
+		return &backend{
+			users:         memory_repo.NewUserMemoryRepo(store),
+			boards:        memory_repo.NewBoardMemoryRepo(store),
+			tasks:         memory_repo.NewTaskMemoryRepo(store),
+			boardTasks:    memory_repo.NewBoardTaskMemoryRepo(store),
+			refreshTokens: memory_repo.NewRefreshTokenMemoryRepo(store),
+			savedViews:    memory_repo.NewSavedViewMemoryRepo(store),
+			taskSeries:    memory_repo.NewTaskSeriesMemoryRepo(store),
+			timeEntries:   memory_repo.NewTimeEntryMemoryRepo(store),
+			customFields:  memory_repo.NewCustomFieldMemoryRepo(store),
+			idempotency:   memory_repo.NewIdempotencyMemoryRepo(store),
+			uow:           memory_repo.NewUnitOfWork(store),
+		}
+	})
+}
+
+func TestSQLiteContract(t *testing.T) {
+	runContract(t, func(t *testing.T) *backend {
+		db, err := database.NewSQLiteDB(&config.Config{
+			DBPath:               filepath.Join(t.TempDir(), "test.db"),
+			SQLiteMigrationsPath: "file://../../migrations/sqlite",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return &backend{
+			users:         user_repo.NewUserSQLiteRepo(db, contractTimeout),
+			boards:        board_repo.NewBoardSQLiteRepo(db, contractTimeout),
+			tasks:         task_repo.NewTaskSQLiteRepo(db, contractTimeout),
+			boardTasks:    board_task_repo.NewBoardTaskSQLiteRepo(db, contractTimeout),
+			refreshTokens: refresh_token_repo.NewRefreshTokenSQLiteRepo(db, contractTimeout),
+			savedViews:    saved_view_repo.NewSavedViewSQLiteRepo(db, contractTimeout),
+			taskSeries:    task_series_repo.NewTaskSeriesSQLiteRepo(db, contractTimeout),
+			timeEntries:   time_entry_repo.NewTimeEntrySQLiteRepo(db, contractTimeout),
+			customFields:  custom_field_repo.NewCustomFieldSQLiteRepo(db, contractTimeout),
+			idempotency:   idempotency_repo.NewIdempotencySQLiteRepo(db, contractTimeout),
+			uow:           repository.NewDB(db),
+		}
+	})
+}
+
+/*
+Тесты Postgres требуют отдельную базу, адрес которой задаётся в TEST_POSTGRES_DSN
+Перед каждым тестом схема public пересоздаётся, все данные базы удаляются
+*/
+func TestPostgresContract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	runContract(t, func(t *testing.T) *backend {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		if _, err = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+			t.Fatal(err)
+		}
+		driver, err := postgres.WithInstance(db, &postgres.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := migrate.NewWithDatabaseInstance("file://../../migrations", "postgres", driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Up(); err != nil {
+			t.Fatal(err)
+		}
+
+		return &backend{
+			users:         user_repo.NewUserPostgresRepo(db, contractTimeout),
+			boards:        board_repo.NewBoardPostgresRepo(db, contractTimeout),
+			tasks:         task_repo.NewTaskPostgresRepo(db, contractTimeout),
+			boardTasks:    board_task_repo.NewBoardTaskPostgresRepo(db, contractTimeout),
+			refreshTokens: refresh_token_repo.NewRefreshTokenPostgresRepo(db, contractTimeout),
+			savedViews:    saved_view_repo.NewSavedViewPostgresRepo(db, contractTimeout),
+			taskSeries:    task_series_repo.NewTaskSeriesPostgresRepo(db, contractTimeout),
+			timeEntries:   time_entry_repo.NewTimeEntryPostgresRepo(db, contractTimeout),
+			customFields:  custom_field_repo.NewCustomFieldPostgresRepo(db, contractTimeout),
+			idempotency:   idempotency_repo.NewIdempotencyPostgresRepo(db, contractTimeout),
+			uow:           repository.NewDB(db),
+		}
+	})
+}
+
+/*
+Ключи идемпотентности в Redis проверяются на отдельной базе Redis из TEST_REDIS_ADDR
+Redis не хранит пользователей, поэтому проверяется только общая часть контракта
+*/
+func TestRedisIdempotency(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	must(t, client.FlushDB(context.Background()).Err())
+
+	checkIdempotencyKeys(t, idempotency_repo.NewIdempotencyRedisRepo(client, contractTimeout), 1)
+}
+
+const contractTimeout = 5 * time.Second
+
+/*
+Контрактные тесты: одинаковое поведение репозиториев во всех хранилищах
+newBackend вызывается для каждого теста и должен возвращать пустое хранилище.
+Тесты не полагаются на конкретные значения идентификаторов и порядок строк без ORDER BY
+*/
+func runContract(t *testing.T, newBackend func(t *testing.T) *backend) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, b *backend)
+	}{
+		{"Users", testUsers},
+		{"Boards", testBoards},
+		{"BoardSummary", testBoardSummary},
+		{"Tasks", testTasks},
+		{"TaskFilter", testTaskFilter},
+		{"BoardTasks", testBoardTasks},
+		{"WIPLimits", testWIPLimits},
+		{"WIPLimitsConcurrent", testWIPLimitsConcurrent},
+		{"RefreshTokens", testRefreshTokens},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"SavedViews", testSavedViews},
+		{"TaskSeries", testTaskSeries},
+		{"TimeEntries", testTimeEntries},
+		{"CustomFields", testCustomFields},
+		{"Cascades", testCascades},
+		{"Canceled", testCanceled},
+		{"UnitOfWork", testUnitOfWork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newBackend(t))
+		})
+	}
+}
+
+func testUsers(t *testing.T, b *backend) {
+	ctx := context.Background()
+
+	user := &models.User{Email: "alice@example.com", PasswordHash: "hash"}
+	must(t, b.users.Create(ctx, user))
+	if user.ID == 0 {
+		t.Fatal("expected Create to set user ID")
+	}
+
+	expectErr(t, b.users.Create(ctx, &models.User{Email: "alice@example.com", PasswordHash: "hash"}), repository.ErrConflict)
+
+	got, err := b.users.GetByEmail(ctx, "alice@example.com")
+	must(t, err)
+	if got.ID != user.ID || got.PasswordHash != "hash" {
+		t.Fatalf("unexpected user %+v", got)
+	}
+
+	// Пустые поля при обновлении не меняются
+	must(t, b.users.Update(ctx, &models.User{ID: user.ID, PasswordHash: "new-hash"}))
+	got, err = b.users.GetById(ctx, user.ID)
+	must(t, err)
+	if got.Email != "alice@example.com" || got.PasswordHash != "new-hash" {
+		t.Fatalf("unexpected user after update %+v", got)
+	}
+
+	_, err = b.users.GetByEmail(ctx, "bob@example.com")
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.users.Update(ctx, &models.User{ID: user.ID + 1000, Email: "x@example.com"}), repository.ErrNotFound)
+
+	must(t, b.users.Delete(ctx, user.ID))
+	_, err = b.users.GetById(ctx, user.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.users.Delete(ctx, user.ID), repository.ErrNotFound)
+}
+
+func testBoards(t *testing.T, b *backend) {
+	ctx := context.Background()
+	owner := createUser(t, b, "owner@example.com")
+	member := createUser(t, b, "member@example.com")
+	stranger := createUser(t, b, "stranger@example.com")
+
+	board := &models.Board{Name: "Sprint", UserID: owner.ID}
+	must(t, b.boards.Create(ctx, board))
+	if board.EstimateUnit != models.EstimatePoints {
+		t.Fatalf("expected default estimate unit, got %q", board.EstimateUnit)
+	}
+	other := createBoard(t, b, owner, "Backlog")
+	createBoard(t, b, member, "Personal")
+
+	boards, err := b.boards.ListByUser(ctx, owner.ID)
+	must(t, err)
+	expectIDs(t, boardIDs(boards), board.ID, other.ID)
+
+	board.Name = "Sprint 2"
+	board.EstimateUnit = models.EstimateHours
+	board.UpdateddAt = time.Now()
+	must(t, b.boards.Update(ctx, board))
+	got, err := b.boards.GetById(ctx, board.ID)
+	must(t, err)
+	if got.Name != "Sprint 2" || got.EstimateUnit != models.EstimateHours || got.UserID != owner.ID {
+		t.Fatalf("unexpected board after update %+v", got)
+	}
+	if board.Version != 2 || got.Version != 2 {
+		t.Fatalf("expected version 2 after update, got %d and %d", board.Version, got.Version)
+	}
+
+	// Изменение по устаревшей версии отклоняется и не меняет доску
+	stale := *got
+	stale.Version = 1
+	stale.Name = "Stale"
+	expectErr(t, b.boards.Update(ctx, &stale), repository.ErrStale)
+	expectErr(t, b.boards.Delete(ctx, board.ID, 1), repository.ErrStale)
+	got, err = b.boards.GetById(ctx, board.ID)
+	must(t, err)
+	if got.Name != "Sprint 2" || got.Version != 2 {
+		t.Fatalf("stale update changed board %+v", got)
+	}
+
+	// Участником доски становится автор добавленной на неё задачи
+	expectMember(t, b, board.ID, owner.ID, true)
+	expectMember(t, b, board.ID, member.ID, false)
+	task := createTask(t, b, member, "Member task", models.StatusToDo)
+	must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+	expectMember(t, b, board.ID, member.ID, true)
+	expectMember(t, b, board.ID, stranger.ID, false)
+
+	must(t, b.boards.Delete(ctx, other.ID, other.Version))
+	_, err = b.boards.GetById(ctx, other.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.boards.Delete(ctx, other.ID, other.Version), repository.ErrNotFound)
+	expectErr(t, b.boards.Update(ctx, &models.Board{ID: other.ID, Name: "Gone", Version: other.Version}), repository.ErrNotFound)
+}
+
+func testBoardSummary(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	board := createBoard(t, b, user, "Sprint")
+
+	for _, tt := range []struct {
+		status   models.TaskStatus
+		estimate float64
+	}{
+		{models.StatusToDo, 1.5},
+		{models.StatusToDo, 2},
+		{models.StatusDone, 3},
+	} {
+		estimate := tt.estimate
+		task := &models.Task{Title: "Task", Status: tt.status, UserID: user.ID, Estimate: &estimate}
+		must(t, b.tasks.Create(ctx, task))
+		must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+	}
+	createTask(t, b, user, "Not on board", models.StatusInProgres)
+	must(t, b.boards.SetWIPLimits(ctx, board.ID, map[models.TaskStatus]int{models.StatusInProgres: 2}))
+
+	summary, err := b.boards.Summary(ctx, board.ID)
+	must(t, err)
+
+	want := []models.BoardColumnSummary{
+		{Status: models.StatusToDo, Tasks: 2, Estimate: 3.5},
+		{Status: models.StatusInProgres, Tasks: 0, Estimate: 0, WIPLimit: 2},
+		{Status: models.StatusDone, Tasks: 1, Estimate: 3},
+	}
+	if len(summary) != len(want) {
+		t.Fatalf("expected %d columns, got %d", len(want), len(summary))
+	}
+	for i, column := range summary {
+		if *column != want[i] {
+			t.Fatalf("column %d: expected %+v, got %+v", i, want[i], *column)
+		}
+	}
+}
+
+func testTasks(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	other := createUser(t, b, "bob@example.com")
+
+	estimate := 5.0
+	task := &models.Task{Title: "Write docs", Description: "README", Status: models.StatusToDo, UserID: user.ID, Estimate: &estimate}
+	must(t, b.tasks.Create(ctx, task))
+	second := createTask(t, b, user, "Second task", models.StatusDone)
+	createTask(t, b, other, "Other task", models.StatusToDo)
+
+	got, err := b.tasks.GetById(ctx, task.ID)
+	must(t, err)
+	if got.Title != "Write docs" || got.Description != "README" || got.Status != models.StatusToDo ||
+		got.UserID != user.ID || got.SeriesID != nil || got.Estimate == nil || *got.Estimate != 5 {
+		t.Fatalf("unexpected task %+v", got)
+	}
+	if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Fatal("expected task timestamps to be set")
+	}
+
+	tasks, err := b.tasks.ListByUser(ctx, user.ID)
+	must(t, err)
+	expectIDs(t, taskIDs(tasks), task.ID, second.ID)
+
+	// Пакетная загрузка сохраняет порядок запроса и пропускает несуществующие задачи
+	tasks, err = b.tasks.GetByIDs(ctx, []int{second.ID, task.ID + 1000, task.ID, second.ID})
+	must(t, err)
+	if ids := taskIDs(tasks); !slices.Equal(ids, []int{second.ID, task.ID}) {
+		t.Fatalf("expected tasks %v, got %v", []int{second.ID, task.ID}, ids)
+	}
+	if tasks[1].Description != "README" || tasks[1].Estimate == nil || *tasks[1].Estimate != 5 {
+		t.Fatalf("unexpected task %+v", tasks[1])
+	}
+	tasks, err = b.tasks.GetByIDs(ctx, nil)
+	must(t, err)
+	if len(tasks) != 0 {
+		t.Fatalf("expected no tasks, got %v", taskIDs(tasks))
+	}
+
+	got.Title = "Write more docs"
+	got.Status = models.StatusInProgres
+	got.Estimate = nil
+	must(t, b.tasks.Update(ctx, got))
+	got, err = b.tasks.GetById(ctx, task.ID)
+	must(t, err)
+	if got.Title != "Write more docs" || got.Status != models.StatusInProgres || got.Estimate != nil {
+		t.Fatalf("unexpected task after update %+v", got)
+	}
+	if task.Version != 1 || got.Version != 2 {
+		t.Fatalf("expected versions 1 and 2, got %d and %d", task.Version, got.Version)
+	}
+
+	// Изменение и удаление по устаревшей версии отклоняются
+	task.Title = "Stale docs"
+	expectErr(t, b.tasks.Update(ctx, task), repository.ErrStale)
+	expectErr(t, b.tasks.Delete(ctx, task.ID, task.Version), repository.ErrStale)
+	got, err = b.tasks.GetById(ctx, task.ID)
+	must(t, err)
+	if got.Title != "Write more docs" || got.Version != 2 {
+		t.Fatalf("stale update changed task %+v", got)
+	}
+
+	must(t, b.tasks.Delete(ctx, task.ID, got.Version))
+	_, err = b.tasks.GetById(ctx, task.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.tasks.Delete(ctx, task.ID, got.Version), repository.ErrNotFound)
+	expectErr(t, b.tasks.Update(ctx, got), repository.ErrNotFound)
+}
+
+func testTaskFilter(t *testing.T, b *backend) {
+	ctx := context.Background()
+	alice := createUser(t, b, "alice@example.com")
+	bob := createUser(t, b, "bob@example.com")
+	board := createBoard(t, b, alice, "Sprint")
+
+	milk := createTask(t, b, alice, "Buy milk", models.StatusToDo)
+	bread := createTask(t, b, alice, "Buy bread", models.StatusDone)
+	report := createTask(t, b, alice, "Write report", models.StatusInProgres)
+	review := createTask(t, b, bob, "Review report", models.StatusToDo)
+	for _, task := range []*models.Task{milk, report, review} {
+		must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+	}
+
+	priority := createField(t, b, board, "Priority", models.FieldSingleSelect, "low", "high")
+	labels := createField(t, b, board, "Labels", models.FieldMultiSelect, "bug", "docs")
+	points := createField(t, b, board, "Points", models.FieldNumber)
+	must(t, b.customFields.SetValues(ctx, milk.ID, map[int]json.RawMessage{
+		priority.ID: json.RawMessage(`"high"`),
+		labels.ID:   json.RawMessage(`["bug","docs"]`),
+		points.ID:   json.RawMessage(`3`),
+	}))
+	must(t, b.customFields.SetValues(ctx, review.ID, map[int]json.RawMessage{
+		priority.ID: json.RawMessage(`"low"`),
+		labels.ID:   json.RawMessage(`["docs"]`),
+	}))
+
+	for _, tt := range []struct {
+		name   string
+		userID int
+		filter models.TaskFilter
+		want   []int
+	}{
+		{"own tasks", alice.ID, models.TaskFilter{}, []int{milk.ID, bread.ID, report.ID}},
+		{"statuses", alice.ID, models.TaskFilter{Statuses: []models.TaskStatus{models.StatusToDo, models.StatusDone}}, []int{milk.ID, bread.ID}},
+		{"search is case insensitive", alice.ID, models.TaskFilter{Search: "  BUY "}, []int{milk.ID, bread.ID}},
+		{"board", bob.ID, models.TaskFilter{BoardID: board.ID}, []int{milk.ID, report.ID, review.ID}},
+		{"board only mine", bob.ID, models.TaskFilter{BoardID: board.ID, OnlyMine: true}, []int{review.ID}},
+		{"single select", alice.ID, models.TaskFilter{BoardID: board.ID, Fields: map[int]string{priority.ID: "low"}}, []int{review.ID}},
+		{"multi select", alice.ID, models.TaskFilter{BoardID: board.ID, Fields: map[int]string{labels.ID: "docs"}}, []int{milk.ID, review.ID}},
+		{"number", alice.ID, models.TaskFilter{BoardID: board.ID, Fields: map[int]string{points.ID: "3"}}, []int{milk.ID}},
+		{"several fields", alice.ID, models.TaskFilter{BoardID: board.ID, Fields: map[int]string{labels.ID: "bug", priority.ID: "low"}}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := b.tasks.ListByFilter(ctx, tt.userID, tt.filter)
+			must(t, err)
+			expectIDs(t, taskIDs(tasks), tt.want...)
+		})
+	}
+}
+
+func testBoardTasks(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	from := createBoard(t, b, user, "Source")
+	to := createBoard(t, b, user, "Target")
+	task := createTask(t, b, user, "Move me", models.StatusToDo)
+
+	must(t, b.boardTasks.AddTask(ctx, from.ID, task.ID))
+	// Повторное добавление ничего не меняет
+	must(t, b.boardTasks.AddTask(ctx, from.ID, task.ID))
+	expectErr(t, b.boardTasks.AddTask(ctx, from.ID, task.ID+1000), repository.ErrNotFound)
+
+	ids, err := b.boardTasks.GetTasks(ctx, from.ID)
+	must(t, err)
+	expectIDs(t, ids, task.ID)
+
+	tasks, err := b.boardTasks.ListTasks(ctx, from.ID)
+	must(t, err)
+	if len(tasks) != 1 || tasks[0].ID != task.ID || tasks[0].Title != "Move me" || tasks[0].UserID != user.ID {
+		t.Fatalf("unexpected board tasks %+v", tasks)
+	}
+	tasks, err = b.boardTasks.ListTasks(ctx, to.ID)
+	must(t, err)
+	if len(tasks) != 0 {
+		t.Fatalf("expected empty board, got %v", taskIDs(tasks))
+	}
+
+	must(t, b.boardTasks.MoveTask(ctx, from.ID, to.ID, task.ID))
+	expectOnBoard(t, b, from.ID, task.ID, false)
+	expectOnBoard(t, b, to.ID, task.ID, true)
+
+	ids, err = b.boardTasks.GetBoards(ctx, task.ID)
+	must(t, err)
+	expectIDs(t, ids, to.ID)
+
+	must(t, b.boardTasks.AddTask(ctx, from.ID, task.ID))
+	expectErr(t, b.boardTasks.MoveTask(ctx, from.ID, to.ID, task.ID), repository.ErrConflict)
+	expectOnBoard(t, b, from.ID, task.ID, true)
+
+	must(t, b.boardTasks.RemoveTask(ctx, from.ID, task.ID))
+	must(t, b.boardTasks.RemoveTask(ctx, from.ID, task.ID))
+	expectOnBoard(t, b, from.ID, task.ID, false)
+
+	// Задачу, которой нет на исходной доске, перенести нельзя
+	expectErr(t, b.boardTasks.MoveTask(ctx, from.ID, to.ID, task.ID), repository.ErrNotFound)
+	stray := createTask(t, b, user, "Stray", models.StatusToDo)
+	expectErr(t, b.boardTasks.MoveTask(ctx, from.ID, to.ID, stray.ID), repository.ErrNotFound)
+	expectOnBoard(t, b, to.ID, stray.ID, false)
+	expectOnBoard(t, b, to.ID, task.ID, true)
+}
+
+func testWIPLimits(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	board := createBoard(t, b, user, "Limited")
+	free := createBoard(t, b, user, "Free")
+
+	must(t, b.boards.SetWIPLimits(ctx, board.ID, map[models.TaskStatus]int{models.StatusToDo: 5, models.StatusInProgres: 1}))
+	must(t, b.boards.SetWIPLimits(ctx, board.ID, map[models.TaskStatus]int{models.StatusInProgres: 1}))
+	limits, err := b.boards.GetWIPLimits(ctx, board.ID)
+	must(t, err)
+	if len(limits) != 1 || limits[models.StatusInProgres] != 1 {
+		t.Fatalf("expected limits to be replaced, got %v", limits)
+	}
+
+	first := createTask(t, b, user, "First", models.StatusInProgres)
+	second := createTask(t, b, user, "Second", models.StatusInProgres)
+	third := createTask(t, b, user, "Third", models.StatusToDo)
+	must(t, b.boardTasks.AddTask(ctx, board.ID, first.ID))
+	must(t, b.boardTasks.AddTask(ctx, free.ID, second.ID))
+	must(t, b.boardTasks.AddTask(ctx, board.ID, third.ID))
+
+	err = b.boardTasks.MoveTask(ctx, free.ID, board.ID, second.ID)
+	var limitErr *repository.WIPLimitError
+	if !errors.As(err, &limitErr) || limitErr.BoardID != board.ID || limitErr.Status != models.StatusInProgres || limitErr.Limit != 1 {
+		t.Fatalf("expected WIP limit error, got %v", err)
+	}
+	expectErr(t, err, repository.ErrConflict)
+	expectOnBoard(t, b, free.ID, second.ID, true)
+	expectOnBoard(t, b, board.ID, second.ID, false)
+
+	fourth := createTask(t, b, user, "Fourth", models.StatusInProgres)
+	expectErr(t, b.boardTasks.AddTask(ctx, board.ID, fourth.ID), repository.ErrConflict)
+	expectOnBoard(t, b, board.ID, fourth.ID, false)
+	// Повторное добавление не меняет колонку и лимит не проверяет
+	must(t, b.boardTasks.AddTask(ctx, board.ID, first.ID))
+	must(t, b.boardTasks.CheckLimits(ctx, first.ID))
+
+	// Превышение после смены статуса откатывает всю единицу работы
+	moved := *third
+	moved.Status = models.StatusInProgres
+	err = b.uow.Do(ctx, func(ctx context.Context) error {
+		if err := b.tasks.Update(ctx, &moved); err != nil {
+			return err
+		}
+		return b.boardTasks.CheckLimits(ctx, third.ID)
+	})
+	expectErr(t, err, repository.ErrConflict)
+	got, err := b.tasks.GetById(ctx, third.ID)
+	must(t, err)
+	if got.Status != models.StatusToDo {
+		t.Errorf("expected status change to be rolled back, got %q", got.Status)
+	}
+}
+
+// Параллельные изменения одной колонки вместе не превышают WIP-лимит
+func testWIPLimitsConcurrent(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	board := createBoard(t, b, user, "Limited")
+	must(t, b.boards.SetWIPLimits(ctx, board.ID, map[models.TaskStatus]int{models.StatusToDo: 1, models.StatusInProgres: 1}))
+
+	const workers = 5
+	var added, moved []*models.Task
+	for range workers {
+		added = append(added, createTask(t, b, user, "Added", models.StatusToDo))
+	}
+
+	run := func(tasks []*models.Task, fn func(task *models.Task) error) int {
+		var wg sync.WaitGroup
+		var ok atomic.Int32
+		for _, task := range tasks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := fn(task)
+				if err == nil {
+					ok.Add(1)
+				} else if !errors.Is(err, repository.ErrConflict) {
+					t.Errorf("expected WIP limit error, got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		return int(ok.Load())
+	}
+
+	if n := run(added, func(task *models.Task) error {
+		return b.boardTasks.AddTask(ctx, board.ID, task.ID)
+	}); n != 1 {
+		t.Errorf("expected one task to be added, got %d", n)
+	}
+
+	for range workers {
+		task := createTask(t, b, user, "Moved", models.StatusDone)
+		must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+		moved = append(moved, task)
+	}
+	if n := run(moved, func(task *models.Task) error {
+		return b.uow.Do(ctx, func(ctx context.Context) error {
+			changed := *task
+			changed.Status = models.StatusInProgres
+			if err := b.tasks.Update(ctx, &changed); err != nil {
+				return err
+			}
+			return b.boardTasks.CheckLimits(ctx, task.ID)
+		})
+	}); n != 1 {
+		t.Errorf("expected one status change to be committed, got %d", n)
+	}
+}
+
+func testRefreshTokens(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+
+	must(t, b.refreshTokens.Create(ctx, &models.RefreshToken{TokenHash: "active", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}))
+	must(t, b.refreshTokens.Create(ctx, &models.RefreshToken{TokenHash: "expired", UserID: user.ID, ExpiresAt: time.Now().Add(-time.Hour)}))
+
+	token, err := b.refreshTokens.GetByHash(ctx, "active")
+	must(t, err)
+	if token.UserID != user.ID {
+		t.Fatalf("unexpected token %+v", token)
+	}
+	_, err = b.refreshTokens.GetByHash(ctx, "expired")
+	expectErr(t, err, repository.ErrNotFound)
+
+	expectExists := func(hash string, want bool) {
+		t.Helper()
+		exists, err := b.refreshTokens.Exists(ctx, hash)
+		must(t, err)
+		if exists != want {
+			t.Fatalf("token %q: expected exists=%v", hash, want)
+		}
+	}
+	expectExists("active", true)
+	expectExists("expired", false)
+
+	revoked, err := b.refreshTokens.RevokeExpires(ctx)
+	must(t, err)
+	if revoked != 1 {
+		t.Fatalf("expected one expired token to be revoked, got %d", revoked)
+	}
+
+	must(t, b.refreshTokens.DeleteByHash(ctx, "active"))
+	expectExists("active", false)
+
+	must(t, b.refreshTokens.Create(ctx, &models.RefreshToken{TokenHash: "other", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}))
+	must(t, b.refreshTokens.DeleteAllForUser(ctx, user.ID))
+	expectExists("other", false)
+}
+
+func testIdempotencyKeys(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	checkIdempotencyKeys(t, b.idempotency, user.ID)
+
+	// Истёкший ключ резервируется заново, а затем удаляется очисткой
+	expired := &models.IdempotencyRecord{UserID: user.ID, Key: "expired", Fingerprint: "a", ExpiresAt: time.Now().Add(-time.Minute)}
+	_, err := b.idempotency.Reserve(ctx, expired)
+	must(t, err)
+	existing, err := b.idempotency.Reserve(ctx, &models.IdempotencyRecord{UserID: user.ID, Key: "expired", Fingerprint: "b", ExpiresAt: time.Now().Add(-time.Second)})
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("expected expired key to be reserved again, got %+v", existing)
+	}
+	deleted, err := b.idempotency.DeleteExpired(ctx)
+	must(t, err)
+	if deleted != 1 {
+		t.Fatalf("expected one expired key to be deleted, got %d", deleted)
+	}
+
+	// Complete продлевает истёкший резерв до срока хранения ответа
+	leased := &models.IdempotencyRecord{UserID: user.ID, Key: "leased", Fingerprint: "a", ExpiresAt: time.Now().Add(-time.Second)}
+	_, err = b.idempotency.Reserve(ctx, leased)
+	must(t, err)
+	leased.StatusCode = 201
+	leased.ExpiresAt = time.Now().Add(time.Hour)
+	must(t, b.idempotency.Complete(ctx, leased))
+	existing, err = b.idempotency.Reserve(ctx, &models.IdempotencyRecord{UserID: user.ID, Key: "leased", Fingerprint: "b", ExpiresAt: time.Now().Add(time.Minute)})
+	must(t, err)
+	if existing == nil || !existing.Completed || existing.StatusCode != 201 {
+		t.Fatalf("expected completed record to outlive its reservation, got %+v", existing)
+	}
+
+	_, err = b.idempotency.Reserve(ctx, &models.IdempotencyRecord{UserID: user.ID + 1000, Key: "k", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	expectErr(t, err, repository.ErrNotFound)
+
+	// Ключи удалённого пользователя удаляются вместе с ним
+	must(t, b.users.Delete(ctx, user.ID))
+	other := createUser(t, b, "bob@example.com")
+	existing, err = b.idempotency.Reserve(ctx, &models.IdempotencyRecord{UserID: other.ID, Key: "done", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("expected keys to be scoped by user, got %+v", existing)
+	}
+}
+
+// Общая часть контракта ключей идемпотентности, userID должен существовать в хранилище
+func checkIdempotencyKeys(t *testing.T, repo repository.IdempotencyRepository, userID int) {
+	ctx := context.Background()
+	newRecord := func(key, fingerprint string) *models.IdempotencyRecord {
+		return &models.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint, ExpiresAt: time.Now().Add(time.Hour)}
+	}
+
+	record := newRecord("done", "fp-1")
+	existing, err := repo.Reserve(ctx, record)
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("expected new key to be reserved, got %+v", existing)
+	}
+
+	existing, err = repo.Reserve(ctx, newRecord("done", "fp-2"))
+	must(t, err)
+	if existing == nil || existing.Completed || existing.Fingerprint != "fp-1" {
+		t.Fatalf("expected pending record with the first fingerprint, got %+v", existing)
+	}
+
+	record.StatusCode = 201
+	record.Header = map[string]string{"Content-Type": "application/json", "ETag": `"1"`}
+	record.Body = []byte(`{"id":1}`)
+	must(t, repo.Complete(ctx, record))
+	expectErr(t, repo.Complete(ctx, newRecord("missing", "fp")), repository.ErrNotFound)
+
+	// Снятие резерва не затрагивает сохранённый ответ
+	must(t, repo.Release(ctx, userID, "done"))
+	existing, err = repo.Reserve(ctx, newRecord("done", "fp-1"))
+	must(t, err)
+	if existing == nil || !existing.Completed || existing.StatusCode != 201 ||
+		existing.Header["ETag"] != `"1"` || string(existing.Body) != `{"id":1}` {
+		t.Fatalf("expected completed record to be returned, got %+v", existing)
+	}
+
+	_, err = repo.Reserve(ctx, newRecord("failed", "fp"))
+	must(t, err)
+	must(t, repo.Release(ctx, userID, "failed"))
+	existing, err = repo.Reserve(ctx, newRecord("failed", "fp"))
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("expected released key to be reserved again, got %+v", existing)
+	}
+}
+
+func testSavedViews(t *testing.T, b *backend) {
+	ctx := context.Background()
+	owner := createUser(t, b, "owner@example.com")
+	member := createUser(t, b, "member@example.com")
+	stranger := createUser(t, b, "stranger@example.com")
+	board := createBoard(t, b, owner, "Sprint")
+	task := createTask(t, b, member, "Member task", models.StatusToDo)
+	must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+
+	filter := models.TaskFilter{Statuses: []models.TaskStatus{models.StatusToDo}, BoardID: board.ID, Fields: map[int]string{7: "high"}}
+	shared := &models.SavedView{UserID: owner.ID, Name: "Team todo", Filter: filter, Shared: true}
+	must(t, b.savedViews.Create(ctx, shared))
+	private := &models.SavedView{UserID: owner.ID, Name: "Alpha", Filter: models.TaskFilter{Search: "milk"}, Pinned: true}
+	must(t, b.savedViews.Create(ctx, private))
+
+	got, err := b.savedViews.GetById(ctx, shared.ID)
+	must(t, err)
+	if got.Name != "Team todo" || !got.Shared || got.Pinned || got.Filter.BoardID != board.ID ||
+		!slices.Equal(got.Filter.Statuses, filter.Statuses) || got.Filter.Fields[7] != "high" {
+		t.Fatalf("unexpected view %+v", got)
+	}
+
+	expectViews := func(list func(context.Context, int) ([]*models.SavedView, error), userID int, want ...int) {
+		t.Helper()
+		views, err := list(ctx, userID)
+		must(t, err)
+		ids := make([]int, 0, len(views))
+		for _, view := range views {
+			ids = append(ids, view.ID)
+		}
+		// Представления упорядочены по имени
+		if !slices.Equal(ids, want) {
+			t.Fatalf("expected views %v, got %v", want, ids)
+		}
+	}
+	expectViews(b.savedViews.ListAccessible, owner.ID, private.ID, shared.ID)
+	expectViews(b.savedViews.ListAccessible, member.ID, shared.ID)
+	expectViews(b.savedViews.ListAccessible, stranger.ID)
+	expectViews(b.savedViews.ListPinned, owner.ID, private.ID)
+
+	shared.Shared = false
+	shared.Pinned = true
+	must(t, b.savedViews.Update(ctx, shared))
+	expectViews(b.savedViews.ListAccessible, member.ID)
+	expectViews(b.savedViews.ListPinned, owner.ID, private.ID, shared.ID)
+
+	must(t, b.savedViews.Delete(ctx, private.ID))
+	_, err = b.savedViews.GetById(ctx, private.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.savedViews.Delete(ctx, private.ID), repository.ErrNotFound)
+	expectErr(t, b.savedViews.Update(ctx, private), repository.ErrNotFound)
+}
+
+func testTaskSeries(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	now := time.Now()
+
+	series := &models.TaskSeries{
+		UserID:    user.ID,
+		Title:     "Weekly report",
+		Rule:      models.RecurrenceRule{Frequency: models.FrequencyWeekly},
+		NextRunAt: now.Add(time.Hour),
+	}
+	must(t, b.taskSeries.Create(ctx, series))
+	if series.Rule.Interval != 1 {
+		t.Fatalf("expected default interval 1, got %d", series.Rule.Interval)
+	}
+
+	until := now.Add(-time.Hour)
+	expired := &models.TaskSeries{
+		UserID:    user.ID,
+		Title:     "Expired",
+		Rule:      models.RecurrenceRule{Frequency: models.FrequencyDaily, Interval: 2, Until: &until},
+		NextRunAt: now.Add(-2 * time.Hour),
+	}
+	must(t, b.taskSeries.Create(ctx, expired))
+
+	// Серия без незавершённых экземпляров ждёт создания нового
+	expectDue(t, b, now, series.ID)
+
+	open := createTask(t, b, user, "Weekly report", models.StatusToDo)
+	done := createTask(t, b, user, "Weekly report", models.StatusDone)
+	must(t, b.taskSeries.AttachTask(ctx, series.ID, done.ID))
+	must(t, b.taskSeries.AttachTask(ctx, series.ID, open.ID))
+	expectDue(t, b, now)
+	expectDue(t, b, now.Add(2*time.Hour), series.ID)
+
+	got, err := b.taskSeries.GetById(ctx, series.ID)
+	must(t, err)
+	if got.Title != "Weekly report" || got.Rule.Frequency != models.FrequencyWeekly || got.Rule.Until != nil ||
+		got.LastTaskID != max(open.ID, done.ID) || !sameTime(got.NextRunAt, series.NextRunAt) {
+		t.Fatalf("unexpected series %+v", got)
+	}
+	scheduled := got.NextRunAt
+
+	got, err = b.taskSeries.GetById(ctx, expired.ID)
+	must(t, err)
+	if got.Rule.Interval != 2 || got.Rule.Until == nil || !sameTime(*got.Rule.Until, until) {
+		t.Fatalf("unexpected expired series %+v", got)
+	}
+
+	must(t, b.taskSeries.SetNextRun(ctx, series.ID, scheduled, now.Add(-time.Minute)))
+	expectDue(t, b, now, series.ID)
+	// Запуск с тем же прежним временем уже перенесён, например другим экземпляром приложения
+	expectErr(t, b.taskSeries.SetNextRun(ctx, series.ID, scheduled, now.Add(time.Hour)), repository.ErrStale)
+	expectDue(t, b, now, series.ID)
+
+	// Изменения шаблона переносятся только на незавершённые экземпляры
+	series.Title = "Weekly summary"
+	series.Description = "Send on Friday"
+	series.NextRunAt = now.Add(time.Hour)
+	must(t, b.taskSeries.Update(ctx, series))
+	expectTitle(t, b, open.ID, "Weekly summary")
+	expectTitle(t, b, done.ID, "Weekly report")
+	if got, err := b.tasks.GetById(ctx, open.ID); err != nil || got.Version != open.Version+1 {
+		t.Fatalf("expected series update to bump instance version, got %+v, %v", got, err)
+	}
+
+	// Удаление серии оставляет экземпляры
+	must(t, b.taskSeries.Delete(ctx, series.ID))
+	_, err = b.taskSeries.GetById(ctx, series.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.taskSeries.Delete(ctx, series.ID), repository.ErrNotFound)
+
+	task, err := b.tasks.GetById(ctx, open.ID)
+	must(t, err)
+	if task.SeriesID != nil {
+		t.Fatalf("expected instance to be detached from deleted series, got %d", *task.SeriesID)
+	}
+}
+
+func testTimeEntries(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	other := createUser(t, b, "bob@example.com")
+	board := createBoard(t, b, user, "Sprint")
+	task := createTask(t, b, user, "Tracked", models.StatusToDo)
+	second := createTask(t, b, user, "Also tracked", models.StatusToDo)
+	must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+
+	_, err := b.timeEntries.GetRunning(ctx, user.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	_, err = b.timeEntries.StopTimer(ctx, user.ID)
+	expectErr(t, err, repository.ErrNotFound)
+
+	running, err := b.timeEntries.StartTimer(ctx, user.ID, task.ID)
+	must(t, err)
+	if running.EndedAt != nil || running.Manual || running.TaskID != task.ID {
+		t.Fatalf("unexpected running timer %+v", running)
+	}
+	_, err = b.timeEntries.StartTimer(ctx, user.ID, second.ID)
+	expectErr(t, err, repository.ErrConflict)
+	_, err = b.timeEntries.StartTimer(ctx, other.ID, task.ID+1000)
+	expectErr(t, err, repository.ErrNotFound)
+
+	got, err := b.timeEntries.GetRunning(ctx, user.ID)
+	must(t, err)
+	if got.ID != running.ID {
+		t.Fatalf("expected running timer %d, got %d", running.ID, got.ID)
+	}
+
+	stopped, err := b.timeEntries.StopTimer(ctx, user.ID)
+	must(t, err)
+	if stopped.ID != running.ID || stopped.EndedAt == nil || stopped.Seconds > 5 {
+		t.Fatalf("unexpected stopped timer %+v", stopped)
+	}
+	must(t, b.timeEntries.Delete(ctx, stopped.ID))
+
+	day := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	worklog := func(taskID int, started time.Time, minutes int) *models.TimeEntry {
+		t.Helper()
+		ended := started.Add(time.Duration(minutes) * time.Minute)
+		entry := &models.TimeEntry{UserID: user.ID, TaskID: taskID, StartedAt: started, EndedAt: &ended, Note: "work"}
+		must(t, b.timeEntries.CreateManual(ctx, entry))
+		if !entry.Manual || entry.Seconds != int64(minutes*60) {
+			t.Fatalf("unexpected worklog %+v", entry)
+		}
+		return entry
+	}
+	first := worklog(task.ID, day, 30)
+	worklog(task.ID, day.Add(2*time.Hour), 15)
+	worklog(second.ID, day.Add(time.Hour), 60)
+	worklog(task.ID, day.AddDate(0, 0, 1), 90)
+
+	got, err = b.timeEntries.GetById(ctx, first.ID)
+	must(t, err)
+	if got.Seconds != 1800 || got.Note != "work" || !got.Manual || !got.StartedAt.Equal(day) {
+		t.Fatalf("unexpected time entry %+v", got)
+	}
+
+	entries, err := b.timeEntries.ListByTask(ctx, task.ID)
+	must(t, err)
+	if len(entries) != 3 || !entries[0].StartedAt.After(entries[1].StartedAt) || !entries[1].StartedAt.After(entries[2].StartedAt) {
+		t.Fatalf("expected task entries newest first, got %d entries", len(entries))
+	}
+
+	expectTotal := func(total func(context.Context, int) (int64, error), id int, want int64) {
+		t.Helper()
+		seconds, err := total(ctx, id)
+		must(t, err)
+		if seconds != want {
+			t.Fatalf("expected total %d, got %d", want, seconds)
+		}
+	}
+	expectTotal(b.timeEntries.TotalByTask, task.ID, (30+15+90)*60)
+	expectTotal(b.timeEntries.TotalByBoard, board.ID, (30+15+90)*60)
+	expectTotal(b.timeEntries.TotalByUser, user.ID, (30+15+60+90)*60)
+	expectTotal(b.timeEntries.TotalByUser, other.ID, 0)
+
+	report, err := b.timeEntries.Report(ctx, user.ID, day, day.AddDate(0, 0, 1))
+	must(t, err)
+	if len(report) != 2 {
+		t.Fatalf("expected two report rows, got %d", len(report))
+	}
+	for _, row := range report {
+		if row.Day.Year() != 2024 || row.Day.Month() != time.March || row.Day.Day() != 10 {
+			t.Fatalf("unexpected report day %v", row.Day)
+		}
+	}
+	if report[0].TaskID != min(task.ID, second.ID) || report[1].TaskID != max(task.ID, second.ID) {
+		t.Fatalf("expected report rows ordered by task, got %+v, %+v", report[0], report[1])
+	}
+	for _, row := range report {
+		want := map[int]int64{task.ID: 45 * 60, second.ID: 60 * 60}[row.TaskID]
+		if row.Seconds != want {
+			t.Fatalf("expected %d seconds for task %d, got %d", want, row.TaskID, row.Seconds)
+		}
+	}
+
+	expectErr(t, b.timeEntries.Delete(ctx, stopped.ID), repository.ErrNotFound)
+}
+
+func testCustomFields(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	board := createBoard(t, b, user, "Sprint")
+	other := createBoard(t, b, user, "Other")
+
+	priority := createField(t, b, board, "Priority", models.FieldSingleSelect, "low", "high")
+	notes := createField(t, b, board, "Notes", models.FieldText)
+	createField(t, b, other, "Priority", models.FieldText)
+	expectErr(t, b.customFields.Create(ctx, &models.CustomField{BoardID: board.ID, Name: "Priority", Type: models.FieldText}), repository.ErrConflict)
+
+	got, err := b.customFields.GetById(ctx, priority.ID)
+	must(t, err)
+	if got.Name != "Priority" || got.Type != models.FieldSingleSelect || !slices.Equal(got.Options, []string{"low", "high"}) {
+		t.Fatalf("unexpected field %+v", got)
+	}
+
+	got, err = b.customFields.GetById(ctx, notes.ID)
+	must(t, err)
+	if len(got.Options) != 0 {
+		t.Fatalf("expected no options, got %v", got.Options)
+	}
+
+	priority.Name = "Urgency"
+	priority.Options = []string{"low", "medium", "high"}
+	priority.Required = true
+	must(t, b.customFields.Update(ctx, priority))
+
+	fields, err := b.customFields.ListByBoard(ctx, board.ID)
+	must(t, err)
+	if len(fields) != 2 || fields[0].ID != priority.ID || fields[0].Name != "Urgency" || !fields[0].Required || len(fields[0].Options) != 3 {
+		t.Fatalf("unexpected board fields %+v", fields)
+	}
+
+	task := createTask(t, b, user, "With fields", models.StatusToDo)
+	plain := createTask(t, b, user, "Without fields", models.StatusToDo)
+	fields, err = b.customFields.ListForTask(ctx, task.ID)
+	must(t, err)
+	if len(fields) != 0 {
+		t.Fatalf("expected no fields for task outside boards, got %d", len(fields))
+	}
+	must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+	fields, err = b.customFields.ListForTask(ctx, task.ID)
+	must(t, err)
+	if len(fields) != 2 {
+		t.Fatalf("expected board fields for task, got %d", len(fields))
+	}
+
+	must(t, b.customFields.SetValues(ctx, task.ID, map[int]json.RawMessage{
+		priority.ID: json.RawMessage(`"low"`),
+		notes.ID:    json.RawMessage(`"first"`),
+	}))
+	must(t, b.customFields.SetValues(ctx, task.ID, map[int]json.RawMessage{
+		priority.ID: json.RawMessage(`"high"`),
+		notes.ID:    nil,
+	}))
+
+	values, err := b.customFields.GetValues(ctx, task.ID)
+	must(t, err)
+	if len(values) != 1 || values[0].FieldID != priority.ID || values[0].Name != "Urgency" ||
+		values[0].Type != models.FieldSingleSelect || string(values[0].Value) != `"high"` {
+		t.Fatalf("unexpected values %+v", values)
+	}
+
+	byTask, err := b.customFields.GetValuesForTasks(ctx, []int{task.ID, plain.ID})
+	must(t, err)
+	if len(byTask) != 1 || len(byTask[task.ID]) != 1 {
+		t.Fatalf("unexpected values by task %+v", byTask)
+	}
+	byTask, err = b.customFields.GetValuesForTasks(ctx, nil)
+	must(t, err)
+	if byTask == nil || len(byTask) != 0 {
+		t.Fatalf("expected empty map for no tasks, got %+v", byTask)
+	}
+
+	// Удаление поля удаляет его значения
+	must(t, b.customFields.Delete(ctx, priority.ID))
+	values, err = b.customFields.GetValues(ctx, task.ID)
+	must(t, err)
+	if len(values) != 0 {
+		t.Fatalf("expected values of deleted field to be removed, got %+v", values)
+	}
+	_, err = b.customFields.GetById(ctx, priority.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	expectErr(t, b.customFields.Delete(ctx, priority.ID), repository.ErrNotFound)
+	expectErr(t, b.customFields.Update(ctx, priority), repository.ErrNotFound)
+}
+
+// Удаление пользователя, доски и задачи каскадно удаляет зависимые записи
+func testCascades(t *testing.T, b *backend) {
+	ctx := context.Background()
+	alice := createUser(t, b, "alice@example.com")
+	bob := createUser(t, b, "bob@example.com")
+	board := createBoard(t, b, alice, "Sprint")
+	field := createField(t, b, board, "Notes", models.FieldText)
+	view := &models.SavedView{UserID: bob.ID, Name: "Board view", Filter: models.TaskFilter{BoardID: board.ID}}
+	must(t, b.savedViews.Create(ctx, view))
+
+	task := createTask(t, b, bob, "Bob task", models.StatusToDo)
+	must(t, b.boardTasks.AddTask(ctx, board.ID, task.ID))
+	must(t, b.customFields.SetValues(ctx, task.ID, map[int]json.RawMessage{field.ID: json.RawMessage(`"note"`)}))
+	entry, err := b.timeEntries.StartTimer(ctx, bob.ID, task.ID)
+	must(t, err)
+
+	must(t, b.boards.Delete(ctx, board.ID, board.Version))
+	_, err = b.savedViews.GetById(ctx, view.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	_, err = b.customFields.GetById(ctx, field.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	boards, err := b.boardTasks.GetBoards(ctx, task.ID)
+	must(t, err)
+	if len(boards) != 0 {
+		t.Fatalf("expected task to be removed from deleted board, got %v", boards)
+	}
+
+	must(t, b.tasks.Delete(ctx, task.ID, task.Version))
+	_, err = b.timeEntries.GetById(ctx, entry.ID)
+	expectErr(t, err, repository.ErrNotFound)
+
+	other := createTask(t, b, bob, "Other task", models.StatusToDo)
+	must(t, b.users.Delete(ctx, bob.ID))
+	_, err = b.tasks.GetById(ctx, other.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	_, err = b.users.GetById(ctx, alice.ID)
+	must(t, err)
+}
+
+func testCanceled(t *testing.T, b *backend) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := b.users.GetByEmail(ctx, "alice@example.com")
+	expectErr(t, err, repository.ErrCanceled)
+	expectErr(t, b.users.Create(ctx, &models.User{Email: "alice@example.com", PasswordHash: "hash"}), context.Canceled)
+}
+
+func testUnitOfWork(t *testing.T, b *backend) {
+	ctx := context.Background()
+	user := createUser(t, b, "alice@example.com")
+	board := createBoard(t, b, user, "Sprint")
+	errFailed := errors.New("failed")
+
+	var committed models.Task
+	must(t, b.uow.Do(ctx, func(ctx context.Context) error {
+		committed = models.Task{Title: "Committed", Status: models.StatusToDo, UserID: user.ID}
+		if err := b.tasks.Create(ctx, &committed); err != nil {
+			return err
+		}
+		return b.boardTasks.AddTask(ctx, board.ID, committed.ID)
+	}))
+	expectOnBoard(t, b, board.ID, committed.ID, true)
+
+	// Ошибка откатывает все изменения, включая транзакции самих репозиториев
+	var orphan models.Task
+	err := b.uow.Do(ctx, func(ctx context.Context) error {
+		orphan = models.Task{Title: "Orphan", Status: models.StatusToDo, UserID: user.ID}
+		if err := b.tasks.Create(ctx, &orphan); err != nil {
+			return err
+		}
+		if err := b.boards.SetWIPLimits(ctx, board.ID, map[models.TaskStatus]int{models.StatusDone: 3}); err != nil {
+			return err
+		}
+		return b.boardTasks.AddTask(ctx, board.ID+1000, orphan.ID)
+	})
+	expectErr(t, err, repository.ErrNotFound)
+	_, err = b.tasks.GetById(ctx, orphan.ID)
+	expectErr(t, err, repository.ErrNotFound)
+	limits, err := b.boards.GetWIPLimits(ctx, board.ID)
+	must(t, err)
+	if len(limits) != 0 {
+		t.Fatalf("expected WIP limits to be rolled back, got %v", limits)
+	}
+
+	// Отклонённая операция репозитория не мешает завершить остальные
+	other := createBoard(t, b, user, "Other")
+	must(t, b.boards.SetWIPLimits(ctx, other.ID, map[models.TaskStatus]int{models.StatusToDo: 1}))
+	blocker := createTask(t, b, user, "Blocker", models.StatusToDo)
+	must(t, b.boardTasks.AddTask(ctx, other.ID, blocker.ID))
+	must(t, b.uow.Do(ctx, func(ctx context.Context) error {
+		err := b.boardTasks.MoveTask(ctx, board.ID, other.ID, committed.ID)
+		if !errors.Is(err, repository.ErrConflict) {
+			t.Errorf("expected WIP limit conflict, got %v", err)
+		}
+		committed.Title = "Renamed"
+		return b.tasks.Update(ctx, &committed)
+	}))
+	expectOnBoard(t, b, board.ID, committed.ID, true)
+	expectOnBoard(t, b, other.ID, committed.ID, false)
+	expectTitle(t, b, committed.ID, "Renamed")
+
+	// Вложенная единица работы присоединяется к внешней
+	err = b.uow.Do(ctx, func(ctx context.Context) error {
+		if err := b.uow.Do(ctx, func(ctx context.Context) error {
+			return b.tasks.Delete(ctx, committed.ID, committed.Version)
+		}); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	expectErr(t, err, errFailed)
+	expectTitle(t, b, committed.ID, "Renamed")
+
+	// Ошибка в точке сохранения откатывает только её изменения
+	var kept models.Task
+	must(t, b.uow.Do(ctx, func(ctx context.Context) error {
+		kept = models.Task{Title: "Kept", Status: models.StatusToDo, UserID: user.ID}
+		if err := b.tasks.Create(ctx, &kept); err != nil {
+			return err
+		}
+		err := b.uow.Savepoint(ctx, func(ctx context.Context) error {
+			if err := b.tasks.Delete(ctx, committed.ID, committed.Version); err != nil {
+				return err
+			}
+			return b.boardTasks.AddTask(ctx, board.ID+1000, kept.ID)
+		})
+		expectErr(t, err, repository.ErrNotFound)
+		return b.boardTasks.AddTask(ctx, board.ID, kept.ID)
+	}))
+	expectTitle(t, b, committed.ID, "Renamed")
+	expectOnBoard(t, b, board.ID, kept.ID, true)
+
+	// Действия AfterCommit выполняются только после фиксации
+	var hooks []string
+	must(t, b.uow.Do(ctx, func(ctx context.Context) error {
+		repository.AfterCommit(ctx, func() { hooks = append(hooks, "committed") })
+		if len(hooks) != 0 {
+			t.Fatal("expected hook to wait for commit")
+		}
+		return nil
+	}))
+	err = b.uow.Do(ctx, func(ctx context.Context) error {
+		repository.AfterCommit(ctx, func() { hooks = append(hooks, "rolled back") })
+		return errFailed
+	})
+	expectErr(t, err, errFailed)
+	repository.AfterCommit(ctx, func() { hooks = append(hooks, "immediate") })
+	if !slices.Equal(hooks, []string{"committed", "immediate"}) {
+		t.Fatalf("expected hooks committed,immediate, got %v", hooks)
+	}
+
+	// Паника откатывает изменения и передаётся дальше
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic to be propagated")
+			}
+		}()
+		b.uow.Do(ctx, func(ctx context.Context) error {
+			if err := b.tasks.Delete(ctx, committed.ID, committed.Version); err != nil {
+				return err
+			}
+			panic(errFailed)
+		})
+	}()
+	expectTitle(t, b, committed.ID, "Renamed")
+}
+
+func createUser(t *testing.T, b *backend, email string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email, PasswordHash: "hash"}
+	must(t, b.users.Create(context.Background(), user))
+	return user
+}
+
+func createBoard(t *testing.T, b *backend, owner *models.User, name string) *models.Board {
+	t.Helper()
+	board := &models.Board{Name: name, UserID: owner.ID}
+	must(t, b.boards.Create(context.Background(), board))
+	return board
+}
+
+func createTask(t *testing.T, b *backend, owner *models.User, title string, status models.TaskStatus) *models.Task {
+	t.Helper()
+	task := &models.Task{Title: title, Status: status, UserID: owner.ID}
+	must(t, b.tasks.Create(context.Background(), task))
+	return task
+}
+
+func createField(t *testing.T, b *backend, board *models.Board, name string, fieldType models.CustomFieldType, options ...string) *models.CustomField {
+	t.Helper()
+	field := &models.CustomField{BoardID: board.ID, Name: name, Type: fieldType, Options: options}
+	must(t, b.customFields.Create(context.Background(), field))
+	return field
+}
+
+func expectMember(t *testing.T, b *backend, boardID, userID int, want bool) {
+	t.Helper()
+	isMember, err := b.boards.IsMember(context.Background(), boardID, userID)
+	must(t, err)
+	if isMember != want {
+		t.Fatalf("user %d on board %d: expected member=%v", userID, boardID, want)
+	}
+}
+
+func expectOnBoard(t *testing.T, b *backend, boardID, taskID int, want bool) {
+	t.Helper()
+	exists, err := b.boardTasks.Exists(context.Background(), boardID, taskID)
+	must(t, err)
+	if exists != want {
+		t.Fatalf("task %d on board %d: expected exists=%v", taskID, boardID, want)
+	}
+}
+
+func expectDue(t *testing.T, b *backend, now time.Time, want ...int) {
+	t.Helper()
+	due, err := b.taskSeries.ListDue(context.Background(), now)
+	must(t, err)
+	ids := make([]int, 0, len(due))
+	for _, series := range due {
+		ids = append(ids, series.ID)
+	}
+	expectIDs(t, ids, want...)
+}
+
+func expectTitle(t *testing.T, b *backend, taskID int, title string) {
+	t.Helper()
+	task, err := b.tasks.GetById(context.Background(), taskID)
+	must(t, err)
+	if task.Title != title {
+		t.Fatalf("task %d: expected title %q, got %q", taskID, title, task.Title)
+	}
+}
+
+// Сравнивает идентификаторы без учёта порядка
+func expectIDs(t *testing.T, got []int, want ...int) {
+	t.Helper()
+	got = slices.Sorted(slices.Values(got))
+	want = slices.Sorted(slices.Values(want))
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected IDs %v, got %v", want, got)
+	}
+}
+
+// Postgres хранит время с точностью до микросекунд
+func sameTime(a, b time.Time) bool {
+	return a.Sub(b).Abs() < time.Millisecond
+}
+
+func boardIDs(boards []*models.Board) []int {
+	ids := make([]int, 0, len(boards))
+	for _, board := range boards {
+		ids = append(ids, board.ID)
+	}
+	return ids
+}
+
+func taskIDs(tasks []*models.Task) []int {
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected error %v, got %v", target, err)
+	}
+}
@@ -0,0 +1,261 @@
+package custom_field_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type CustomFieldSQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewCustomFieldSQLiteRepo(db *sql.DB, timeout time.Duration) *CustomFieldSQLiteRepo {
+	return &CustomFieldSQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+func (r *CustomFieldSQLiteRepo) Create(ctx context.Context, field *models.CustomField) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO board_custom_fields (board_id, name, type, options, required, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	options, err := json.Marshal(optionsOf(field))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		field.BoardID,
+		field.Name,
+		field.Type,
+		string(options),
+		field.Required,
+		now,
+	).Scan(&field.ID)
+
+	if err != nil {
+		return repository.TranslateSQLite(err, "custom field")
+	}
+
+	field.CreatedAt = now
+	return nil
+}
+
+func (r *CustomFieldSQLiteRepo) GetById(ctx context.Context, id int) (_ *models.CustomField, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, board_id, name, type, options, required, created_at
+		FROM board_custom_fields
+		WHERE id = $1
+	`
+
+	field, err := scanField(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("custom field")
+		}
+		return nil, err
+	}
+
+	return field, nil
+}
+
+// Тип поля не меняется, чтобы не инвалидировать сохранённые значения
+func (r *CustomFieldSQLiteRepo) Update(ctx context.Context, field *models.CustomField) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE board_custom_fields
+		SET name = $1,
+			options = $2,
+			required = $3
+		WHERE id = $4
+	`
+
+	options, err := json.Marshal(optionsOf(field))
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, field.Name, string(options), field.Required, field.ID)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "custom field")
+}
+
+func (r *CustomFieldSQLiteRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM board_custom_fields WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "custom field")
+}
+
+func (r *CustomFieldSQLiteRepo) ListByBoard(ctx context.Context, boardID int) (_ []*models.CustomField, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, board_id, name, type, options, required, created_at
+		FROM board_custom_fields
+		WHERE board_id = $1
+		ORDER BY id
+	`
+
+	return r.list(ctx, query, boardID)
+}
+
+// Поля всех досок, на которых находится задача
+func (r *CustomFieldSQLiteRepo) ListForTask(ctx context.Context, taskID int) (_ []*models.CustomField, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT f.id, f.board_id, f.name, f.type, f.options, f.required, f.created_at
+		FROM board_custom_fields f
+		JOIN board_tasks bt ON bt.board_id = f.board_id
+		WHERE bt.task_id = $1
+		ORDER BY f.board_id, f.id
+	`
+
+	return r.list(ctx, query, taskID)
+}
+
+// Сохраняет значения полей задачи, значение nil удаляет сохранённое значение
+func (r *CustomFieldSQLiteRepo) SetValues(ctx context.Context, taskID int, values map[int]json.RawMessage) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for fieldID, value := range values {
+		if value == nil {
+			_, err = tx.ExecContext(ctx,
+				`DELETE FROM task_custom_field_values WHERE task_id = $1 AND field_id = $2`,
+				taskID,
+				fieldID,
+			)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO task_custom_field_values (task_id, field_id, value)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (task_id, field_id) DO UPDATE SET value = excluded.value
+			`, taskID, fieldID, string(value))
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *CustomFieldSQLiteRepo) GetValues(ctx context.Context, taskID int) (_ []models.CustomFieldValue, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	values, err := r.GetValuesForTasks(ctx, []int{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return values[taskID], nil
+}
+
+// Значения полей сразу для нескольких задач, ключ - идентификатор задачи
+func (r *CustomFieldSQLiteRepo) GetValuesForTasks(ctx context.Context, taskIDs []int) (_ map[int][]models.CustomFieldValue, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	values := make(map[int][]models.CustomFieldValue)
+	if len(taskIDs) == 0 {
+		return values, nil
+	}
+
+	placeholders := make([]string, 0, len(taskIDs))
+	args := make([]interface{}, 0, len(taskIDs))
+	for _, id := range taskIDs {
+		args = append(args, id)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+
+	query := `
+		SELECT v.task_id, f.id, f.name, f.type, v.value
+		FROM task_custom_field_values v
+		JOIN board_custom_fields f ON f.id = v.field_id
+		WHERE v.task_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY v.task_id, f.board_id, f.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var value models.CustomFieldValue
+		var raw []byte
+		if err := rows.Scan(&taskID, &value.FieldID, &value.Name, &value.Type, &raw); err != nil {
+			return nil, err
+		}
+		value.Value = raw
+		values[taskID] = append(values[taskID], value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (r *CustomFieldSQLiteRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.CustomField, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []*models.CustomField
+	for rows.Next() {
+		field, err := scanField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Ошибки репозиториев, проверяются через errors.Is
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrStale    = errors.New("stale")
+)
+
+// Запись не найдена, текст ошибки - "<entity> not found"
+type NotFoundError struct {
+	Entity string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Entity + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Операция противоречит текущему состоянию данных (дубликат, запущенный таймер и т.п.)
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Запись изменилась после того, как клиент её прочитал: версия не совпала с ожидаемой
+type StaleError struct {
+	Entity string
+}
+
+func (e *StaleError) Error() string {
+	return e.Entity + " was modified by another request"
+}
+
+func (e *StaleError) Is(target error) bool {
+	return target == ErrStale
+}
+
+// Ошибка превышения WIP-лимита доски
+type WIPLimitError struct {
+	BoardID int
+	Status  models.TaskStatus
+	Limit   int
+}
+
+func (e *WIPLimitError) Error() string {
+	return fmt.Sprintf("WIP limit reached: board %d allows at most %d tasks in status %q", e.BoardID, e.Limit, e.Status)
+}
+
+func (e *WIPLimitError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func NotFound(entity string) error {
+	return &NotFoundError{Entity: entity}
+}
+
+func Conflict(message string) error {
+	return &ConflictError{Message: message}
+}
+
+func Stale(entity string) error {
+	return &StaleError{Entity: entity}
+}
+
+// Возвращает NotFound, если запрос не затронул ни одной строки
+func CheckAffected(result sql.Result, entity string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return NotFound(entity)
+	}
+	return nil
+}
+
+/*
+Разбирает результат запроса с условием на версию записи
+Если ни одна строка не затронута, отличает удалённую запись (NotFound) от изменённой (Stale)
+*/
+func CheckVersion(ctx context.Context, db *DB, result sql.Result, table string, id int, entity string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1)`
+	if err := db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return NotFound(entity)
+	}
+	return Stale(entity)
+}
+
+// Нарушение уникальности - конфликт, нарушение внешнего ключа - ссылка на несуществующую запись
+func TranslatePQ(err error, entity string) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "23505":
+		return Conflict(entity + " already exists")
+	case "23503":
+		return NotFound("referenced " + entity)
+	}
+	return err
+}
+
+// То же для ошибок SQLite
+func TranslateSQLite(err error, entity string) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return Conflict(entity + " already exists")
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return NotFound("referenced " + entity)
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package idempotency_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type IdempotencyPostgresRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewIdempotencyPostgresRepo(db *sql.DB, timeout time.Duration) *IdempotencyPostgresRepo {
+	return &IdempotencyPostgresRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+/*
+Вставка и перезапись истёкшего ключа выполняются одним запросом,
+поэтому из двух одновременных запросов с одним ключом резерв получает только один
+*/
+func (r *IdempotencyPostgresRepo) Reserve(ctx context.Context, record *models.IdempotencyRecord) (_ *models.IdempotencyRecord, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			completed = FALSE,
+			status_code = 0,
+			response_header = '{}',
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+	`
+
+	record.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx,
+		query,
+		record.UserID,
+		record.Key,
+		record.Fingerprint,
+		record.CreatedAt,
+		record.ExpiresAt,
+	)
+	if err != nil {
+		return nil, repository.TranslatePQ(err, "user")
+	}
+
+	reserved, err := result.RowsAffected()
+	if err != nil || reserved > 0 {
+		return nil, err
+	}
+	return r.get(ctx, record.UserID, record.Key)
+}
+
+func (r *IdempotencyPostgresRepo) get(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, key, fingerprint, completed, status_code, response_header, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	record := &models.IdempotencyRecord{}
+	var header []byte
+	err := r.db.QueryRowContext(ctx, query, userID, key).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.Fingerprint,
+		&record.Completed,
+		&record.StatusCode,
+		&header,
+		&record.Body,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Ключ освободили между вставкой и чтением, запрос можно повторить
+			return nil, repository.Conflict("idempotency key was released")
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(header, &record.Header); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *IdempotencyPostgresRepo) Complete(ctx context.Context, record *models.IdempotencyRecord) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE idempotency_keys
+		SET completed = TRUE, status_code = $1, response_header = $2, response_body = $3, expires_at = $4
+		WHERE user_id = $5 AND key = $6 AND NOT completed
+	`
+
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, record.StatusCode, header, record.Body, record.ExpiresAt.UTC(), record.UserID, record.Key)
+	if err != nil {
+		return err
+	}
+	if err := repository.CheckAffected(result, "idempotency key"); err != nil {
+		return err
+	}
+
+	record.Completed = true
+	return nil
+}
+
+func (r *IdempotencyPostgresRepo) Release(ctx context.Context, userID int, key string) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND NOT completed`
+	_, err = r.db.ExecContext(ctx, query, userID, key)
+	return err
+}
+
+func (r *IdempotencyPostgresRepo) DeleteExpired(ctx context.Context) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+	result, err := r.db.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
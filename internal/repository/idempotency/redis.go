@@ -0,0 +1,108 @@
+package idempotency_repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Ключи идемпотентности в Redis
+Запись хранится в JSON под ключом idempotency:<пользователь>:<ключ> и удаляется самим Redis по истечении срока.
+Ссылку на пользователя Redis не проверяет, записи удалённого пользователя просто истекают
+*/
+type IdempotencyRedisRepo struct {
+	client  *redis.Client
+	timeout time.Duration
+}
+
+func NewIdempotencyRedisRepo(client *redis.Client, timeout time.Duration) *IdempotencyRedisRepo {
+	return &IdempotencyRedisRepo{client: client, timeout: timeout}
+}
+
+func redisKey(userID int, key string) string {
+	return "idempotency:" + strconv.Itoa(userID) + ":" + key
+}
+
+// SET NX резервирует ключ атомарно, истёкший ключ Redis к этому моменту уже удалил
+func (r *IdempotencyRedisRepo) Reserve(ctx context.Context, record *models.IdempotencyRecord) (_ *models.IdempotencyRecord, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	record.CreatedAt = time.Now()
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	key := redisKey(record.UserID, record.Key)
+	reserved, err := r.client.SetNX(ctx, key, value, time.Until(record.ExpiresAt)).Result()
+	if err != nil || reserved {
+		return nil, err
+	}
+
+	raw, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, repository.Conflict("idempotency key was released")
+		}
+		return nil, err
+	}
+
+	existing := &models.IdempotencyRecord{}
+	if err := json.Unmarshal(raw, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (r *IdempotencyRedisRepo) Complete(ctx context.Context, record *models.IdempotencyRecord) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	completed := *record
+	completed.Completed = true
+	value, err := json.Marshal(completed)
+	if err != nil {
+		return err
+	}
+
+	// XX не даёт воскресить ключ, резерв которого уже истёк; срок ключа продлевается до срока хранения ответа
+	err = r.client.SetArgs(ctx, redisKey(record.UserID, record.Key), value, redis.SetArgs{Mode: "XX", ExpireAt: record.ExpiresAt}).Err()
+	if errors.Is(err, redis.Nil) {
+		return repository.NotFound("idempotency key")
+	}
+	if err != nil {
+		return err
+	}
+
+	record.Completed = true
+	return nil
+}
+
+// Резерв снимается, только если ответ ещё не сохранён
+var releaseScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value and not cjson.decode(value)["Completed"] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (r *IdempotencyRedisRepo) Release(ctx context.Context, userID int, key string) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	return releaseScript.Run(ctx, r.client, []string{redisKey(userID, key)}).Err()
+}
+
+// Истёкшие ключи удаляет сам Redis
+func (r *IdempotencyRedisRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,140 @@
+package idempotency_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type IdempotencySQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewIdempotencySQLiteRepo(db *sql.DB, timeout time.Duration) *IdempotencySQLiteRepo {
+	return &IdempotencySQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+func (r *IdempotencySQLiteRepo) Reserve(ctx context.Context, record *models.IdempotencyRecord) (_ *models.IdempotencyRecord, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = excluded.fingerprint,
+			completed = FALSE,
+			status_code = 0,
+			response_header = '{}',
+			response_body = NULL,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at
+	`
+
+	record.CreatedAt = time.Now().UTC()
+	result, err := r.db.ExecContext(ctx,
+		query,
+		record.UserID,
+		record.Key,
+		record.Fingerprint,
+		record.CreatedAt,
+		record.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return nil, repository.TranslateSQLite(err, "user")
+	}
+
+	reserved, err := result.RowsAffected()
+	if err != nil || reserved > 0 {
+		return nil, err
+	}
+	return r.get(ctx, record.UserID, record.Key)
+}
+
+func (r *IdempotencySQLiteRepo) get(ctx context.Context, userID int, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, key, fingerprint, completed, status_code, response_header, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2
+	`
+
+	record := &models.IdempotencyRecord{}
+	var header string
+	err := r.db.QueryRowContext(ctx, query, userID, key).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.Fingerprint,
+		&record.Completed,
+		&record.StatusCode,
+		&header,
+		&record.Body,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Ключ освободили между вставкой и чтением, запрос можно повторить
+			return nil, repository.Conflict("idempotency key was released")
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *IdempotencySQLiteRepo) Complete(ctx context.Context, record *models.IdempotencyRecord) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE idempotency_keys
+		SET completed = TRUE, status_code = $1, response_header = $2, response_body = $3, expires_at = $4
+		WHERE user_id = $5 AND key = $6 AND NOT completed
+	`
+
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, query, record.StatusCode, string(header), record.Body, record.ExpiresAt.UTC(), record.UserID, record.Key)
+	if err != nil {
+		return err
+	}
+	if err := repository.CheckAffected(result, "idempotency key"); err != nil {
+		return err
+	}
+
+	record.Completed = true
+	return nil
+}
+
+func (r *IdempotencySQLiteRepo) Release(ctx context.Context, userID int, key string) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND NOT completed`
+	_, err = r.db.ExecContext(ctx, query, userID, key)
+	return err
+}
+
+func (r *IdempotencySQLiteRepo) DeleteExpired(ctx context.Context) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+	result, err := r.db.ExecContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
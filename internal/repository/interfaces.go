@@ -1,44 +1,128 @@
 package repository
 
-import "github.com/CAATHARSIS/task-tracking/internal/models"
+import (
+	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+)
+
+/*
+Update и Delete задач и досок проверяют версию записи (оптимистичная блокировка)
+Если версия изменилась, возвращается ErrStale; успешный Update увеличивает Version модели.
+GetByIDs загружает задачи одним запросом в порядке ids, отсутствующие задачи пропускаются
+*/
 type TaskRepository interface {
-	Create(task *models.Task) error
-	GetById(id int) (*models.Task, error)
-	Update(task *models.Task) error
-	Delete(id int) error
-	ListByUser(userID int) ([]*models.Task, error)
+	Create(ctx context.Context, task *models.Task) error
+	GetById(ctx context.Context, id int) (*models.Task, error)
+	GetByIDs(ctx context.Context, ids []int) ([]*models.Task, error)
+	Update(ctx context.Context, task *models.Task) error
+	Delete(ctx context.Context, id, version int) error
+	ListByUser(ctx context.Context, userID int) ([]*models.Task, error)
+	ListByFilter(ctx context.Context, userID int, filter models.TaskFilter) ([]*models.Task, error)
 }
 
 type UserRepository interface {
-	Create(user *models.User) error
-	GetById(id int) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id int) error
+	Create(ctx context.Context, user *models.User) error
+	GetById(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id int) error
 }
 
 type BoardRepository interface {
-	Create(board *models.Board) error
-	GetById(id int) (*models.Board, error)
-	Update(*models.Board) error
-	Delete(id int) error
-	ListByUser(userID int) ([]*models.Board, error)
+	Create(ctx context.Context, board *models.Board) error
+	GetById(ctx context.Context, id int) (*models.Board, error)
+	Update(ctx context.Context, board *models.Board) error
+	Delete(ctx context.Context, id, version int) error
+	ListByUser(ctx context.Context, userID int) ([]*models.Board, error)
+	IsMember(ctx context.Context, boardID, userID int) (bool, error)
+	GetWIPLimits(ctx context.Context, boardID int) (map[models.TaskStatus]int, error)
+	SetWIPLimits(ctx context.Context, boardID int, limits map[models.TaskStatus]int) error
+	Summary(ctx context.Context, boardID int) ([]*models.BoardColumnSummary, error)
 }
 
 type RefreshTokenRepository interface {
-	Create(token *models.RefreshToken) error
-	GetByHash(tokenHash string) (*models.RefreshToken, error)
-	DeleteByHash(tokenHash string) error
-	DeleteAllForUser(userID int) error
-	Exists(tokenHash string) (bool, error)
-	RevokeExpires() (int64, error)
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	DeleteByHash(ctx context.Context, tokenHash string) error
+	DeleteAllForUser(ctx context.Context, userID int) error
+	Exists(ctx context.Context, tokenHash string) (bool, error)
+	RevokeExpires(ctx context.Context) (int64, error)
 }
 
+/*
+ListTasks возвращает задачи доски целиком одним запросом, в порядке создания
+AddTask и MoveTask не меняют доску, если с задачей колонка превысит WIP-лимит, и возвращают WIPLimitError.
+CheckLimits проверяет лимиты после смены статуса задачи и вызывается в той же единице работы, что и изменение
+*/
 type BoardTaskRepository interface {
-	AddTask(boardID, taskID int) error
-	RemoveTask(boardID, taskID int) error
-	GetTasks(boardID int) ([]int, error)
-	MoveTask(fromBoardID, toBoardID, taskID int) error
-	Exists(boardID, taskID int) (bool, error)
+	AddTask(ctx context.Context, boardID, taskID int) error
+	RemoveTask(ctx context.Context, boardID, taskID int) error
+	GetTasks(ctx context.Context, boardID int) ([]int, error)
+	ListTasks(ctx context.Context, boardID int) ([]*models.Task, error)
+	GetBoards(ctx context.Context, taskID int) ([]int, error)
+	MoveTask(ctx context.Context, fromBoardID, toBoardID, taskID int) error
+	CheckLimits(ctx context.Context, taskID int) error
+	Exists(ctx context.Context, boardID, taskID int) (bool, error)
+}
+
+type SavedViewRepository interface {
+	Create(ctx context.Context, view *models.SavedView) error
+	GetById(ctx context.Context, id int) (*models.SavedView, error)
+	Update(ctx context.Context, view *models.SavedView) error
+	Delete(ctx context.Context, id int) error
+	ListAccessible(ctx context.Context, userID int) ([]*models.SavedView, error)
+	ListPinned(ctx context.Context, userID int) ([]*models.SavedView, error)
+}
+
+type TaskSeriesRepository interface {
+	Create(ctx context.Context, series *models.TaskSeries) error
+	GetById(ctx context.Context, id int) (*models.TaskSeries, error)
+	Update(ctx context.Context, series *models.TaskSeries) error
+	Delete(ctx context.Context, id int) error
+	AttachTask(ctx context.Context, seriesID, taskID int) error
+	ListDue(ctx context.Context, now time.Time) ([]*models.TaskSeries, error)
+	// Переносит следующий запуск с prev на next; если серию уже перенёс другой экземпляр приложения, возвращает ErrStale
+	SetNextRun(ctx context.Context, id int, prev, next time.Time) error
+}
+
+type TimeEntryRepository interface {
+	StartTimer(ctx context.Context, userID, taskID int) (*models.TimeEntry, error)
+	StopTimer(ctx context.Context, userID int) (*models.TimeEntry, error)
+	GetRunning(ctx context.Context, userID int) (*models.TimeEntry, error)
+	CreateManual(ctx context.Context, entry *models.TimeEntry) error
+	GetById(ctx context.Context, id int) (*models.TimeEntry, error)
+	Delete(ctx context.Context, id int) error
+	ListByTask(ctx context.Context, taskID int) ([]*models.TimeEntry, error)
+	TotalByTask(ctx context.Context, taskID int) (int64, error)
+	TotalByBoard(ctx context.Context, boardID int) (int64, error)
+	TotalByUser(ctx context.Context, userID int) (int64, error)
+	Report(ctx context.Context, userID int, from, to time.Time) ([]*models.TimeReportRow, error)
+}
+
+type CustomFieldRepository interface {
+	Create(ctx context.Context, field *models.CustomField) error
+	GetById(ctx context.Context, id int) (*models.CustomField, error)
+	Update(ctx context.Context, field *models.CustomField) error
+	Delete(ctx context.Context, id int) error
+	ListByBoard(ctx context.Context, boardID int) ([]*models.CustomField, error)
+	ListForTask(ctx context.Context, taskID int) ([]*models.CustomField, error)
+	SetValues(ctx context.Context, taskID int, values map[int]json.RawMessage) error
+	GetValues(ctx context.Context, taskID int) ([]models.CustomFieldValue, error)
+	GetValuesForTasks(ctx context.Context, taskIDs []int) (map[int][]models.CustomFieldValue, error)
+}
+
+/*
+Ключи идемпотентности запросов API
+Reserve сохраняет запись, если ключа нет или он истёк, и возвращает nil; иначе возвращает уже сохранённую запись.
+Резерв действует до ExpiresAt записи, Complete сохраняет ответ зарезервированного ключа и продлевает срок до её ExpiresAt.
+Release снимает резерв, чтобы запрос можно было повторить
+*/
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Release(ctx context.Context, userID int, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
 }
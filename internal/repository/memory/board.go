@@ -0,0 +1,191 @@
+package memory_repo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type BoardMemoryRepo struct {
+	s *Store
+}
+
+func NewBoardMemoryRepo(s *Store) *BoardMemoryRepo {
+	return &BoardMemoryRepo{s: s}
+}
+
+func (r *BoardMemoryRepo) Create(ctx context.Context, board *models.Board) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[board.UserID]; !ok {
+		return repository.NotFound("referenced user")
+	}
+
+	if !board.EstimateUnit.IsValid() {
+		board.EstimateUnit = models.EstimatePoints
+	}
+
+	now := time.Now()
+	board.ID = r.s.nextID()
+	board.Version = 1
+	board.CreatedAt = now
+	board.UpdateddAt = now
+	r.s.boards[board.ID] = copyBoard(board)
+	return nil
+}
+
+func (r *BoardMemoryRepo) GetById(ctx context.Context, id int) (*models.Board, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	board, ok := r.s.boards[id]
+	if !ok {
+		return nil, repository.NotFound("board")
+	}
+	return copyBoard(board), nil
+}
+
+func (r *BoardMemoryRepo) Update(ctx context.Context, board *models.Board) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.boards[board.ID]
+	if !ok {
+		return repository.NotFound("board")
+	}
+	if stored.Version != board.Version {
+		return repository.Stale("board")
+	}
+
+	if !board.EstimateUnit.IsValid() {
+		board.EstimateUnit = models.EstimatePoints
+	}
+
+	stored.Name = board.Name
+	stored.EstimateUnit = board.EstimateUnit
+	stored.UpdateddAt = board.UpdateddAt
+	stored.Version++
+	board.Version = stored.Version
+	return nil
+}
+
+func (r *BoardMemoryRepo) Delete(ctx context.Context, id, version int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	board, ok := r.s.boards[id]
+	if !ok {
+		return repository.NotFound("board")
+	}
+	if board.Version != version {
+		return repository.Stale("board")
+	}
+	r.s.deleteBoard(id)
+	return nil
+}
+
+func (r *BoardMemoryRepo) ListByUser(ctx context.Context, userID int) ([]*models.Board, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	var boards []*models.Board
+	for _, board := range r.s.boards {
+		if board.UserID == userID {
+			boards = append(boards, copyBoard(board))
+		}
+	}
+
+	sort.Slice(boards, func(i, j int) bool { return boards[i].ID < boards[j].ID })
+	return boards, nil
+}
+
+// Участник доски - её владелец или пользователь, чьи задачи добавлены на доску
+func (r *BoardMemoryRepo) IsMember(ctx context.Context, boardID, userID int) (bool, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.s.isMember(boardID, userID), nil
+}
+
+func (r *BoardMemoryRepo) GetWIPLimits(ctx context.Context, boardID int) (map[models.TaskStatus]int, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	limits := make(map[models.TaskStatus]int, len(r.s.wipLimits[boardID]))
+	for status, limit := range r.s.wipLimits[boardID] {
+		limits[status] = limit
+	}
+	return limits, nil
+}
+
+// Заменяет все лимиты доски, статусы без лимита не ограничены
+func (r *BoardMemoryRepo) SetWIPLimits(ctx context.Context, boardID int, limits map[models.TaskStatus]int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.boards[boardID]; !ok && len(limits) > 0 {
+		return repository.NotFound("referenced board")
+	}
+
+	stored := make(map[models.TaskStatus]int, len(limits))
+	for status, limit := range limits {
+		stored[status] = limit
+	}
+	r.s.wipLimits[boardID] = stored
+	return nil
+}
+
+// Количество задач, сумма оценок и лимит для каждого статуса доски
+func (r *BoardMemoryRepo) Summary(ctx context.Context, boardID int) ([]*models.BoardColumnSummary, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	summary := make([]*models.BoardColumnSummary, 0, len(models.TaskStatuses))
+	for _, status := range models.TaskStatuses {
+		column := &models.BoardColumnSummary{
+			Status:   status,
+			WIPLimit: r.s.wipLimits[boardID][status],
+		}
+		for _, taskID := range r.s.boardTaskIDs(boardID) {
+			task := r.s.tasks[taskID]
+			if task.Status != status {
+				continue
+			}
+			column.Tasks++
+			if task.Estimate != nil {
+				column.Estimate += *task.Estimate
+			}
+		}
+		summary = append(summary, column)
+	}
+
+	return summary, nil
+}
+
+func copyBoard(board *models.Board) *models.Board {
+	c := *board
+	c.WIPLimits = nil
+	return &c
+}
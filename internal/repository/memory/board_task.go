@@ -0,0 +1,171 @@
+package memory_repo
+
+import (
+	"context"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type BoardTaskMemoryRepo struct {
+	s *Store
+}
+
+func NewBoardTaskMemoryRepo(s *Store) *BoardTaskMemoryRepo {
+	return &BoardTaskMemoryRepo{s: s}
+}
+
+// Повторное добавление задачи на доску ничего не меняет, новая задача не должна превысить WIP-лимит
+func (r *BoardTaskMemoryRepo) AddTask(ctx context.Context, boardID, taskID int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if err := r.checkRefs(boardID, taskID); err != nil {
+		return err
+	}
+	if r.s.onBoard(boardID, taskID) {
+		return nil
+	}
+	if err := r.checkLimit(boardID, taskID, r.s.tasks[taskID].Status); err != nil {
+		return err
+	}
+
+	r.s.boardTasks = append(r.s.boardTasks, boardTask{boardID: boardID, taskID: taskID})
+	return nil
+}
+
+func (r *BoardTaskMemoryRepo) RemoveTask(ctx context.Context, boardID, taskID int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	r.s.removeBoardTasks(func(bt boardTask) bool {
+		return bt.boardID != boardID || bt.taskID != taskID
+	})
+	return nil
+}
+
+func (r *BoardTaskMemoryRepo) GetTasks(ctx context.Context, boardID int) ([]int, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.s.boardTaskIDs(boardID), nil
+}
+
+// Задачи от новых к старым, как ORDER BY created_at DESC в SQL-репозиториях
+func (r *BoardTaskMemoryRepo) ListTasks(ctx context.Context, boardID int) ([]*models.Task, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	onBoard := make(map[int]bool)
+	for _, id := range r.s.boardTaskIDs(boardID) {
+		onBoard[id] = true
+	}
+	return (&TaskMemoryRepo{s: r.s}).list(func(task *models.Task) bool {
+		return onBoard[task.ID]
+	}), nil
+}
+
+func (r *BoardTaskMemoryRepo) GetBoards(ctx context.Context, taskID int) ([]int, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.s.taskBoardIDs(taskID), nil
+}
+
+/*
+Перенос выполняется целиком или не выполняется, если на целевой доске превышен WIP-лимит
+Задача, которой нет на исходной доске, не переносится (NotFound)
+*/
+func (r *BoardTaskMemoryRepo) MoveTask(ctx context.Context, fromBoardID, toBoardID, taskID int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if err := r.checkRefs(toBoardID, taskID); err != nil {
+		return err
+	}
+	if !r.s.onBoard(fromBoardID, taskID) {
+		return repository.NotFound("task on the board")
+	}
+	if fromBoardID != toBoardID && r.s.onBoard(toBoardID, taskID) {
+		return repository.Conflict("task is already on the board")
+	}
+
+	if err := r.checkLimit(toBoardID, taskID, r.s.tasks[taskID].Status); err != nil {
+		return err
+	}
+
+	r.s.removeBoardTasks(func(bt boardTask) bool {
+		return bt.boardID != fromBoardID || bt.taskID != taskID
+	})
+	r.s.boardTasks = append(r.s.boardTasks, boardTask{boardID: toBoardID, taskID: taskID})
+	return nil
+}
+
+// Проверяет WIP-лимиты всех досок задачи для её текущего статуса
+func (r *BoardTaskMemoryRepo) CheckLimits(ctx context.Context, taskID int) error {
+	if err := r.s.rlock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.RUnlock()
+
+	task, ok := r.s.tasks[taskID]
+	if !ok {
+		return nil
+	}
+	for _, boardID := range r.s.taskBoardIDs(taskID) {
+		if err := r.checkLimit(boardID, taskID, task.Status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ошибка, если с задачей taskID в статусе status колонка доски превысит WIP-лимит
+func (r *BoardTaskMemoryRepo) checkLimit(boardID, taskID int, status models.TaskStatus) error {
+	limit, ok := r.s.wipLimits[boardID][status]
+	if !ok {
+		return nil
+	}
+
+	count := 1
+	for _, id := range r.s.boardTaskIDs(boardID) {
+		if id != taskID && r.s.tasks[id].Status == status {
+			count++
+		}
+	}
+	if count > limit {
+		return &repository.WIPLimitError{BoardID: boardID, Status: status, Limit: limit}
+	}
+	return nil
+}
+
+func (r *BoardTaskMemoryRepo) Exists(ctx context.Context, boardID, taskID int) (bool, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.s.onBoard(boardID, taskID), nil
+}
+
+func (r *BoardTaskMemoryRepo) checkRefs(boardID, taskID int) error {
+	if _, ok := r.s.boards[boardID]; !ok {
+		return repository.NotFound("referenced board or task")
+	}
+	if _, ok := r.s.tasks[taskID]; !ok {
+		return repository.NotFound("referenced board or task")
+	}
+	return nil
+}
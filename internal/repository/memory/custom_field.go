@@ -0,0 +1,211 @@
+package memory_repo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type CustomFieldMemoryRepo struct {
+	s *Store
+}
+
+func NewCustomFieldMemoryRepo(s *Store) *CustomFieldMemoryRepo {
+	return &CustomFieldMemoryRepo{s: s}
+}
+
+func (r *CustomFieldMemoryRepo) Create(ctx context.Context, field *models.CustomField) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.boards[field.BoardID]; !ok {
+		return repository.NotFound("referenced custom field")
+	}
+	if r.nameTaken(field.BoardID, field.Name, 0) {
+		return repository.Conflict("custom field already exists")
+	}
+
+	field.ID = r.s.nextID()
+	field.CreatedAt = time.Now()
+	r.s.fields[field.ID] = copyField(field)
+	return nil
+}
+
+func (r *CustomFieldMemoryRepo) GetById(ctx context.Context, id int) (*models.CustomField, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	field, ok := r.s.fields[id]
+	if !ok {
+		return nil, repository.NotFound("custom field")
+	}
+	return copyField(field), nil
+}
+
+// Тип поля не меняется, чтобы не инвалидировать сохранённые значения
+func (r *CustomFieldMemoryRepo) Update(ctx context.Context, field *models.CustomField) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.fields[field.ID]
+	if !ok {
+		return repository.NotFound("custom field")
+	}
+	if r.nameTaken(stored.BoardID, field.Name, field.ID) {
+		return repository.Conflict("custom field already exists")
+	}
+
+	stored.Name = field.Name
+	stored.Options = copyField(field).Options
+	stored.Required = field.Required
+	return nil
+}
+
+func (r *CustomFieldMemoryRepo) Delete(ctx context.Context, id int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.fields[id]; !ok {
+		return repository.NotFound("custom field")
+	}
+	r.s.deleteField(id)
+	return nil
+}
+
+func (r *CustomFieldMemoryRepo) ListByBoard(ctx context.Context, boardID int) ([]*models.CustomField, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.list(func(field *models.CustomField) bool { return field.BoardID == boardID }), nil
+}
+
+// Поля всех досок, на которых находится задача
+func (r *CustomFieldMemoryRepo) ListForTask(ctx context.Context, taskID int) ([]*models.CustomField, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.list(func(field *models.CustomField) bool { return r.s.onBoard(field.BoardID, taskID) }), nil
+}
+
+// Сохраняет значения полей задачи, значение nil удаляет сохранённое значение
+func (r *CustomFieldMemoryRepo) SetValues(ctx context.Context, taskID int, values map[int]json.RawMessage) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	for fieldID, value := range values {
+		if value == nil {
+			continue
+		}
+		if _, ok := r.s.tasks[taskID]; !ok {
+			return repository.NotFound("referenced task")
+		}
+		if _, ok := r.s.fields[fieldID]; !ok {
+			return repository.NotFound("referenced custom field")
+		}
+	}
+
+	stored := r.s.fieldValues[taskID]
+	if stored == nil {
+		stored = make(map[int]json.RawMessage)
+		r.s.fieldValues[taskID] = stored
+	}
+
+	for fieldID, value := range values {
+		if value == nil {
+			delete(stored, fieldID)
+			continue
+		}
+		stored[fieldID] = append(json.RawMessage(nil), value...)
+	}
+	return nil
+}
+
+func (r *CustomFieldMemoryRepo) GetValues(ctx context.Context, taskID int) ([]models.CustomFieldValue, error) {
+	values, err := r.GetValuesForTasks(ctx, []int{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return values[taskID], nil
+}
+
+// Значения полей сразу для нескольких задач, ключ - идентификатор задачи
+func (r *CustomFieldMemoryRepo) GetValuesForTasks(ctx context.Context, taskIDs []int) (map[int][]models.CustomFieldValue, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	values := make(map[int][]models.CustomFieldValue)
+	for _, taskID := range taskIDs {
+		stored := r.s.fieldValues[taskID]
+		if len(stored) == 0 {
+			continue
+		}
+
+		fields := r.list(func(field *models.CustomField) bool {
+			_, ok := stored[field.ID]
+			return ok
+		})
+		for _, field := range fields {
+			values[taskID] = append(values[taskID], models.CustomFieldValue{
+				FieldID: field.ID,
+				Name:    field.Name,
+				Type:    field.Type,
+				Value:   append(json.RawMessage(nil), stored[field.ID]...),
+			})
+		}
+	}
+
+	return values, nil
+}
+
+// Поля упорядочены по доске и порядку создания
+func (r *CustomFieldMemoryRepo) list(match func(field *models.CustomField) bool) []*models.CustomField {
+	var fields []*models.CustomField
+	for _, field := range r.s.fields {
+		if match(field) {
+			fields = append(fields, copyField(field))
+		}
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].BoardID != fields[j].BoardID {
+			return fields[i].BoardID < fields[j].BoardID
+		}
+		return fields[i].ID < fields[j].ID
+	})
+	return fields
+}
+
+func (r *CustomFieldMemoryRepo) nameTaken(boardID int, name string, exceptID int) bool {
+	for _, field := range r.s.fields {
+		if field.BoardID == boardID && field.Name == name && field.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func copyField(field *models.CustomField) *models.CustomField {
+	c := *field
+	c.Options = append([]string{}, field.Options...)
+	return &c
+}
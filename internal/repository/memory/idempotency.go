@@ -0,0 +1,109 @@
+package memory_repo
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Ключи идемпотентности уникальны в пределах пользователя, как первичный ключ таблицы
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+type IdempotencyMemoryRepo struct {
+	s *Store
+}
+
+func NewIdempotencyMemoryRepo(s *Store) *IdempotencyMemoryRepo {
+	return &IdempotencyMemoryRepo{s: s}
+}
+
+func (r *IdempotencyMemoryRepo) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[record.UserID]; !ok {
+		return nil, repository.NotFound("referenced user")
+	}
+
+	key := idempotencyKey{userID: record.UserID, key: record.Key}
+	now := time.Now()
+	if existing, ok := r.s.idempotencyKeys[key]; ok && existing.ExpiresAt.After(now) {
+		return copyIdempotencyRecord(existing), nil
+	}
+
+	record.CreatedAt = now
+	r.s.idempotencyKeys[key] = &models.IdempotencyRecord{
+		UserID:      record.UserID,
+		Key:         record.Key,
+		Fingerprint: record.Fingerprint,
+		CreatedAt:   record.CreatedAt,
+		ExpiresAt:   record.ExpiresAt,
+	}
+	return nil, nil
+}
+
+func (r *IdempotencyMemoryRepo) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.idempotencyKeys[idempotencyKey{userID: record.UserID, key: record.Key}]
+	if !ok || stored.Completed {
+		return repository.NotFound("idempotency key")
+	}
+
+	stored.Completed = true
+	stored.StatusCode = record.StatusCode
+	stored.Header = maps.Clone(record.Header)
+	stored.Body = slices.Clone(record.Body)
+	stored.ExpiresAt = record.ExpiresAt
+	record.Completed = true
+	return nil
+}
+
+func (r *IdempotencyMemoryRepo) Release(ctx context.Context, userID int, key string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	k := idempotencyKey{userID: userID, key: key}
+	if stored, ok := r.s.idempotencyKeys[k]; ok && !stored.Completed {
+		delete(r.s.idempotencyKeys, k)
+	}
+	return nil
+}
+
+func (r *IdempotencyMemoryRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.s.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for key, record := range r.s.idempotencyKeys {
+		if !record.ExpiresAt.After(now) {
+			delete(r.s.idempotencyKeys, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func copyIdempotencyRecord(record *models.IdempotencyRecord) *models.IdempotencyRecord {
+	c := *record
+	c.Header = maps.Clone(record.Header)
+	c.Body = slices.Clone(record.Body)
+	return &c
+}
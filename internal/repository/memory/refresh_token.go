@@ -0,0 +1,114 @@
+package memory_repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type RefreshTokenMemoryRepo struct {
+	s *Store
+}
+
+func NewRefreshTokenMemoryRepo(s *Store) *RefreshTokenMemoryRepo {
+	return &RefreshTokenMemoryRepo{s: s}
+}
+
+// Токены хранятся так же, как в Postgres - по SHA-256 хешу
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (r *RefreshTokenMemoryRepo) Create(ctx context.Context, token *models.RefreshToken) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[token.UserID]; !ok {
+		return repository.NotFound("referenced user")
+	}
+
+	hash := hashToken(token.TokenHash)
+	if _, ok := r.s.refreshTokens[hash]; ok {
+		return repository.Conflict("refresh token already exists")
+	}
+
+	r.s.refreshTokens[hash] = &models.RefreshToken{
+		TokenHash: hash,
+		UserID:    token.UserID,
+		ExpiresAt: token.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	return nil
+}
+
+func (r *RefreshTokenMemoryRepo) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	token, ok := r.s.refreshTokens[hashToken(tokenHash)]
+	if !ok || !token.ExpiresAt.After(time.Now()) {
+		return nil, repository.NotFound("refresh token")
+	}
+	found := *token
+	return &found, nil
+}
+
+func (r *RefreshTokenMemoryRepo) DeleteByHash(ctx context.Context, tokenHash string) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	delete(r.s.refreshTokens, hashToken(tokenHash))
+	return nil
+}
+
+func (r *RefreshTokenMemoryRepo) DeleteAllForUser(ctx context.Context, userID int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	for hash, token := range r.s.refreshTokens {
+		if token.UserID == userID {
+			delete(r.s.refreshTokens, hash)
+		}
+	}
+	return nil
+}
+
+func (r *RefreshTokenMemoryRepo) Exists(ctx context.Context, tokenHash string) (bool, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return false, err
+	}
+	defer r.s.mu.RUnlock()
+
+	token, ok := r.s.refreshTokens[hashToken(tokenHash)]
+	return ok && token.ExpiresAt.After(time.Now()), nil
+}
+
+func (r *RefreshTokenMemoryRepo) RevokeExpires(ctx context.Context) (int64, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.s.mu.Unlock()
+
+	var revoked int64
+	now := time.Now()
+	for hash, token := range r.s.refreshTokens {
+		if token.ExpiresAt.Before(now) {
+			delete(r.s.refreshTokens, hash)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
@@ -0,0 +1,151 @@
+package memory_repo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type SavedViewMemoryRepo struct {
+	s *Store
+}
+
+func NewSavedViewMemoryRepo(s *Store) *SavedViewMemoryRepo {
+	return &SavedViewMemoryRepo{s: s}
+}
+
+func (r *SavedViewMemoryRepo) Create(ctx context.Context, view *models.SavedView) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if err := r.checkRefs(view); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	view.ID = r.s.nextID()
+	view.CreatedAt = now
+	view.UpdatedAt = now
+	r.s.savedViews[view.ID] = copyView(view)
+	return nil
+}
+
+func (r *SavedViewMemoryRepo) GetById(ctx context.Context, id int) (*models.SavedView, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	view, ok := r.s.savedViews[id]
+	if !ok {
+		return nil, repository.NotFound("saved view")
+	}
+	return copyView(view), nil
+}
+
+func (r *SavedViewMemoryRepo) Update(ctx context.Context, view *models.SavedView) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.savedViews[view.ID]
+	if !ok {
+		return repository.NotFound("saved view")
+	}
+	if err := r.checkRefs(view); err != nil {
+		return err
+	}
+
+	view.UpdatedAt = time.Now()
+	updated := copyView(view)
+	updated.UserID = stored.UserID
+	updated.CreatedAt = stored.CreatedAt
+	r.s.savedViews[view.ID] = updated
+	return nil
+}
+
+func (r *SavedViewMemoryRepo) Delete(ctx context.Context, id int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.savedViews[id]; !ok {
+		return repository.NotFound("saved view")
+	}
+	delete(r.s.savedViews, id)
+	return nil
+}
+
+// Собственные представления пользователя и расшаренные на доски, участником которых он является
+func (r *SavedViewMemoryRepo) ListAccessible(ctx context.Context, userID int) ([]*models.SavedView, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.list(func(view *models.SavedView) bool {
+		return view.UserID == userID ||
+			(view.Shared && view.Filter.BoardID != 0 && r.s.isMember(view.Filter.BoardID, userID))
+	}), nil
+}
+
+func (r *SavedViewMemoryRepo) ListPinned(ctx context.Context, userID int) ([]*models.SavedView, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.list(func(view *models.SavedView) bool {
+		return view.UserID == userID && view.Pinned
+	}), nil
+}
+
+func (r *SavedViewMemoryRepo) list(match func(view *models.SavedView) bool) []*models.SavedView {
+	var views []*models.SavedView
+	for _, view := range r.s.savedViews {
+		if match(view) {
+			views = append(views, copyView(view))
+		}
+	}
+
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].Name != views[j].Name {
+			return views[i].Name < views[j].Name
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views
+}
+
+func (r *SavedViewMemoryRepo) checkRefs(view *models.SavedView) error {
+	if _, ok := r.s.users[view.UserID]; !ok && view.ID == 0 {
+		return repository.NotFound("referenced user")
+	}
+	if view.Filter.BoardID != 0 {
+		if _, ok := r.s.boards[view.Filter.BoardID]; !ok {
+			return repository.NotFound("referenced board")
+		}
+	}
+	return nil
+}
+
+func copyView(view *models.SavedView) *models.SavedView {
+	c := *view
+	if view.Filter.Statuses != nil {
+		c.Filter.Statuses = append([]models.TaskStatus(nil), view.Filter.Statuses...)
+	}
+	if view.Filter.Fields != nil {
+		c.Filter.Fields = make(map[int]string, len(view.Filter.Fields))
+		for fieldID, value := range view.Filter.Fields {
+			c.Filter.Fields[fieldID] = value
+		}
+	}
+	return &c
+}
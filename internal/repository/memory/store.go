@@ -0,0 +1,261 @@
+/*
+Реализация всех репозиториев в памяти процесса
+
+Используется в тестах и для запуска без Postgres. Репозитории работают поверх общего Store,
+который повторяет связи таблиц: внешние ключи, каскадные удаления и ограничения уникальности.
+Наружу отдаются только копии записей, изменения через возвращённые указатели в хранилище не попадают
+*/
+package memory_repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Связь задачи с доской, порядок добавления сохраняется
+type boardTask struct {
+	boardID int
+	taskID  int
+}
+
+type Store struct {
+	mu     sync.RWMutex
+	lastID int
+
+	users         map[int]*models.User
+	boards        map[int]*models.Board
+	tasks         map[int]*models.Task
+	boardTasks    []boardTask
+	wipLimits     map[int]map[models.TaskStatus]int
+	refreshTokens map[string]*models.RefreshToken
+	savedViews    map[int]*models.SavedView
+	series        map[int]*models.TaskSeries
+	timeEntries   map[int]*models.TimeEntry
+	fields        map[int]*models.CustomField
+	// Значения пользовательских полей: задача -> поле -> значение
+	fieldValues     map[int]map[int]json.RawMessage
+	idempotencyKeys map[idempotencyKey]*models.IdempotencyRecord
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:         make(map[int]*models.User),
+		boards:        make(map[int]*models.Board),
+		tasks:         make(map[int]*models.Task),
+		wipLimits:     make(map[int]map[models.TaskStatus]int),
+		refreshTokens: make(map[string]*models.RefreshToken),
+		savedViews:    make(map[int]*models.SavedView),
+		series:        make(map[int]*models.TaskSeries),
+		timeEntries:   make(map[int]*models.TimeEntry),
+		fields:        make(map[int]*models.CustomField),
+		fieldValues:   make(map[int]map[int]json.RawMessage),
+
+		idempotencyKeys: make(map[idempotencyKey]*models.IdempotencyRecord),
+	}
+}
+
+// Идентификаторы общие для всех сущностей, так в тестах проще заметить перепутанные ID
+func (s *Store) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+// Учитывает отмену контекста так же, как запросы к базе
+func (s *Store) lock(ctx context.Context) error {
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	return nil
+}
+
+func (s *Store) rlock(ctx context.Context) error {
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	return nil
+}
+
+func contextError(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &repository.TimeoutError{Err: err}
+	case err != nil:
+		return &repository.CanceledError{Err: err}
+	}
+	return nil
+}
+
+func (s *Store) onBoard(boardID, taskID int) bool {
+	for _, bt := range s.boardTasks {
+		if bt.boardID == boardID && bt.taskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) boardTaskIDs(boardID int) []int {
+	var ids []int
+	for _, bt := range s.boardTasks {
+		if bt.boardID == boardID {
+			ids = append(ids, bt.taskID)
+		}
+	}
+	return ids
+}
+
+func (s *Store) taskBoardIDs(taskID int) []int {
+	var ids []int
+	for _, bt := range s.boardTasks {
+		if bt.taskID == taskID {
+			ids = append(ids, bt.boardID)
+		}
+	}
+	return ids
+}
+
+func (s *Store) removeBoardTasks(keep func(bt boardTask) bool) {
+	kept := s.boardTasks[:0]
+	for _, bt := range s.boardTasks {
+		if keep(bt) {
+			kept = append(kept, bt)
+		}
+	}
+	s.boardTasks = kept
+}
+
+func (s *Store) isMember(boardID, userID int) bool {
+	if board, ok := s.boards[boardID]; ok && board.UserID == userID {
+		return true
+	}
+	for _, taskID := range s.boardTaskIDs(boardID) {
+		if s.tasks[taskID].UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Каскадные удаления повторяют ON DELETE из миграций
+
+func (s *Store) deleteUser(id int) {
+	delete(s.users, id)
+
+	for boardID, board := range s.boards {
+		if board.UserID == id {
+			s.deleteBoard(boardID)
+		}
+	}
+	for taskID, task := range s.tasks {
+		if task.UserID == id {
+			s.deleteTask(taskID)
+		}
+	}
+	for hash, token := range s.refreshTokens {
+		if token.UserID == id {
+			delete(s.refreshTokens, hash)
+		}
+	}
+	for key := range s.idempotencyKeys {
+		if key.userID == id {
+			delete(s.idempotencyKeys, key)
+		}
+	}
+	for viewID, view := range s.savedViews {
+		if view.UserID == id {
+			delete(s.savedViews, viewID)
+		}
+	}
+	for seriesID, series := range s.series {
+		if series.UserID == id {
+			s.deleteSeries(seriesID)
+		}
+	}
+	for entryID, entry := range s.timeEntries {
+		if entry.UserID == id {
+			delete(s.timeEntries, entryID)
+		}
+	}
+}
+
+func (s *Store) deleteBoard(id int) {
+	delete(s.boards, id)
+	delete(s.wipLimits, id)
+	s.removeBoardTasks(func(bt boardTask) bool { return bt.boardID != id })
+
+	for viewID, view := range s.savedViews {
+		if view.Filter.BoardID == id {
+			delete(s.savedViews, viewID)
+		}
+	}
+	for fieldID, field := range s.fields {
+		if field.BoardID == id {
+			s.deleteField(fieldID)
+		}
+	}
+}
+
+func (s *Store) deleteTask(id int) {
+	delete(s.tasks, id)
+	delete(s.fieldValues, id)
+	s.removeBoardTasks(func(bt boardTask) bool { return bt.taskID != id })
+
+	for entryID, entry := range s.timeEntries {
+		if entry.TaskID == id {
+			delete(s.timeEntries, entryID)
+		}
+	}
+}
+
+// Удаление серии отвязывает от неё экземпляры (ON DELETE SET NULL)
+func (s *Store) deleteSeries(id int) {
+	delete(s.series, id)
+	for _, task := range s.tasks {
+		if task.SeriesID != nil && *task.SeriesID == id {
+			task.SeriesID = nil
+		}
+	}
+}
+
+func (s *Store) deleteField(id int) {
+	delete(s.fields, id)
+	for _, values := range s.fieldValues {
+		delete(values, id)
+	}
+}
+
+func copyInt(v *int) *int {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func copyFloat(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+var (
+	_ repository.UserRepository         = (*UserMemoryRepo)(nil)
+	_ repository.BoardRepository        = (*BoardMemoryRepo)(nil)
+	_ repository.TaskRepository         = (*TaskMemoryRepo)(nil)
+	_ repository.BoardTaskRepository    = (*BoardTaskMemoryRepo)(nil)
+	_ repository.RefreshTokenRepository = (*RefreshTokenMemoryRepo)(nil)
+	_ repository.SavedViewRepository    = (*SavedViewMemoryRepo)(nil)
+	_ repository.TaskSeriesRepository   = (*TaskSeriesMemoryRepo)(nil)
+	_ repository.TimeEntryRepository    = (*TimeEntryMemoryRepo)(nil)
+	_ repository.CustomFieldRepository  = (*CustomFieldMemoryRepo)(nil)
+	_ repository.UnitOfWork             = (*UnitOfWork)(nil)
+)
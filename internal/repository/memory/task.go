@@ -0,0 +1,227 @@
+package memory_repo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TaskMemoryRepo struct {
+	s *Store
+}
+
+func NewTaskMemoryRepo(s *Store) *TaskMemoryRepo {
+	return &TaskMemoryRepo{s: s}
+}
+
+func (r *TaskMemoryRepo) Create(ctx context.Context, task *models.Task) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[task.UserID]; !ok {
+		return repository.NotFound("referenced user")
+	}
+	if task.SeriesID != nil {
+		if _, ok := r.s.series[*task.SeriesID]; !ok {
+			return repository.NotFound("referenced task series")
+		}
+	}
+
+	now := time.Now()
+	task.ID = r.s.nextID()
+	task.Version = 1
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	r.s.tasks[task.ID] = copyTask(task)
+	return nil
+}
+
+func (r *TaskMemoryRepo) GetById(ctx context.Context, id int) (*models.Task, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	task, ok := r.s.tasks[id]
+	if !ok {
+		return nil, repository.NotFound("task")
+	}
+	return copyTask(task), nil
+}
+
+func (r *TaskMemoryRepo) GetByIDs(ctx context.Context, ids []int) ([]*models.Task, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	var tasks []*models.Task
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if task, ok := r.s.tasks[id]; ok && !seen[id] {
+			seen[id] = true
+			tasks = append(tasks, copyTask(task))
+		}
+	}
+	return tasks, nil
+}
+
+func (r *TaskMemoryRepo) Update(ctx context.Context, task *models.Task) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.tasks[task.ID]
+	if !ok {
+		return repository.NotFound("task")
+	}
+	if stored.Version != task.Version {
+		return repository.Stale("task")
+	}
+
+	stored.Title = task.Title
+	stored.Description = task.Description
+	stored.Status = task.Status
+	stored.Estimate = copyFloat(task.Estimate)
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	task.Version = stored.Version
+	return nil
+}
+
+func (r *TaskMemoryRepo) Delete(ctx context.Context, id, version int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	task, ok := r.s.tasks[id]
+	if !ok {
+		return repository.NotFound("task")
+	}
+	if task.Version != version {
+		return repository.Stale("task")
+	}
+	r.s.deleteTask(id)
+	return nil
+}
+
+func (r *TaskMemoryRepo) ListByUser(ctx context.Context, userID int) ([]*models.Task, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	return r.list(func(task *models.Task) bool { return task.UserID == userID }), nil
+}
+
+// Выборка задач по фильтру. Без доски в фильтре возвращаются только задачи пользователя
+func (r *TaskMemoryRepo) ListByFilter(ctx context.Context, userID int, filter models.TaskFilter) ([]*models.Task, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	search := strings.ToLower(strings.TrimSpace(filter.Search))
+
+	return r.list(func(task *models.Task) bool {
+		if filter.BoardID != 0 && !r.s.onBoard(filter.BoardID, task.ID) {
+			return false
+		}
+
+		if (filter.BoardID == 0 || filter.OnlyMine) && task.UserID != userID {
+			return false
+		}
+
+		if len(filter.Statuses) > 0 && !hasStatus(filter.Statuses, task.Status) {
+			return false
+		}
+
+		for fieldID, value := range filter.Fields {
+			if !fieldValueMatches(r.s.fieldValues[task.ID][fieldID], value) {
+				return false
+			}
+		}
+
+		if search != "" &&
+			!strings.Contains(strings.ToLower(task.Title), search) &&
+			!strings.Contains(strings.ToLower(task.Description), search) {
+			return false
+		}
+
+		return true
+	}), nil
+}
+
+// Задачи от новых к старым, как ORDER BY created_at DESC
+func (r *TaskMemoryRepo) list(match func(task *models.Task) bool) []*models.Task {
+	var tasks []*models.Task
+	for _, task := range r.s.tasks {
+		if match(task) {
+			tasks = append(tasks, copyTask(task))
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID > tasks[j].ID
+	})
+	return tasks
+}
+
+func hasStatus(statuses []models.TaskStatus, status models.TaskStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Сравнивает сохранённое значение поля с текстом из фильтра
+Для множественного выбора значение ищется среди элементов списка
+*/
+func fieldValueMatches(raw json.RawMessage, value string) bool {
+	if raw == nil {
+		return false
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return false
+	}
+
+	switch v := decoded.(type) {
+	case nil:
+		return false
+	case string:
+		return v == value
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == value {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.TrimSpace(string(raw)) == value
+}
+
+func copyTask(task *models.Task) *models.Task {
+	c := *task
+	c.SeriesID = copyInt(task.SeriesID)
+	c.Estimate = copyFloat(task.Estimate)
+	c.CustomFields = nil
+	return &c
+}
@@ -0,0 +1,193 @@
+package memory_repo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TaskSeriesMemoryRepo struct {
+	s *Store
+}
+
+func NewTaskSeriesMemoryRepo(s *Store) *TaskSeriesMemoryRepo {
+	return &TaskSeriesMemoryRepo{s: s}
+}
+
+func (r *TaskSeriesMemoryRepo) Create(ctx context.Context, series *models.TaskSeries) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[series.UserID]; !ok {
+		return repository.NotFound("referenced user")
+	}
+
+	if series.Rule.Interval < 1 {
+		series.Rule.Interval = 1
+	}
+
+	now := time.Now()
+	series.ID = r.s.nextID()
+	series.CreatedAt = now
+	series.UpdatedAt = now
+	series.LastTaskID = 0
+	r.s.series[series.ID] = copySeries(series)
+	return nil
+}
+
+func (r *TaskSeriesMemoryRepo) GetById(ctx context.Context, id int) (*models.TaskSeries, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	series, ok := r.s.series[id]
+	if !ok {
+		return nil, repository.NotFound("task series")
+	}
+	return r.withLastTask(series), nil
+}
+
+// Обновляет шаблон серии и переносит изменения на все незавершённые экземпляры
+func (r *TaskSeriesMemoryRepo) Update(ctx context.Context, series *models.TaskSeries) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if series.Rule.Interval < 1 {
+		series.Rule.Interval = 1
+	}
+
+	now := time.Now()
+	series.UpdatedAt = now
+
+	stored, ok := r.s.series[series.ID]
+	if !ok {
+		return nil
+	}
+
+	stored.Title = series.Title
+	stored.Description = series.Description
+	stored.Rule = copySeries(series).Rule
+	stored.NextRunAt = series.NextRunAt
+	stored.UpdatedAt = now
+
+	for _, task := range r.s.tasks {
+		if task.SeriesID != nil && *task.SeriesID == series.ID && task.Status != models.StatusDone {
+			task.Title = series.Title
+			task.Description = series.Description
+			task.UpdatedAt = now
+			task.Version++
+		}
+	}
+
+	return nil
+}
+
+// Удаление серии прекращает повторение, созданные экземпляры остаются
+func (r *TaskSeriesMemoryRepo) Delete(ctx context.Context, id int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.series[id]; !ok {
+		return repository.NotFound("task series")
+	}
+	r.s.deleteSeries(id)
+	return nil
+}
+
+func (r *TaskSeriesMemoryRepo) AttachTask(ctx context.Context, seriesID, taskID int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.series[seriesID]; !ok {
+		return repository.NotFound("referenced task series")
+	}
+	if task, ok := r.s.tasks[taskID]; ok {
+		task.SeriesID = &seriesID
+	}
+	return nil
+}
+
+/*
+Серии, для которых пора создать экземпляр: наступило время по расписанию
+или все созданные экземпляры завершены. Исчерпанные правила не возвращаются
+*/
+func (r *TaskSeriesMemoryRepo) ListDue(ctx context.Context, now time.Time) ([]*models.TaskSeries, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	var list []*models.TaskSeries
+	for _, series := range r.s.series {
+		if series.Rule.Until != nil && series.Rule.Until.Before(now) {
+			continue
+		}
+		if series.NextRunAt.After(now) && r.hasOpenTask(series.ID) {
+			continue
+		}
+		list = append(list, r.withLastTask(series))
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].NextRunAt.Equal(list[j].NextRunAt) {
+			return list[i].NextRunAt.Before(list[j].NextRunAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (r *TaskSeriesMemoryRepo) SetNextRun(ctx context.Context, id int, prev, next time.Time) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	series, ok := r.s.series[id]
+	if !ok || !series.NextRunAt.Equal(prev) {
+		return repository.Stale("task series")
+	}
+	series.NextRunAt = next
+	return nil
+}
+
+func (r *TaskSeriesMemoryRepo) hasOpenTask(seriesID int) bool {
+	for _, task := range r.s.tasks {
+		if task.SeriesID != nil && *task.SeriesID == seriesID && task.Status != models.StatusDone {
+			return true
+		}
+	}
+	return false
+}
+
+// Копия серии с последним созданным экземпляром
+func (r *TaskSeriesMemoryRepo) withLastTask(series *models.TaskSeries) *models.TaskSeries {
+	c := copySeries(series)
+	for _, task := range r.s.tasks {
+		if task.SeriesID != nil && *task.SeriesID == series.ID && task.ID > c.LastTaskID {
+			c.LastTaskID = task.ID
+		}
+	}
+	return c
+}
+
+func copySeries(series *models.TaskSeries) *models.TaskSeries {
+	c := *series
+	if series.Rule.Until != nil {
+		until := *series.Rule.Until
+		c.Rule.Until = &until
+	}
+	return &c
+}
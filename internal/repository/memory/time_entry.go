@@ -0,0 +1,249 @@
+package memory_repo
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TimeEntryMemoryRepo struct {
+	s *Store
+}
+
+func NewTimeEntryMemoryRepo(s *Store) *TimeEntryMemoryRepo {
+	return &TimeEntryMemoryRepo{s: s}
+}
+
+func (r *TimeEntryMemoryRepo) StartTimer(ctx context.Context, userID, taskID int) (*models.TimeEntry, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	if err := r.checkRefs(userID, taskID); err != nil {
+		return nil, err
+	}
+	if r.running(userID) != nil {
+		return nil, repository.Conflict("timer already running")
+	}
+
+	now := time.Now()
+	entry := &models.TimeEntry{
+		ID:        r.s.nextID(),
+		UserID:    userID,
+		TaskID:    taskID,
+		StartedAt: now,
+		CreatedAt: now,
+	}
+	r.s.timeEntries[entry.ID] = entry
+	return copyEntry(entry), nil
+}
+
+func (r *TimeEntryMemoryRepo) StopTimer(ctx context.Context, userID int) (*models.TimeEntry, error) {
+	if err := r.s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.Unlock()
+
+	entry := r.running(userID)
+	if entry == nil {
+		return nil, repository.NotFound("running timer")
+	}
+
+	now := time.Now()
+	entry.EndedAt = &now
+	return copyEntry(entry), nil
+}
+
+func (r *TimeEntryMemoryRepo) GetRunning(ctx context.Context, userID int) (*models.TimeEntry, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	entry := r.running(userID)
+	if entry == nil {
+		return nil, repository.NotFound("running timer")
+	}
+	return copyEntry(entry), nil
+}
+
+func (r *TimeEntryMemoryRepo) CreateManual(ctx context.Context, entry *models.TimeEntry) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if err := r.checkRefs(entry.UserID, entry.TaskID); err != nil {
+		return err
+	}
+
+	entry.ID = r.s.nextID()
+	entry.Manual = true
+	entry.CreatedAt = time.Now()
+	stored := copyEntry(entry)
+	r.s.timeEntries[entry.ID] = stored
+	entry.Seconds = stored.Seconds
+	return nil
+}
+
+func (r *TimeEntryMemoryRepo) GetById(ctx context.Context, id int) (*models.TimeEntry, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	entry, ok := r.s.timeEntries[id]
+	if !ok {
+		return nil, repository.NotFound("time entry")
+	}
+	return copyEntry(entry), nil
+}
+
+func (r *TimeEntryMemoryRepo) Delete(ctx context.Context, id int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.timeEntries[id]; !ok {
+		return repository.NotFound("time entry")
+	}
+	delete(r.s.timeEntries, id)
+	return nil
+}
+
+func (r *TimeEntryMemoryRepo) ListByTask(ctx context.Context, taskID int) ([]*models.TimeEntry, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	var entries []*models.TimeEntry
+	for _, entry := range r.s.timeEntries {
+		if entry.TaskID == taskID {
+			entries = append(entries, copyEntry(entry))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].StartedAt.Equal(entries[j].StartedAt) {
+			return entries[i].StartedAt.After(entries[j].StartedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	return entries, nil
+}
+
+func (r *TimeEntryMemoryRepo) TotalByTask(ctx context.Context, taskID int) (int64, error) {
+	return r.total(ctx, func(entry *models.TimeEntry) bool { return entry.TaskID == taskID })
+}
+
+func (r *TimeEntryMemoryRepo) TotalByBoard(ctx context.Context, boardID int) (int64, error) {
+	return r.total(ctx, func(entry *models.TimeEntry) bool { return r.s.onBoard(boardID, entry.TaskID) })
+}
+
+func (r *TimeEntryMemoryRepo) TotalByUser(ctx context.Context, userID int) (int64, error) {
+	return r.total(ctx, func(entry *models.TimeEntry) bool { return entry.UserID == userID })
+}
+
+func (r *TimeEntryMemoryRepo) total(ctx context.Context, match func(entry *models.TimeEntry) bool) (int64, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer r.s.mu.RUnlock()
+
+	var seconds int64
+	for _, entry := range r.s.timeEntries {
+		if match(entry) {
+			seconds += entrySeconds(entry)
+		}
+	}
+	return seconds, nil
+}
+
+// Время пользователя за период [from, to), сгруппированное по дням и задачам
+func (r *TimeEntryMemoryRepo) Report(ctx context.Context, userID int, from, to time.Time) ([]*models.TimeReportRow, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	type key struct {
+		day    time.Time
+		taskID int
+	}
+
+	rows := make(map[key]*models.TimeReportRow)
+	for _, entry := range r.s.timeEntries {
+		if entry.UserID != userID || entry.StartedAt.Before(from) || !entry.StartedAt.Before(to) {
+			continue
+		}
+
+		started := entry.StartedAt
+		day := time.Date(started.Year(), started.Month(), started.Day(), 0, 0, 0, 0, started.Location())
+		k := key{day: day, taskID: entry.TaskID}
+
+		row, ok := rows[k]
+		if !ok {
+			row = &models.TimeReportRow{Day: day, TaskID: entry.TaskID, TaskTitle: r.s.tasks[entry.TaskID].Title}
+			rows[k] = row
+		}
+		row.Seconds += entrySeconds(entry)
+	}
+
+	report := make([]*models.TimeReportRow, 0, len(rows))
+	for _, row := range rows {
+		report = append(report, row)
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		if !report[i].Day.Equal(report[j].Day) {
+			return report[i].Day.Before(report[j].Day)
+		}
+		return report[i].TaskID < report[j].TaskID
+	})
+	return report, nil
+}
+
+func (r *TimeEntryMemoryRepo) running(userID int) *models.TimeEntry {
+	for _, entry := range r.s.timeEntries {
+		if entry.UserID == userID && entry.EndedAt == nil {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (r *TimeEntryMemoryRepo) checkRefs(userID, taskID int) error {
+	if _, ok := r.s.users[userID]; !ok {
+		return repository.NotFound("referenced user")
+	}
+	if _, ok := r.s.tasks[taskID]; !ok {
+		return repository.NotFound("referenced task")
+	}
+	return nil
+}
+
+// Длительность запущенного таймера считается до текущего момента
+func entrySeconds(entry *models.TimeEntry) int64 {
+	end := time.Now()
+	if entry.EndedAt != nil {
+		end = *entry.EndedAt
+	}
+	return int64(math.Round(end.Sub(entry.StartedAt).Seconds()))
+}
+
+func copyEntry(entry *models.TimeEntry) *models.TimeEntry {
+	c := *entry
+	if entry.EndedAt != nil {
+		ended := *entry.EndedAt
+		c.EndedAt = &ended
+	}
+	c.Seconds = entrySeconds(entry)
+	return &c
+}
@@ -0,0 +1,140 @@
+package memory_repo
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+/*
+Единица работы над Store
+Перед выполнением сохраняется снимок хранилища, при ошибке или панике он восстанавливается.
+Единицы работы выполняются по очереди, но вызовы репозиториев вне них не изолированы
+от незавершённой единицы работы: для тестов и локального запуска этого достаточно
+*/
+type UnitOfWork struct {
+	s  *Store
+	mu sync.Mutex
+}
+
+func NewUnitOfWork(s *Store) *UnitOfWork {
+	return &UnitOfWork{s: s}
+}
+
+type uowKey struct{}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(uowKey{}) == u {
+		return fn(ctx)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.s.rlock(ctx); err != nil {
+		return err
+	}
+	snapshot := u.s.snapshot()
+	u.s.mu.RUnlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			u.s.restore(snapshot)
+			panic(p)
+		}
+	}()
+
+	ctx, committed := repository.TrackCommit(ctx)
+	if err = fn(context.WithValue(ctx, uowKey{}, u)); err != nil {
+		u.s.restore(snapshot)
+		return err
+	}
+	committed()
+	return nil
+}
+
+func (u *UnitOfWork) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(uowKey{}) != u {
+		return u.Do(ctx, fn)
+	}
+
+	if err := u.s.rlock(ctx); err != nil {
+		return err
+	}
+	snapshot := u.s.snapshot()
+	u.s.mu.RUnlock()
+
+	err := fn(ctx)
+	if err != nil {
+		u.s.restore(snapshot)
+	}
+	return err
+}
+
+// Копия всех записей хранилища, вызывается под блокировкой
+func (s *Store) snapshot() *Store {
+	c := &Store{
+		users:         cloneRecords(s.users, copyUser),
+		boards:        cloneRecords(s.boards, copyBoard),
+		tasks:         cloneRecords(s.tasks, copyTask),
+		boardTasks:    slices.Clone(s.boardTasks),
+		wipLimits:     make(map[int]map[models.TaskStatus]int, len(s.wipLimits)),
+		refreshTokens: cloneRecords(s.refreshTokens, copyToken),
+		savedViews:    cloneRecords(s.savedViews, copyView),
+		series:        cloneRecords(s.series, copySeries),
+		timeEntries:   cloneRecords(s.timeEntries, copyEntry),
+		fields:        cloneRecords(s.fields, copyField),
+		fieldValues:   make(map[int]map[int]json.RawMessage, len(s.fieldValues)),
+
+		idempotencyKeys: cloneRecords(s.idempotencyKeys, copyIdempotencyRecord),
+	}
+	for boardID, limits := range s.wipLimits {
+		c.wipLimits[boardID] = maps.Clone(limits)
+	}
+	for taskID, values := range s.fieldValues {
+		c.fieldValues[taskID] = maps.Clone(values)
+	}
+	return c
+}
+
+func (s *Store) restore(snapshot *Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Идентификаторы не переиспользуются, как и значения последовательностей в базе
+	s.users = snapshot.users
+	s.boards = snapshot.boards
+	s.tasks = snapshot.tasks
+	s.boardTasks = snapshot.boardTasks
+	s.wipLimits = snapshot.wipLimits
+	s.refreshTokens = snapshot.refreshTokens
+	s.savedViews = snapshot.savedViews
+	s.series = snapshot.series
+	s.timeEntries = snapshot.timeEntries
+	s.fields = snapshot.fields
+	s.fieldValues = snapshot.fieldValues
+	s.idempotencyKeys = snapshot.idempotencyKeys
+}
+
+func cloneRecords[K comparable, V any](records map[K]*V, copyRecord func(*V) *V) map[K]*V {
+	c := make(map[K]*V, len(records))
+	for key, record := range records {
+		c[key] = copyRecord(record)
+	}
+	return c
+}
+
+func copyUser(user *models.User) *models.User {
+	c := *user
+	return &c
+}
+
+func copyToken(token *models.RefreshToken) *models.RefreshToken {
+	c := *token
+	return &c
+}
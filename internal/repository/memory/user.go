@@ -0,0 +1,109 @@
+package memory_repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type UserMemoryRepo struct {
+	s *Store
+}
+
+func NewUserMemoryRepo(s *Store) *UserMemoryRepo {
+	return &UserMemoryRepo{s: s}
+}
+
+func (r *UserMemoryRepo) Create(ctx context.Context, user *models.User) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if r.emailTaken(user.Email, 0) {
+		return repository.Conflict("user already exists")
+	}
+
+	user.ID = r.s.nextID()
+	user.CreatedAt = time.Now()
+	stored := *user
+	r.s.users[user.ID] = &stored
+	return nil
+}
+
+func (r *UserMemoryRepo) GetById(ctx context.Context, id int) (*models.User, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, repository.NotFound("user")
+	}
+	found := *user
+	return &found, nil
+}
+
+func (r *UserMemoryRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := r.s.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.s.mu.RUnlock()
+
+	for _, user := range r.s.users {
+		if user.Email == email {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, repository.NotFound("user")
+}
+
+// Пустые email и пароль не меняют сохранённые значения
+func (r *UserMemoryRepo) Update(ctx context.Context, user *models.User) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.users[user.ID]
+	if !ok {
+		return repository.NotFound("user")
+	}
+
+	if user.Email != "" {
+		if r.emailTaken(user.Email, user.ID) {
+			return repository.Conflict("user already exists")
+		}
+		stored.Email = user.Email
+	}
+	if user.PasswordHash != "" {
+		stored.PasswordHash = user.PasswordHash
+	}
+	return nil
+}
+
+func (r *UserMemoryRepo) Delete(ctx context.Context, id int) error {
+	if err := r.s.lock(ctx); err != nil {
+		return err
+	}
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[id]; !ok {
+		return repository.NotFound("user")
+	}
+	r.s.deleteUser(id)
+	return nil
+}
+
+func (r *UserMemoryRepo) emailTaken(email string, exceptID int) bool {
+	for _, user := range r.s.users {
+		if user.Email == email && user.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -1,21 +1,23 @@
 package refresh_token_repo
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
-	"errors"
 	"time"
 
 	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
 )
 
 type RefreshTokenPostgresRepo struct {
-	db *sql.DB
+	db      *repository.DB
+	timeout time.Duration
 }
 
-func NewRefreshTokenPostgresRepo(db *sql.DB) *RefreshTokenPostgresRepo {
-	return &RefreshTokenPostgresRepo{db: db}
+func NewRefreshTokenPostgresRepo(db *sql.DB, timeout time.Duration) *RefreshTokenPostgresRepo {
+	return &RefreshTokenPostgresRepo{db: repository.NewDB(db), timeout: timeout}
 }
 
 func hashToken(token string) string {
@@ -23,13 +25,16 @@ func hashToken(token string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func (r *RefreshTokenPostgresRepo) Create(token *models.RefreshToken) error {
+func (r *RefreshTokenPostgresRepo) Create(ctx context.Context, token *models.RefreshToken) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		INSERT INTO refresh_tokens (token_hash, user_id, expires_at, created_at)
 		VALUES ($1, $2, $3, $4)
 	`
 
-	_, err := r.db.Exec(
+	_, err = r.db.ExecContext(ctx,
 		query,
 		hashToken(token.TokenHash),
 		token.UserID,
@@ -40,7 +45,10 @@ func (r *RefreshTokenPostgresRepo) Create(token *models.RefreshToken) error {
 	return err
 }
 
-func (r *RefreshTokenPostgresRepo) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+func (r *RefreshTokenPostgresRepo) GetByHash(ctx context.Context, tokenHash string) (_ *models.RefreshToken, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		SELECT token_hash, user_id, expires_at, created_at
 		FROM refresh_tokens
@@ -48,7 +56,7 @@ func (r *RefreshTokenPostgresRepo) GetByHash(tokenHash string) (*models.RefreshT
 	`
 
 	refreshToken := &models.RefreshToken{}
-	err := r.db.QueryRow(query, hashToken(tokenHash)).Scan(
+	err = r.db.QueryRowContext(ctx, query, hashToken(tokenHash)).Scan(
 		&refreshToken.TokenHash,
 		&refreshToken.UserID,
 		&refreshToken.ExpiresAt,
@@ -57,7 +65,7 @@ func (r *RefreshTokenPostgresRepo) GetByHash(tokenHash string) (*models.RefreshT
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("refresh token not found")
+			return nil, repository.NotFound("refresh token")
 		}
 		return nil, err
 	}
@@ -65,27 +73,36 @@ func (r *RefreshTokenPostgresRepo) GetByHash(tokenHash string) (*models.RefreshT
 	return refreshToken, nil
 }
 
-func (r *RefreshTokenPostgresRepo) DeleteByHash(tokenHash string) error {
+func (r *RefreshTokenPostgresRepo) DeleteByHash(ctx context.Context, tokenHash string) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `DELETE FROM refresh_tokens WHERE token_hash = $1`
-	_, err := r.db.Exec(query, hashToken(tokenHash))
+	_, err = r.db.ExecContext(ctx, query, hashToken(tokenHash))
 	return err
 }
 
-func (r *RefreshTokenPostgresRepo) DeleteAllForUser(userID int) error {
+func (r *RefreshTokenPostgresRepo) DeleteAllForUser(ctx context.Context, userID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
-	_, err := r.db.Exec(query, userID)
+	_, err = r.db.ExecContext(ctx, query, userID)
 	return err
 }
 
-func (r *RefreshTokenPostgresRepo) Exists(tokenHash string) (bool, error) {
+func (r *RefreshTokenPostgresRepo) Exists(ctx context.Context, tokenHash string) (_ bool, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		SELECT COUNT(*)
 		FROM refresh_tokens
 		WHERE token_hash = $1 AND expires_at > NOW()
 	`
-	
+
 	var count int
-	err := r.db.QueryRow(query, hashToken(tokenHash)).Scan(&count)
+	err = r.db.QueryRowContext(ctx, query, hashToken(tokenHash)).Scan(&count)
 
 	if count > 0 {
 		return true, err
@@ -93,11 +110,14 @@ func (r *RefreshTokenPostgresRepo) Exists(tokenHash string) (bool, error) {
 	return false, err
 }
 
-func (r *RefreshTokenPostgresRepo) RevokeExpires() (int64, error) {
+func (r *RefreshTokenPostgresRepo) RevokeExpires(ctx context.Context) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `DELETE FROM refresh_tokens WHERE expires_at < NOW()`
-	result, err := r.db.Exec(query)
+	result, err := r.db.ExecContext(ctx, query)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected()
-}
\ No newline at end of file
+}
@@ -0,0 +1,112 @@
+package refresh_token_repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type RefreshTokenSQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewRefreshTokenSQLiteRepo(db *sql.DB, timeout time.Duration) *RefreshTokenSQLiteRepo {
+	return &RefreshTokenSQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+func (r *RefreshTokenSQLiteRepo) Create(ctx context.Context, token *models.RefreshToken) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO refresh_tokens (token_hash, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err = r.db.ExecContext(ctx,
+		query,
+		hashToken(token.TokenHash),
+		token.UserID,
+		token.ExpiresAt.UTC(),
+		time.Now().UTC(),
+	)
+
+	return err
+}
+
+func (r *RefreshTokenSQLiteRepo) GetByHash(ctx context.Context, tokenHash string) (_ *models.RefreshToken, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT token_hash, user_id, expires_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1 AND expires_at > $2
+	`
+
+	refreshToken := &models.RefreshToken{}
+	err = r.db.QueryRowContext(ctx, query, hashToken(tokenHash), time.Now().UTC()).Scan(
+		&refreshToken.TokenHash,
+		&refreshToken.UserID,
+		&refreshToken.ExpiresAt,
+		&refreshToken.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("refresh token")
+		}
+		return nil, err
+	}
+
+	return refreshToken, nil
+}
+
+func (r *RefreshTokenSQLiteRepo) DeleteByHash(ctx context.Context, tokenHash string) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM refresh_tokens WHERE token_hash = $1`
+	_, err = r.db.ExecContext(ctx, query, hashToken(tokenHash))
+	return err
+}
+
+func (r *RefreshTokenSQLiteRepo) DeleteAllForUser(ctx context.Context, userID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
+	_, err = r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *RefreshTokenSQLiteRepo) Exists(ctx context.Context, tokenHash string) (_ bool, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT COUNT(*)
+		FROM refresh_tokens
+		WHERE token_hash = $1 AND expires_at > $2
+	`
+
+	var count int
+	err = r.db.QueryRowContext(ctx, query, hashToken(tokenHash), time.Now().UTC()).Scan(&count)
+	return count > 0, err
+}
+
+func (r *RefreshTokenSQLiteRepo) RevokeExpires(ctx context.Context) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM refresh_tokens WHERE expires_at < $1`
+	result, err := r.db.ExecContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,225 @@
+package saved_view_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type SavedViewPostgresRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewSavedViewPostgresRepo(db *sql.DB, timeout time.Duration) *SavedViewPostgresRepo {
+	return &SavedViewPostgresRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+// Доска фильтра хранится отдельной колонкой, чтобы находить представления, расшаренные на доску
+func boardIDOf(view *models.SavedView) sql.NullInt64 {
+	if view.Filter.BoardID == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(view.Filter.BoardID), Valid: true}
+}
+
+func (r *SavedViewPostgresRepo) Create(ctx context.Context, view *models.SavedView) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO saved_views (user_id, name, filter, board_id, pinned, shared, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	filter, err := json.Marshal(view.Filter)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		view.UserID,
+		view.Name,
+		filter,
+		boardIDOf(view),
+		view.Pinned,
+		view.Shared,
+		now,
+		now,
+	).Scan(&view.ID)
+
+	if err != nil {
+		return err
+	}
+
+	view.CreatedAt = now
+	view.UpdatedAt = now
+	return nil
+}
+
+func (r *SavedViewPostgresRepo) GetById(ctx context.Context, id int) (_ *models.SavedView, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, user_id, name, filter, pinned, shared, created_at, updated_at
+		FROM saved_views
+		WHERE id = $1
+	`
+
+	view, err := scanView(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("saved view")
+		}
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (r *SavedViewPostgresRepo) Update(ctx context.Context, view *models.SavedView) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE saved_views
+		SET name = $1,
+			filter = $2,
+			board_id = $3,
+			pinned = $4,
+			shared = $5,
+			updated_at = $6
+		WHERE id = $7
+	`
+
+	filter, err := json.Marshal(view.Filter)
+	if err != nil {
+		return err
+	}
+
+	view.UpdatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx,
+		query,
+		view.Name,
+		filter,
+		boardIDOf(view),
+		view.Pinned,
+		view.Shared,
+		view.UpdatedAt,
+		view.ID,
+	)
+
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "saved view")
+}
+
+func (r *SavedViewPostgresRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM saved_views WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "saved view")
+}
+
+// Собственные представления пользователя и расшаренные на доски, участником которых он является
+func (r *SavedViewPostgresRepo) ListAccessible(ctx context.Context, userID int) (_ []*models.SavedView, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, user_id, name, filter, pinned, shared, created_at, updated_at
+		FROM saved_views v
+		WHERE v.user_id = $1
+			OR (v.shared AND (
+				EXISTS (SELECT 1 FROM boards b WHERE b.id = v.board_id AND b.user_id = $1)
+				OR EXISTS (
+					SELECT 1
+					FROM board_tasks bt
+					JOIN tasks t ON t.id = bt.task_id
+					WHERE bt.board_id = v.board_id AND t.user_id = $1
+				)
+			))
+		ORDER BY v.name
+	`
+
+	return r.list(ctx, query, userID)
+}
+
+func (r *SavedViewPostgresRepo) ListPinned(ctx context.Context, userID int) (_ []*models.SavedView, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, user_id, name, filter, pinned, shared, created_at, updated_at
+		FROM saved_views
+		WHERE user_id = $1 AND pinned
+		ORDER BY name
+	`
+
+	return r.list(ctx, query, userID)
+}
+
+func (r *SavedViewPostgresRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.SavedView, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*models.SavedView
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanView(row rowScanner) (*models.SavedView, error) {
+	view := &models.SavedView{}
+	var filter []byte
+	err := row.Scan(
+		&view.ID,
+		&view.UserID,
+		&view.Name,
+		&filter,
+		&view.Pinned,
+		&view.Shared,
+		&view.CreatedAt,
+		&view.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(filter, &view.Filter); err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
@@ -1,37 +1,47 @@
 package task_repo
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/lib/pq"
 )
 
 type TaskPostgresRepo struct {
-	db *sql.DB
+	db      *repository.DB
+	timeout time.Duration
 }
 
-func NewTaskPostgresRepo(db *sql.DB) *TaskPostgresRepo {
-	return &TaskPostgresRepo{db: db}
+func NewTaskPostgresRepo(db *sql.DB, timeout time.Duration) *TaskPostgresRepo {
+	return &TaskPostgresRepo{db: repository.NewDB(db), timeout: timeout}
 }
 
-func (r *TaskPostgresRepo) Create(task *models.Task) error {
+func (r *TaskPostgresRepo) Create(ctx context.Context, task *models.Task) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		INSERT INTO tasks (title, description, status, user_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		INSERT INTO tasks (title, description, status, user_id, series_id, estimate, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, version
 	`
 	now := time.Now()
-	err := r.db.QueryRow(
+	err = r.db.QueryRowContext(ctx,
 		query,
 		task.Title,
 		task.Description,
 		task.Status,
 		task.UserID,
+		task.SeriesID,
+		task.Estimate,
 		now,
 		now,
-	).Scan(&task.ID)
+	).Scan(&task.ID, &task.Version)
 
 	if err != nil {
 		return err
@@ -40,29 +50,35 @@ func (r *TaskPostgresRepo) Create(task *models.Task) error {
 	return nil
 }
 
-func (r *TaskPostgresRepo) GetById(id int) (*models.Task, error) {
+func (r *TaskPostgresRepo) GetById(ctx context.Context, id int) (_ *models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		SELECT id, title, description, status, user_id, created_at, updated_at
+		SELECT id, title, description, status, user_id, series_id, estimate, version, created_at, updated_at
 		FROM tasks
 		WHERE id = $1
 	`
 
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id)
 
 	task := &models.Task{}
-	err := row.Scan(
+	err = row.Scan(
 		&task.ID,
 		&task.Title,
 		&task.Description,
 		&task.Status,
 		&task.UserID,
+		&task.SeriesID,
+		&task.Estimate,
+		&task.Version,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("task not found")
+			return nil, repository.NotFound("task")
 		}
 
 		return nil, err
@@ -71,43 +87,115 @@ func (r *TaskPostgresRepo) GetById(id int) (*models.Task, error) {
 	return task, nil
 }
 
-func (r *TaskPostgresRepo) Update(task *models.Task) error {
+func (r *TaskPostgresRepo) GetByIDs(ctx context.Context, ids []int) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, title, description, status, user_id, series_id, estimate, version, created_at, updated_at
+		FROM tasks
+		WHERE id = ANY($1)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	for rows.Next() {
+		task := &models.Task{}
+		err := rows.Scan(
+			&task.ID,
+			&task.Title,
+			&task.Description,
+			&task.Status,
+			&task.UserID,
+			&task.SeriesID,
+			&task.Estimate,
+			&task.Version,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return inOrder(tasks, ids), nil
+}
+
+func (r *TaskPostgresRepo) Update(ctx context.Context, task *models.Task) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		UPDATE tasks
 		SET title = $1,
 			description = $2,
 			status = $3,
-			updated_at = $4
-		WHERE id = $5
+			estimate = $4,
+			updated_at = $5,
+			version = version + 1
+		WHERE id = $6 AND version = $7
 	`
 
-	_, err := r.db.Exec(
+	result, err := r.db.ExecContext(ctx,
 		query,
 		task.Title,
 		task.Description,
 		task.Status,
+		task.Estimate,
 		time.Now(),
 		task.ID,
+		task.Version,
 	)
 
-	return err
+	if err != nil {
+		return err
+	}
+	if err := repository.CheckVersion(ctx, r.db, result, "tasks", task.ID, "task"); err != nil {
+		return err
+	}
+
+	task.Version++
+	return nil
 }
 
-func (r *TaskPostgresRepo) Delete(id int) error {
-	query := `DELETE FROM tasks WHERE id = $1`
-	_, err := r.db.Exec(query, id)
-	return err
+func (r *TaskPostgresRepo) Delete(ctx context.Context, id, version int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM tasks WHERE id = $1 AND version = $2`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+	return repository.CheckVersion(ctx, r.db, result, "tasks", id, "task")
 }
 
-func (r *TaskPostgresRepo) ListByUser(userID int) ([]*models.Task, error) {
+func (r *TaskPostgresRepo) ListByUser(ctx context.Context, userID int) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		SELECT id, title, description, status, user_id, created_at, updated_at
+		SELECT id, title, description, status, user_id, series_id, estimate, version, created_at, updated_at
 		FROM tasks
 		WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +210,9 @@ func (r *TaskPostgresRepo) ListByUser(userID int) ([]*models.Task, error) {
 			&task.Description,
 			&task.Status,
 			&task.UserID,
+			&task.SeriesID,
+			&task.Estimate,
+			&task.Version,
 			&task.CreatedAt,
 			&task.UpdatedAt,
 		)
@@ -138,14 +229,17 @@ func (r *TaskPostgresRepo) ListByUser(userID int) ([]*models.Task, error) {
 	return tasks, nil
 }
 
-func (r *TaskPostgresRepo) ListByUserAndStatus(userID int, status models.TaskStatus) ([]*models.Task, error) {
+func (r *TaskPostgresRepo) ListByUserAndStatus(ctx context.Context, userID int, status models.TaskStatus) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
-		SELECT id, title, description, status, user_id, created_at, updated_at
+		SELECT id, title, description, status, user_id, series_id, estimate, version, created_at, updated_at
 		FROM tasks
 		WHERE user_id = $1 AND status = $2
 	`
 
-	rows, err := r.db.Query(query, userID, status)
+	rows, err := r.db.QueryContext(ctx, query, userID, status)
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +254,90 @@ func (r *TaskPostgresRepo) ListByUserAndStatus(userID int, status models.TaskSta
 			&task.Description,
 			&task.Status,
 			&task.UserID,
+			&task.SeriesID,
+			&task.Estimate,
+			&task.Version,
 			&task.CreatedAt,
+			&task.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// Выборка задач по фильтру. Без доски в фильтре возвращаются только задачи пользователя
+func (r *TaskPostgresRepo) ListByFilter(ctx context.Context, userID int, filter models.TaskFilter) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	var conditions []string
+	var args []interface{}
+
+	addArg := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.BoardID != 0 {
+		conditions = append(conditions, "id IN (SELECT task_id FROM board_tasks WHERE board_id = "+addArg(filter.BoardID)+")")
+	}
+
+	if filter.BoardID == 0 || filter.OnlyMine {
+		conditions = append(conditions, "user_id = "+addArg(userID))
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		conditions = append(conditions, "status::text = ANY("+addArg(pq.Array(statuses))+")")
+	}
+
+	// Для множественного выбора значение ищется среди элементов списка
+	for fieldID, value := range filter.Fields {
+		field, val := addArg(fieldID), addArg(value)
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM task_custom_field_values v
+			WHERE v.task_id = tasks.id AND v.field_id = `+field+`
+				AND (v.value #>> '{}' = `+val+` OR (jsonb_typeof(v.value) = 'array' AND v.value ? `+val+`))
+		)`)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := addArg("%" + search + "%")
+		conditions = append(conditions, "(title ILIKE "+pattern+" OR description ILIKE "+pattern+")")
+	}
+
+	query := `
+		SELECT id, title, description, status, user_id, series_id, estimate, version, created_at, updated_at
+		FROM tasks
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	for rows.Next() {
+		task := &models.Task{}
+		err := rows.Scan(
+			&task.ID,
+			&task.Title,
+			&task.Description,
+			&task.Status,
+			&task.UserID,
+			&task.SeriesID,
+			&task.Estimate,
+			&task.Version,
 			&task.CreatedAt,
 			&task.UpdatedAt,
 		)
@@ -170,5 +347,9 @@ func (r *TaskPostgresRepo) ListByUserAndStatus(userID int, status models.TaskSta
 		tasks = append(tasks, task)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return tasks, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,284 @@
+package task_repo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TaskSQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewTaskSQLiteRepo(db *sql.DB, timeout time.Duration) *TaskSQLiteRepo {
+	return &TaskSQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+const taskColumns = `id, title, description, status, user_id, series_id, estimate, version, created_at, updated_at`
+
+func (r *TaskSQLiteRepo) Create(ctx context.Context, task *models.Task) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO tasks (title, description, status, user_id, series_id, estimate, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, version
+	`
+
+	now := time.Now().UTC()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		task.Title,
+		task.Description,
+		task.Status,
+		task.UserID,
+		task.SeriesID,
+		task.Estimate,
+		now,
+		now,
+	).Scan(&task.ID, &task.Version)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *TaskSQLiteRepo) GetById(ctx context.Context, id int) (_ *models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1`
+
+	task, err := scanTask(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("task")
+		}
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (r *TaskSQLiteRepo) GetByIDs(ctx context.Context, ids []int) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id IN (` + strings.Join(placeholders, ", ") + `)`
+
+	tasks, err := r.list(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(tasks, ids), nil
+}
+
+func (r *TaskSQLiteRepo) Update(ctx context.Context, task *models.Task) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE tasks
+		SET title = $1,
+			description = $2,
+			status = $3,
+			estimate = $4,
+			updated_at = $5,
+			version = version + 1
+		WHERE id = $6 AND version = $7
+	`
+
+	result, err := r.db.ExecContext(ctx,
+		query,
+		task.Title,
+		task.Description,
+		task.Status,
+		task.Estimate,
+		time.Now().UTC(),
+		task.ID,
+		task.Version,
+	)
+
+	if err != nil {
+		return err
+	}
+	if err := repository.CheckVersion(ctx, r.db, result, "tasks", task.ID, "task"); err != nil {
+		return err
+	}
+
+	task.Version++
+	return nil
+}
+
+func (r *TaskSQLiteRepo) Delete(ctx context.Context, id, version int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM tasks WHERE id = $1 AND version = $2`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+	return repository.CheckVersion(ctx, r.db, result, "tasks", id, "task")
+}
+
+func (r *TaskSQLiteRepo) ListByUser(ctx context.Context, userID int) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.list(ctx, query, userID)
+}
+
+/*
+Выборка задач по фильтру. Без доски в фильтре возвращаются только задачи пользователя
+LIKE в SQLite не учитывает регистр только для латиницы, в отличие от ILIKE в Postgres
+*/
+func (r *TaskSQLiteRepo) ListByFilter(ctx context.Context, userID int, filter models.TaskFilter) (_ []*models.Task, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	var conditions []string
+	var args []interface{}
+
+	addArg := func(arg interface{}) string {
+		args = append(args, arg)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.BoardID != 0 {
+		conditions = append(conditions, "id IN (SELECT task_id FROM board_tasks WHERE board_id = "+addArg(filter.BoardID)+")")
+	}
+
+	if filter.BoardID == 0 || filter.OnlyMine {
+		conditions = append(conditions, "user_id = "+addArg(userID))
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, addArg(string(status)))
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(statuses, ", ")+")")
+	}
+
+	// Для множественного выбора значение ищется среди элементов списка
+	for fieldID, value := range filter.Fields {
+		field, val := addArg(fieldID), addArg(value)
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM task_custom_field_values v
+			WHERE v.task_id = tasks.id AND v.field_id = `+field+`
+				AND (CAST(json_extract(v.value, '$') AS TEXT) = `+val+`
+					OR (json_type(v.value) = 'array' AND EXISTS (
+						SELECT 1 FROM json_each(v.value) e WHERE e.value = `+val+`
+					)))
+		)`)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := addArg("%" + search + "%")
+		conditions = append(conditions, "(title LIKE "+pattern+" OR description LIKE "+pattern+")")
+	}
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY created_at DESC
+	`
+
+	return r.list(ctx, query, args...)
+}
+
+func (r *TaskSQLiteRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.Task, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// Расставляет задачи в порядке ids, повторы в ids дают одну задачу
+func inOrder(tasks []*models.Task, ids []int) []*models.Task {
+	byID := make(map[int]*models.Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+
+	ordered := make([]*models.Task, 0, len(tasks))
+	for _, id := range ids {
+		if task, ok := byID[id]; ok {
+			ordered = append(ordered, task)
+			delete(byID, id)
+		}
+	}
+	return ordered
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	task := &models.Task{}
+	var description sql.NullString
+	err := row.Scan(
+		&task.ID,
+		&task.Title,
+		&description,
+		&task.Status,
+		&task.UserID,
+		&task.SeriesID,
+		&task.Estimate,
+		&task.Version,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	task.Description = description.String
+	return task, nil
+}
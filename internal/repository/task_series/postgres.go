@@ -0,0 +1,255 @@
+package task_series_repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TaskSeriesPostgresRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewTaskSeriesPostgresRepo(db *sql.DB, timeout time.Duration) *TaskSeriesPostgresRepo {
+	return &TaskSeriesPostgresRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+func (r *TaskSeriesPostgresRepo) Create(ctx context.Context, series *models.TaskSeries) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO task_series (user_id, title, description, frequency, interval_count, until, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	if series.Rule.Interval < 1 {
+		series.Rule.Interval = 1
+	}
+
+	now := time.Now()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		series.UserID,
+		series.Title,
+		series.Description,
+		series.Rule.Frequency,
+		series.Rule.Interval,
+		series.Rule.Until,
+		series.NextRunAt,
+		now,
+		now,
+	).Scan(&series.ID)
+
+	if err != nil {
+		return err
+	}
+
+	series.CreatedAt = now
+	series.UpdatedAt = now
+	return nil
+}
+
+func (r *TaskSeriesPostgresRepo) GetById(ctx context.Context, id int) (_ *models.TaskSeries, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT s.id, s.user_id, s.title, s.description, s.frequency, s.interval_count, s.until,
+			s.next_run_at, s.created_at, s.updated_at,
+			COALESCE((SELECT MAX(t.id) FROM tasks t WHERE t.series_id = s.id), 0)
+		FROM task_series s
+		WHERE s.id = $1
+	`
+
+	series, err := scanSeries(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("task series")
+		}
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// Обновляет шаблон серии и переносит изменения на все незавершённые экземпляры
+func (r *TaskSeriesPostgresRepo) Update(ctx context.Context, series *models.TaskSeries) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	if series.Rule.Interval < 1 {
+		series.Rule.Interval = 1
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE task_series
+		SET title = $1,
+			description = $2,
+			frequency = $3,
+			interval_count = $4,
+			until = $5,
+			next_run_at = $6,
+			updated_at = $7
+		WHERE id = $8
+	`,
+		series.Title,
+		series.Description,
+		series.Rule.Frequency,
+		series.Rule.Interval,
+		series.Rule.Until,
+		series.NextRunAt,
+		now,
+		series.ID,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tasks
+		SET title = $1,
+			description = $2,
+			updated_at = $3,
+			version = version + 1
+		WHERE series_id = $4 AND status <> 'done'
+	`,
+		series.Title,
+		series.Description,
+		now,
+		series.ID,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	series.UpdatedAt = now
+	return tx.Commit()
+}
+
+// Удаление серии прекращает повторение, созданные экземпляры остаются
+func (r *TaskSeriesPostgresRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM task_series WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "task series")
+}
+
+func (r *TaskSeriesPostgresRepo) AttachTask(ctx context.Context, seriesID, taskID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `UPDATE tasks SET series_id = $1 WHERE id = $2`
+	_, err = r.db.ExecContext(ctx, query, seriesID, taskID)
+	return err
+}
+
+/*
+Серии, для которых пора создать экземпляр: наступило время по расписанию
+или все созданные экземпляры завершены. Исчерпанные правила не возвращаются
+*/
+func (r *TaskSeriesPostgresRepo) ListDue(ctx context.Context, now time.Time) (_ []*models.TaskSeries, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT s.id, s.user_id, s.title, s.description, s.frequency, s.interval_count, s.until,
+			s.next_run_at, s.created_at, s.updated_at,
+			COALESCE((SELECT MAX(t.id) FROM tasks t WHERE t.series_id = s.id), 0)
+		FROM task_series s
+		WHERE (s.until IS NULL OR s.until >= $1)
+			AND (
+				s.next_run_at <= $1
+				OR NOT EXISTS (
+					SELECT 1 FROM tasks t WHERE t.series_id = s.id AND t.status <> 'done'
+				)
+			)
+		ORDER BY s.next_run_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.TaskSeries
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, series)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Условие на прежнее время запуска не даёт двум экземплярам приложения создать экземпляр за один период
+func (r *TaskSeriesPostgresRepo) SetNextRun(ctx context.Context, id int, prev, next time.Time) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `UPDATE task_series SET next_run_at = $1 WHERE id = $2 AND next_run_at = $3`
+	result, err := r.db.ExecContext(ctx, query, next, id, prev)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.Stale("task series")
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSeries(row rowScanner) (*models.TaskSeries, error) {
+	series := &models.TaskSeries{}
+	var description sql.NullString
+	err := row.Scan(
+		&series.ID,
+		&series.UserID,
+		&series.Title,
+		&description,
+		&series.Rule.Frequency,
+		&series.Rule.Interval,
+		&series.Rule.Until,
+		&series.NextRunAt,
+		&series.CreatedAt,
+		&series.UpdatedAt,
+		&series.LastTaskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	series.Description = description.String
+	return series, nil
+}
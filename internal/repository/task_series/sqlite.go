@@ -0,0 +1,236 @@
+package task_series_repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TaskSeriesSQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewTaskSeriesSQLiteRepo(db *sql.DB, timeout time.Duration) *TaskSeriesSQLiteRepo {
+	return &TaskSeriesSQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+const seriesColumns = `s.id, s.user_id, s.title, s.description, s.frequency, s.interval_count, s.until,
+	s.next_run_at, s.created_at, s.updated_at,
+	COALESCE((SELECT MAX(t.id) FROM tasks t WHERE t.series_id = s.id), 0)`
+
+// Время хранится в UTC, иначе сравнение дат в запросах зависит от часового пояса
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func (r *TaskSeriesSQLiteRepo) Create(ctx context.Context, series *models.TaskSeries) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO task_series (user_id, title, description, frequency, interval_count, until, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	if series.Rule.Interval < 1 {
+		series.Rule.Interval = 1
+	}
+
+	now := time.Now().UTC()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		series.UserID,
+		series.Title,
+		series.Description,
+		series.Rule.Frequency,
+		series.Rule.Interval,
+		utc(series.Rule.Until),
+		series.NextRunAt.UTC(),
+		now,
+		now,
+	).Scan(&series.ID)
+
+	if err != nil {
+		return err
+	}
+
+	series.CreatedAt = now
+	series.UpdatedAt = now
+	return nil
+}
+
+func (r *TaskSeriesSQLiteRepo) GetById(ctx context.Context, id int) (_ *models.TaskSeries, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + seriesColumns + `
+		FROM task_series s
+		WHERE s.id = $1
+	`
+
+	series, err := scanSeries(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("task series")
+		}
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// Обновляет шаблон серии и переносит изменения на все незавершённые экземпляры
+func (r *TaskSeriesSQLiteRepo) Update(ctx context.Context, series *models.TaskSeries) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	if series.Rule.Interval < 1 {
+		series.Rule.Interval = 1
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE task_series
+		SET title = $1,
+			description = $2,
+			frequency = $3,
+			interval_count = $4,
+			until = $5,
+			next_run_at = $6,
+			updated_at = $7
+		WHERE id = $8
+	`,
+		series.Title,
+		series.Description,
+		series.Rule.Frequency,
+		series.Rule.Interval,
+		utc(series.Rule.Until),
+		series.NextRunAt.UTC(),
+		now,
+		series.ID,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tasks
+		SET title = $1,
+			description = $2,
+			updated_at = $3,
+			version = version + 1
+		WHERE series_id = $4 AND status <> 'done'
+	`,
+		series.Title,
+		series.Description,
+		now,
+		series.ID,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	series.UpdatedAt = now
+	return tx.Commit()
+}
+
+// Удаление серии прекращает повторение, созданные экземпляры остаются
+func (r *TaskSeriesSQLiteRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM task_series WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "task series")
+}
+
+func (r *TaskSeriesSQLiteRepo) AttachTask(ctx context.Context, seriesID, taskID int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `UPDATE tasks SET series_id = $1 WHERE id = $2`
+	_, err = r.db.ExecContext(ctx, query, seriesID, taskID)
+	return err
+}
+
+/*
+Серии, для которых пора создать экземпляр: наступило время по расписанию
+или все созданные экземпляры завершены. Исчерпанные правила не возвращаются
+*/
+func (r *TaskSeriesSQLiteRepo) ListDue(ctx context.Context, now time.Time) (_ []*models.TaskSeries, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + seriesColumns + `
+		FROM task_series s
+		WHERE (s.until IS NULL OR s.until >= $1)
+			AND (
+				s.next_run_at <= $1
+				OR NOT EXISTS (
+					SELECT 1 FROM tasks t WHERE t.series_id = s.id AND t.status <> 'done'
+				)
+			)
+		ORDER BY s.next_run_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.TaskSeries
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, series)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Условие на прежнее время запуска не даёт двум экземплярам приложения создать экземпляр за один период
+func (r *TaskSeriesSQLiteRepo) SetNextRun(ctx context.Context, id int, prev, next time.Time) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `UPDATE task_series SET next_run_at = $1 WHERE id = $2 AND next_run_at = $3`
+	result, err := r.db.ExecContext(ctx, query, next.UTC(), id, prev.UTC())
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.Stale("task series")
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package time_entry_repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/lib/pq"
+)
+
+type TimeEntryPostgresRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewTimeEntryPostgresRepo(db *sql.DB, timeout time.Duration) *TimeEntryPostgresRepo {
+	return &TimeEntryPostgresRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+// Длительность запущенного таймера считается до текущего момента
+const secondsExpr = `CAST(EXTRACT(EPOCH FROM (COALESCE(e.ended_at, NOW()) - e.started_at)) AS BIGINT)`
+
+const entryColumns = `e.id, e.user_id, e.task_id, e.started_at, e.ended_at, ` + secondsExpr + `, e.note, e.manual, e.created_at`
+
+func (r *TimeEntryPostgresRepo) StartTimer(ctx context.Context, userID, taskID int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO time_entries AS e (user_id, task_id, started_at, manual, created_at)
+		VALUES ($1, $2, $3, FALSE, $3)
+		RETURNING ` + entryColumns
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, userID, taskID, time.Now()))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, repository.Conflict("timer already running")
+		}
+		return nil, repository.TranslatePQ(err, "task")
+	}
+
+	return entry, nil
+}
+
+func (r *TimeEntryPostgresRepo) StopTimer(ctx context.Context, userID int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE time_entries AS e
+		SET ended_at = $1
+		WHERE e.user_id = $2 AND e.ended_at IS NULL
+		RETURNING ` + entryColumns
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, time.Now(), userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("running timer")
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *TimeEntryPostgresRepo) GetRunning(ctx context.Context, userID int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + entryColumns + `
+		FROM time_entries e
+		WHERE e.user_id = $1 AND e.ended_at IS NULL
+	`
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("running timer")
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *TimeEntryPostgresRepo) CreateManual(ctx context.Context, entry *models.TimeEntry) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO time_entries (user_id, task_id, started_at, ended_at, note, manual, created_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6)
+		RETURNING id
+	`
+
+	now := time.Now()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		entry.UserID,
+		entry.TaskID,
+		entry.StartedAt,
+		entry.EndedAt,
+		entry.Note,
+		now,
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return repository.TranslatePQ(err, "task")
+	}
+
+	entry.Manual = true
+	entry.CreatedAt = now
+	if entry.EndedAt != nil {
+		entry.Seconds = int64(entry.EndedAt.Sub(entry.StartedAt).Seconds())
+	}
+	return nil
+}
+
+func (r *TimeEntryPostgresRepo) GetById(ctx context.Context, id int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + entryColumns + `
+		FROM time_entries e
+		WHERE e.id = $1
+	`
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("time entry")
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *TimeEntryPostgresRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM time_entries WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "time entry")
+}
+
+func (r *TimeEntryPostgresRepo) ListByTask(ctx context.Context, taskID int) (_ []*models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + entryColumns + `
+		FROM time_entries e
+		WHERE e.task_id = $1
+		ORDER BY e.started_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.TimeEntry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *TimeEntryPostgresRepo) TotalByTask(ctx context.Context, taskID int) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `SELECT COALESCE(SUM(` + secondsExpr + `), 0) FROM time_entries e WHERE e.task_id = $1`
+	return r.total(ctx, query, taskID)
+}
+
+func (r *TimeEntryPostgresRepo) TotalByBoard(ctx context.Context, boardID int) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT COALESCE(SUM(` + secondsExpr + `), 0)
+		FROM time_entries e
+		JOIN board_tasks bt ON bt.task_id = e.task_id
+		WHERE bt.board_id = $1
+	`
+	return r.total(ctx, query, boardID)
+}
+
+func (r *TimeEntryPostgresRepo) TotalByUser(ctx context.Context, userID int) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `SELECT COALESCE(SUM(` + secondsExpr + `), 0) FROM time_entries e WHERE e.user_id = $1`
+	return r.total(ctx, query, userID)
+}
+
+func (r *TimeEntryPostgresRepo) total(ctx context.Context, query string, id int) (int64, error) {
+	var seconds int64
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return seconds, nil
+}
+
+// Время пользователя за период [from, to), сгруппированное по дням и задачам
+func (r *TimeEntryPostgresRepo) Report(ctx context.Context, userID int, from, to time.Time) (_ []*models.TimeReportRow, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT DATE_TRUNC('day', e.started_at) AS day, t.id, t.title, SUM(` + secondsExpr + `)
+		FROM time_entries e
+		JOIN tasks t ON t.id = e.task_id
+		WHERE e.user_id = $1 AND e.started_at >= $2 AND e.started_at < $3
+		GROUP BY day, t.id, t.title
+		ORDER BY day, t.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []*models.TimeReportRow
+	for rows.Next() {
+		row := &models.TimeReportRow{}
+		if err := rows.Scan(&row.Day, &row.TaskID, &row.TaskTitle, &row.Seconds); err != nil {
+			return nil, err
+		}
+		report = append(report, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row rowScanner) (*models.TimeEntry, error) {
+	entry := &models.TimeEntry{}
+	err := row.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.TaskID,
+		&entry.StartedAt,
+		&entry.EndedAt,
+		&entry.Seconds,
+		&entry.Note,
+		&entry.Manual,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,282 @@
+package time_entry_repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type TimeEntrySQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewTimeEntrySQLiteRepo(db *sql.DB, timeout time.Duration) *TimeEntrySQLiteRepo {
+	return &TimeEntrySQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+// Длительность запущенного таймера считается до текущего момента, julianday('now') - время в UTC
+const sqliteSecondsExpr = `CAST(ROUND((julianday(COALESCE(e.ended_at, 'now')) - julianday(e.started_at)) * 86400) AS INTEGER)`
+
+const sqliteEntryColumns = `e.id, e.user_id, e.task_id, e.started_at, e.ended_at, ` + sqliteSecondsExpr + `, e.note, e.manual, e.created_at`
+
+/*
+SQLite не допускает псевдонимы таблиц в RETURNING, поэтому запись после вставки
+или обновления читается отдельным запросом
+*/
+func (r *TimeEntrySQLiteRepo) StartTimer(ctx context.Context, userID, taskID int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO time_entries (user_id, task_id, started_at, manual, created_at)
+		VALUES ($1, $2, $3, FALSE, $3)
+		RETURNING id
+	`
+
+	var id int
+	err = r.db.QueryRowContext(ctx, query, userID, taskID, time.Now().UTC()).Scan(&id)
+	if err != nil {
+		err = repository.TranslateSQLite(err, "task")
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, repository.Conflict("timer already running")
+		}
+		return nil, err
+	}
+
+	return r.get(ctx, id)
+}
+
+func (r *TimeEntrySQLiteRepo) StopTimer(ctx context.Context, userID int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE time_entries
+		SET ended_at = $1
+		WHERE user_id = $2 AND ended_at IS NULL
+		RETURNING id
+	`
+
+	var id int
+	err = r.db.QueryRowContext(ctx, query, time.Now().UTC(), userID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("running timer")
+		}
+		return nil, err
+	}
+
+	return r.get(ctx, id)
+}
+
+func (r *TimeEntrySQLiteRepo) GetRunning(ctx context.Context, userID int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + sqliteEntryColumns + `
+		FROM time_entries e
+		WHERE e.user_id = $1 AND e.ended_at IS NULL
+	`
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("running timer")
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *TimeEntrySQLiteRepo) CreateManual(ctx context.Context, entry *models.TimeEntry) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO time_entries (user_id, task_id, started_at, ended_at, note, manual, created_at)
+		VALUES ($1, $2, $3, $4, $5, TRUE, $6)
+		RETURNING id
+	`
+
+	var endedAt *time.Time
+	if entry.EndedAt != nil {
+		t := entry.EndedAt.UTC()
+		endedAt = &t
+	}
+
+	now := time.Now().UTC()
+	err = r.db.QueryRowContext(ctx,
+		query,
+		entry.UserID,
+		entry.TaskID,
+		entry.StartedAt.UTC(),
+		endedAt,
+		entry.Note,
+		now,
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return repository.TranslateSQLite(err, "task")
+	}
+
+	entry.Manual = true
+	entry.CreatedAt = now
+	if entry.EndedAt != nil {
+		entry.Seconds = int64(entry.EndedAt.Sub(entry.StartedAt).Seconds())
+	}
+	return nil
+}
+
+func (r *TimeEntrySQLiteRepo) GetById(ctx context.Context, id int) (_ *models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	return r.get(ctx, id)
+}
+
+func (r *TimeEntrySQLiteRepo) get(ctx context.Context, id int) (*models.TimeEntry, error) {
+	query := `
+		SELECT ` + sqliteEntryColumns + `
+		FROM time_entries e
+		WHERE e.id = $1
+	`
+
+	entry, err := scanEntry(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("time entry")
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *TimeEntrySQLiteRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM time_entries WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "time entry")
+}
+
+func (r *TimeEntrySQLiteRepo) ListByTask(ctx context.Context, taskID int) (_ []*models.TimeEntry, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT ` + sqliteEntryColumns + `
+		FROM time_entries e
+		WHERE e.task_id = $1
+		ORDER BY e.started_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.TimeEntry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *TimeEntrySQLiteRepo) TotalByTask(ctx context.Context, taskID int) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `SELECT COALESCE(SUM(` + sqliteSecondsExpr + `), 0) FROM time_entries e WHERE e.task_id = $1`
+	return r.total(ctx, query, taskID)
+}
+
+func (r *TimeEntrySQLiteRepo) TotalByBoard(ctx context.Context, boardID int) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT COALESCE(SUM(` + sqliteSecondsExpr + `), 0)
+		FROM time_entries e
+		JOIN board_tasks bt ON bt.task_id = e.task_id
+		WHERE bt.board_id = $1
+	`
+	return r.total(ctx, query, boardID)
+}
+
+func (r *TimeEntrySQLiteRepo) TotalByUser(ctx context.Context, userID int) (_ int64, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `SELECT COALESCE(SUM(` + sqliteSecondsExpr + `), 0) FROM time_entries e WHERE e.user_id = $1`
+	return r.total(ctx, query, userID)
+}
+
+func (r *TimeEntrySQLiteRepo) total(ctx context.Context, query string, id int) (int64, error) {
+	var seconds int64
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return seconds, nil
+}
+
+// Время пользователя за период [from, to), сгруппированное по дням (в UTC) и задачам
+func (r *TimeEntrySQLiteRepo) Report(ctx context.Context, userID int, from, to time.Time) (_ []*models.TimeReportRow, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT date(e.started_at) AS day, t.id, t.title, SUM(` + sqliteSecondsExpr + `)
+		FROM time_entries e
+		JOIN tasks t ON t.id = e.task_id
+		WHERE e.user_id = $1 AND e.started_at >= $2 AND e.started_at < $3
+		GROUP BY day, t.id, t.title
+		ORDER BY day, t.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []*models.TimeReportRow
+	for rows.Next() {
+		row := &models.TimeReportRow{}
+		var day string
+		if err := rows.Scan(&day, &row.TaskID, &row.TaskTitle, &row.Seconds); err != nil {
+			return nil, err
+		}
+		if row.Day, err = time.Parse(time.DateOnly, day); err != nil {
+			return nil, err
+		}
+		report = append(report, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"modernc.org/sqlite"
+)
+
+const tracerName = "github.com/CAATHARSIS/task-tracking/internal/repository"
+
+/*
+Первое имя таблицы после FROM, INTO или UPDATE
+После FROM с подзапросом имени нет, и берётся таблица из следующего FROM, уже внутри подзапроса
+*/
+var statementTable = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE)\s+([a-z_][a-z0-9_]*)`)
+
+// Имя СУБД для атрибута db.system
+func dbSystem(db *sql.DB) string {
+	switch db.Driver().(type) {
+	case *pq.Driver:
+		return "postgresql"
+	case *sqlite.Driver:
+		return "sqlite"
+	}
+	return "other_sql"
+}
+
+/*
+Операция (SELECT, INSERT, ...) и таблица SQL-запроса для имени и атрибутов спана
+Разбор приблизительный, но запросы репозиториев начинаются с операции и называют таблицу явно
+*/
+func parseStatement(query string) (operation, table string) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	operation = strings.ToUpper(fields[0])
+	if match := statementTable.FindStringSubmatch(query); match != nil {
+		table = strings.ToLower(match[1])
+	}
+	return operation, table
+}
+
+/*
+Начинает клиентский спан SQL-запроса с именем "<операция> <таблица>"
+Текст запроса параметризован и попадает в спан без значений аргументов
+*/
+func startQuery(ctx context.Context, system, query string) (context.Context, trace.Span) {
+	operation, table := parseStatement(query)
+	name := operation
+	if table != "" {
+		name += " " + table
+	}
+
+	// Трассировщик берётся при каждом запросе, чтобы подхватить провайдер, установленный после создания репозиториев
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.collection.name", table),
+			attribute.String("db.query.text", strings.Join(strings.Fields(query), " ")),
+		),
+	)
+}
+
+// Отсутствие строк - обычный результат запроса, а не ошибка спана
+func endQuery(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func tracedExec(ctx context.Context, conn queryer, system, query string, args []any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, system, query)
+	result, err := conn.ExecContext(ctx, query, args...)
+	endQuery(span, err)
+	return result, err
+}
+
+// Спан покрывает выполнение запроса, но не чтение строк
+func tracedQuery(ctx context.Context, conn queryer, system, query string, args []any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, system, query)
+	rows, err := conn.QueryContext(ctx, query, args...)
+	endQuery(span, err)
+	return rows, err
+}
+
+func tracedQueryRow(ctx context.Context, conn queryer, system, query string, args []any) *sql.Row {
+	ctx, span := startQuery(ctx, system, query)
+	row := conn.QueryRowContext(ctx, query, args...)
+	endQuery(span, row.Err())
+	return row
+}
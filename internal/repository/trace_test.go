@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
+)
+
+func TestParseStatement(t *testing.T) {
+	for _, tt := range []struct {
+		query, operation, table string
+	}{
+		{"SELECT id, title FROM tasks WHERE id = $1", "SELECT", "tasks"},
+		{"\n\t\tselect t.id\n\t\tfrom board_tasks bt\n\t\tjoin tasks t on t.id = bt.task_id", "SELECT", "board_tasks"},
+		{"INSERT INTO board_tasks (board_id, task_id) VALUES ($1, $2) ON CONFLICT DO UPDATE SET task_id = $2", "INSERT", "board_tasks"},
+		{"UPDATE tasks SET updated_at = $1 WHERE id = $2", "UPDATE", "tasks"},
+		{"DELETE FROM time_entries WHERE id = $1", "DELETE", "time_entries"},
+		{"SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1)", "SELECT", "tasks"},
+		{"SELECT COUNT(*) FROM (SELECT task_id FROM board_tasks) bt", "SELECT", "board_tasks"},
+		{"SAVEPOINT sp_1", "SAVEPOINT", ""},
+		{"  ", "", ""},
+	} {
+		operation, table := parseStatement(tt.query)
+		if operation != tt.operation || table != tt.table {
+			t.Errorf("parseStatement(%q) = %q, %q, want %q, %q", tt.query, operation, table, tt.operation, tt.table)
+		}
+	}
+}
+
+// Каждый запрос через DB и Tx получает спан с операцией и таблицей, ошибка запроса отмечается в спане
+func TestQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(1)
+	db := NewDB(sqlDB)
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE tasks (id INTEGER PRIMARY KEY, title TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Do(ctx, func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, `INSERT INTO tasks (title) VALUES ($1)`, "Write docs")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var title string
+	if err := db.QueryRowContext(ctx, `SELECT title FROM tasks WHERE id = $1`, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.QueryContext(ctx, `SELECT title FROM missing`); err == nil {
+		t.Fatal("expected query of missing table to fail")
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	want := []string{"CREATE", "INSERT tasks", "SELECT tasks", "SELECT missing"}
+	if len(names) != len(want) {
+		t.Fatalf("expected spans %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected spans %v, got %v", want, names)
+		}
+	}
+
+	attrs := attribute.NewSet(spans[2].Attributes()...)
+	for key, value := range map[attribute.Key]string{
+		"db.system":          "sqlite",
+		"db.operation.name":  "SELECT",
+		"db.collection.name": "tasks",
+		"db.query.text":      "SELECT title FROM tasks WHERE id = $1",
+	} {
+		if got, _ := attrs.Value(key); got.AsString() != value {
+			t.Errorf("expected %s = %q, got %q", key, value, got.AsString())
+		}
+	}
+	if spans[2].Status().Code != codes.Unset || spans[3].Status().Code != codes.Error {
+		t.Fatalf("expected only the failed query to be marked, got %v and %v", spans[2].Status(), spans[3].Status())
+	}
+}
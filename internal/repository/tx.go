@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+/*
+Единица работы: выполняет несколько вызовов репозиториев атомарно
+Репозитории, вызванные с контекстом, переданным в fn, работают в одной транзакции.
+Если fn возвращает ошибку или паникует, все изменения откатываются.
+Вложенный вызов Do присоединяется к уже открытой транзакции
+*/
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+	/*
+		Выполняет fn внутри открытой единицы работы так, что ошибка fn откатывает только её изменения,
+		а внешняя единица работы продолжается. Вне единицы работы ведёт себя как Do
+	*/
+	Savepoint(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Транзакция, открытая единицей работы, передаётся репозиториям через контекст
+type txKey struct{}
+
+type txState struct {
+	db         *sql.DB
+	tx         *sql.Tx
+	savepoints int
+}
+
+func txFrom(ctx context.Context, db *sql.DB) (*txState, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok || state.db != db {
+		return nil, false
+	}
+	return state, true
+}
+
+/*
+Обёртка над *sql.DB, через которую репозитории выполняют запросы
+Если в контексте есть транзакция единицы работы над той же базой, запросы идут в неё.
+Каждый запрос получает спан трассировки
+*/
+type DB struct {
+	db *sql.DB
+	// Значение атрибута db.system в спанах
+	system string
+}
+
+func NewDB(db *sql.DB) *DB {
+	return &DB{db: db, system: dbSystem(db)}
+}
+
+var _ UnitOfWork = (*DB)(nil)
+
+func (d *DB) conn(ctx context.Context) queryer {
+	if state, ok := txFrom(ctx, d.db); ok {
+		return state.tx
+	}
+	return d.db
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tracedExec(ctx, d.conn(ctx), d.system, query, args)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tracedQuery(ctx, d.conn(ctx), d.system, query, args)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tracedQueryRow(ctx, d.conn(ctx), d.system, query, args)
+}
+
+/*
+Открывает транзакцию репозитория
+Внутри единицы работы вместо новой транзакции создаётся точка сохранения,
+так что откат затрагивает только изменения этого вызова
+*/
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	state, ok := txFrom(ctx, d.db)
+	if !ok {
+		tx, err := d.db.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{tx: tx, system: d.system}, nil
+	}
+
+	state.savepoints++
+	savepoint := fmt.Sprintf("sp_%d", state.savepoints)
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return nil, err
+	}
+	return &Tx{tx: state.tx, savepoint: savepoint, system: d.system}, nil
+}
+
+func (d *DB) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := txFrom(ctx, d.db); ok {
+		return fn(ctx)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	ctx, committed := TrackCommit(ctx)
+	if err = fn(context.WithValue(ctx, txKey{}, &txState{db: d.db, tx: tx})); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	committed()
+	return nil
+}
+
+func (d *DB) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := txFrom(ctx, d.db); !ok {
+		return d.Do(ctx, fn)
+	}
+
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// При панике точку сохранения откатывает внешняя транзакция
+	if err := fn(ctx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Транзакция репозитория или точка сохранения внутри единицы работы
+type Tx struct {
+	tx        *sql.Tx
+	savepoint string
+	system    string
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tracedExec(ctx, t.tx, t.system, query, args)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tracedQuery(ctx, t.tx, t.system, query, args)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tracedQueryRow(ctx, t.tx, t.system, query, args)
+}
+
+func (t *Tx) Commit() error {
+	if t.savepoint == "" {
+		return t.tx.Commit()
+	}
+	_, err := t.tx.Exec("RELEASE SAVEPOINT " + t.savepoint)
+	return err
+}
+
+func (t *Tx) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+	_, err := t.tx.Exec("ROLLBACK TO SAVEPOINT " + t.savepoint)
+	return err
+}
+
+// Действия, отложенные до фиксации единицы работы
+type commitHooksKey struct{}
+
+type commitHooks struct {
+	mu   sync.Mutex
+	fns  []func()
+	done bool
+}
+
+/*
+Собирает действия AfterCommit, вызванные с возвращённым контекстом
+Реализация единицы работы вызывает committed после фиксации, при откате действия отбрасываются
+*/
+func TrackCommit(ctx context.Context) (_ context.Context, committed func()) {
+	hooks := &commitHooks{}
+	return context.WithValue(ctx, commitHooksKey{}, hooks), hooks.run
+}
+
+func (h *commitHooks) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns, h.done = nil, true
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+/*
+Выполняет fn после фиксации единицы работы, в которой вызван, а вне единицы работы - сразу
+Действия из откаченной точки сохранения всё равно выполняются, если внешняя единица работы зафиксирована
+*/
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if ok {
+		hooks.mu.Lock()
+		if !hooks.done {
+			hooks.fns = append(hooks.fns, fn)
+			hooks.mu.Unlock()
+			return
+		}
+		hooks.mu.Unlock()
+	}
+	fn()
+}
@@ -1,29 +1,34 @@
 package user_repo
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
 )
 
 type UserPostgrtesRepo struct {
-	db *sql.DB
+	db      *repository.DB
+	timeout time.Duration
 }
 
-func NewUserPostgresRepo(db *sql.DB) *UserPostgrtesRepo {
-	return &UserPostgrtesRepo{db: db}
+func NewUserPostgresRepo(db *sql.DB, timeout time.Duration) *UserPostgrtesRepo {
+	return &UserPostgrtesRepo{db: repository.NewDB(db), timeout: timeout}
 }
 
-func (r *UserPostgrtesRepo) Create(user *models.User) error {
+func (r *UserPostgrtesRepo) Create(ctx context.Context, user *models.User) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		INSERT INTO users (email, password_hash, created_at)
 		VALUES ($1, $2, $3)
 		RETURNING id
 	`
 
-	err := r.db.QueryRow(
+	err = r.db.QueryRowContext(ctx,
 		query,
 		user.Email,
 		user.PasswordHash,
@@ -31,22 +36,25 @@ func (r *UserPostgrtesRepo) Create(user *models.User) error {
 	).Scan(&user.ID)
 
 	if err != nil {
-		return err
+		return repository.TranslatePQ(err, "user")
 	}
 
 	return nil
 }
 
-func (r *UserPostgrtesRepo) GetById(id int) (*models.User, error) {
+func (r *UserPostgrtesRepo) GetById(ctx context.Context, id int) (_ *models.User, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		SELECT id, email, password_hash, created_at
 		FROM users
 		WHERE id = $1
 	`
 
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id)
 	user := &models.User{}
-	err := row.Scan(
+	err = row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
@@ -55,7 +63,7 @@ func (r *UserPostgrtesRepo) GetById(id int) (*models.User, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, repository.NotFound("user")
 		}
 		return nil, err
 	}
@@ -63,16 +71,19 @@ func (r *UserPostgrtesRepo) GetById(id int) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserPostgrtesRepo) GetByEmail(email string) (*models.User, error) {
+func (r *UserPostgrtesRepo) GetByEmail(ctx context.Context, email string) (_ *models.User, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
 		SELECT id, email, password_hash, created_at
 		FROM users
 		WHERE email = $1
 	`
 
-	row := r.db.QueryRow(query, email)
+	row := r.db.QueryRowContext(ctx, query, email)
 	user := &models.User{}
-	err := row.Scan(
+	err = row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
@@ -81,7 +92,7 @@ func (r *UserPostgrtesRepo) GetByEmail(email string) (*models.User, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, repository.NotFound("user")
 		}
 		return nil, err
 	}
@@ -89,7 +100,10 @@ func (r *UserPostgrtesRepo) GetByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserPostgrtesRepo) Update(user *models.User) error {
+func (r *UserPostgrtesRepo) Update(ctx context.Context, user *models.User) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `
         UPDATE users
         SET email = COALESCE(NULLIF($1, ''), email),
@@ -97,18 +111,27 @@ func (r *UserPostgrtesRepo) Update(user *models.User) error {
         WHERE id = $3
     `
 
-	_, err := r.db.Exec(
+	result, err := r.db.ExecContext(ctx,
 		query,
 		user.Email,
 		user.PasswordHash,
 		user.ID,
 	)
 
-	return err
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "user")
 }
 
-func (r *UserPostgrtesRepo) Delete(id int) error {
+func (r *UserPostgrtesRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
 	query := `DELETE FROM users WHERE id = $1`
-	_, err := r.db.Exec(query, id)
-	return err
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "user")
 }
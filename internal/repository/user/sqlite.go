@@ -0,0 +1,124 @@
+package user_repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+type UserSQLiteRepo struct {
+	db      *repository.DB
+	timeout time.Duration
+}
+
+func NewUserSQLiteRepo(db *sql.DB, timeout time.Duration) *UserSQLiteRepo {
+	return &UserSQLiteRepo{db: repository.NewDB(db), timeout: timeout}
+}
+
+func (r *UserSQLiteRepo) Create(ctx context.Context, user *models.User) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		INSERT INTO users (email, password_hash, created_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	err = r.db.QueryRowContext(ctx,
+		query,
+		user.Email,
+		user.PasswordHash,
+		time.Now().UTC(),
+	).Scan(&user.ID)
+
+	if err != nil {
+		return repository.TranslateSQLite(err, "user")
+	}
+
+	return nil
+}
+
+func (r *UserSQLiteRepo) GetById(ctx context.Context, id int) (_ *models.User, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, email, password_hash, created_at
+		FROM users
+		WHERE id = $1
+	`
+
+	return r.get(ctx, query, id)
+}
+
+func (r *UserSQLiteRepo) GetByEmail(ctx context.Context, email string) (_ *models.User, err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		SELECT id, email, password_hash, created_at
+		FROM users
+		WHERE email = $1
+	`
+
+	return r.get(ctx, query, email)
+}
+
+func (r *UserSQLiteRepo) get(ctx context.Context, query string, arg interface{}) (*models.User, error) {
+	user := &models.User{}
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.NotFound("user")
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *UserSQLiteRepo) Update(ctx context.Context, user *models.User) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `
+		UPDATE users
+		SET email = COALESCE(NULLIF($1, ''), email),
+			password_hash = COALESCE(NULLIF($2, ''), password_hash)
+		WHERE id = $3
+	`
+
+	result, err := r.db.ExecContext(ctx,
+		query,
+		user.Email,
+		user.PasswordHash,
+		user.ID,
+	)
+
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "user")
+}
+
+func (r *UserSQLiteRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := repository.WithTimeout(ctx, r.timeout)
+	defer done(&err)
+
+	query := `DELETE FROM users WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return repository.CheckAffected(result, "user")
+}
@@ -0,0 +1,1258 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/handlers/api"
+	"github.com/CAATHARSIS/task-tracking/internal/health"
+	"github.com/CAATHARSIS/task-tracking/internal/logging"
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/ratelimit"
+	memory_repo "github.com/CAATHARSIS/task-tracking/internal/repository/memory"
+	"github.com/CAATHARSIS/task-tracking/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAPIAuth(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+
+	rec := s.api(http.MethodPost, "/api/auth/register", "", map[string]string{
+		"email":    "alice@example.com",
+		"password": "secret123",
+	})
+	expectError(t, rec, http.StatusConflict, "conflict")
+
+	rec = s.api(http.MethodPost, "/api/auth/register", "", map[string]string{"email": "bob"})
+	body := expectError(t, rec, http.StatusBadRequest, "validation_failed")
+	if len(body.Details) != 2 {
+		t.Fatalf("expected details for email and password, got %+v", body.Details)
+	}
+
+	rec = s.api(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    "alice@example.com",
+		"password": "wrong-password",
+	})
+	expectError(t, rec, http.StatusUnauthorized, "unauthorized")
+
+	rec = s.api(http.MethodGet, "/api/users/1", "", nil)
+	expectError(t, rec, http.StatusUnauthorized, "unauthorized")
+
+	rec = s.api(http.MethodGet, "/api/users/1", "not-a-token", nil)
+	expectError(t, rec, http.StatusUnauthorized, "unauthorized")
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/users/%d", userID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var user models.User
+	decode(t, rec, &user)
+	if user.Email != "alice@example.com" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+
+	// Cookie веб-интерфейса тоже принимается API
+	req := s.page(http.MethodGet, fmt.Sprintf("/api/users/%d", userID), token, nil)
+	expectStatus(t, req, http.StatusOK)
+
+	rec = s.api(http.MethodPut, fmt.Sprintf("/api/users/%d", userID), token, map[string]string{
+		"email": "alice@example.org",
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.api(http.MethodPost, "/api/auth/login", "", map[string]string{
+		"email":    "alice@example.org",
+		"password": "secret123",
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.api(http.MethodDelete, fmt.Sprintf("/api/users/%d", userID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/users/%d", userID), token, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+}
+
+func TestAPIErrorEnvelope(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	req := s.api(http.MethodGet, "/api/tasks/999", token, nil)
+	body := expectError(t, req, http.StatusNotFound, "not_found")
+	if body.RequestID == "" {
+		t.Fatal("expected request_id in error body")
+	}
+	if req.Header().Get("X-Request-ID") != body.RequestID {
+		t.Fatalf("X-Request-ID %q does not match request_id %q", req.Header().Get("X-Request-ID"), body.RequestID)
+	}
+
+	rec := s.api(http.MethodGet, "/api/tasks/abc", token, nil)
+	expectError(t, rec, http.StatusBadRequest, "bad_request")
+
+	rec = s.api(http.MethodGet, "/api/no-such-route", token, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+}
+
+// Запись журнала о запросе получает идентификатор из X-Request-ID и пользователя
+// Соединение оборвалось во время выгрузки CSV: ответ уже начат, ошибка попадает в журнал
+func TestAPITimeReportCSVWriteError(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	task := createTask(t, s, token, "Tracked", "todo")
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/tasks/%d/worklogs", task.ID), token, map[string]interface{}{
+		"started_at": time.Now().Add(-time.Hour),
+		"minutes":    30,
+	})
+	expectStatus(t, rec, http.StatusCreated)
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reports/time?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	s.router.ServeHTTP(brokenWriter{httptest.NewRecorder()}, req)
+
+	expected := `"msg":"failed to write CSV report","error":"connection reset by peer"`
+	if !bytes.Contains(buf.Bytes(), []byte(expected)) {
+		t.Fatalf("expected write error in the log, got %s", buf.String())
+	}
+}
+
+// Ответ, запись которого завершается ошибкой, как при закрытом клиентом соединении
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w brokenWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestAPIAccessLog(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	rec := s.apiWithHeader(http.MethodGet, "/api/tasks/999", token, map[string]string{"X-Request-ID": "trace-42"}, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+	if rec.Header().Get("X-Request-ID") != "trace-42" {
+		t.Fatalf("expected X-Request-ID to be echoed, got %q", rec.Header().Get("X-Request-ID"))
+	}
+
+	var entry struct {
+		Msg       string `json:"msg"`
+		RequestID string `json:"request_id"`
+		Route     string `json:"route"`
+		Status    int    `json:"status"`
+		UserID    int    `json:"user_id"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if entry.Msg != "request" || entry.RequestID != "trace-42" || entry.Route != "/api/tasks/:id" ||
+		entry.Status != http.StatusNotFound || entry.UserID != userID {
+		t.Fatalf("unexpected access log record %+v", entry)
+	}
+}
+
+// Метрики закрыты basic auth и считают запросы по шаблону маршрута, входы, созданные задачи и смены статусов
+// Спан запроса продолжает трассу из заголовка traceparent, а запись журнала получает её идентификатор
+func TestHealthProbes(t *testing.T) {
+	s := newTestServer(t)
+
+	readiness := func(status int) health.Report {
+		t.Helper()
+		rec := s.serve(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		expectStatus(t, rec, status)
+		var report health.Report
+		decode(t, rec, &report)
+		return report
+	}
+
+	rec := s.serve(httptest.NewRequest(http.MethodGet, "/livez", nil))
+	expectStatus(t, rec, http.StatusOK)
+
+	s.checker.Add("database", func(context.Context) (map[string]any, error) {
+		return map[string]any{"version": 3}, nil
+	})
+	report := readiness(http.StatusOK)
+	if report.Status != health.StatusOK || report.Checks["database"].Status != health.StatusOK {
+		t.Fatalf("expected ready report, got %+v", report)
+	}
+
+	s.checker.Add("redis", func(ctx context.Context) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	report = readiness(http.StatusServiceUnavailable)
+	if report.Status != health.StatusFailed {
+		t.Errorf("expected failed report, got %q", report.Status)
+	}
+	if check := report.Checks["redis"]; check.Status != health.StatusFailed || check.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("expected redis check to time out, got %+v", check)
+	}
+	if report.Checks["database"].Status != health.StatusOK {
+		t.Errorf("expected database check to pass, got %+v", report.Checks["database"])
+	}
+
+	s.checker.Shutdown()
+	if report = readiness(http.StatusServiceUnavailable); report.Status != health.StatusShuttingDown {
+		t.Errorf("expected shutting down report, got %q", report.Status)
+	}
+	// Процесс жив до конца остановки
+	rec = s.serve(httptest.NewRequest(http.MethodGet, "/livez", nil))
+	expectStatus(t, rec, http.StatusOK)
+}
+
+func TestAPITraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	tracing.Install(1, sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousLogger := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previousLogger) })
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	rec := s.apiWithHeader(http.MethodGet, "/api/tasks/999", token, map[string]string{
+		"traceparent": "00-" + traceID + "-" + spanID + "-01",
+	}, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "/api/tasks/:id" {
+			server = span
+		}
+	}
+	if server == nil {
+		t.Fatal("expected span for /api/tasks/:id")
+	}
+	if server.SpanContext().TraceID().String() != traceID || server.Parent().SpanID().String() != spanID {
+		t.Fatalf("expected span to continue trace %s from %s, got %s from %s",
+			traceID, spanID, server.SpanContext().TraceID(), server.Parent().SpanID())
+	}
+
+	var entry struct {
+		TraceID string `json:"trace_id"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if entry.TraceID != traceID {
+		t.Fatalf("expected access log in trace %s, got %q", traceID, entry.TraceID)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	failed := testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginFailed))
+	succeeded := testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginSucceeded))
+	created := testutil.ToFloat64(metrics.TasksCreated)
+	transitions := testutil.ToFloat64(metrics.TaskStatusTransitions.WithLabelValues("todo", "done"))
+
+	rec := s.api(http.MethodPost, "/api/auth/login", "", map[string]string{"email": "alice@example.com", "password": "wrong-password"})
+	expectError(t, rec, http.StatusUnauthorized, "unauthorized")
+	rec = s.api(http.MethodPost, "/api/auth/login", "", map[string]string{"email": "alice@example.com", "password": "secret123"})
+	expectStatus(t, rec, http.StatusOK)
+
+	task := createTask(t, s, token, "Measured task", "todo")
+	rec = s.apiIfMatch(http.MethodPatch, fmt.Sprintf("/api/tasks/%d/status", task.ID), token, etag(task.Version), map[string]string{"status": "done"})
+	expectStatus(t, rec, http.StatusOK)
+
+	for _, tt := range []struct {
+		name  string
+		got   float64
+		delta float64
+	}{
+		{"failed logins", testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginFailed)) - failed, 1},
+		{"succeeded logins", testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginSucceeded)) - succeeded, 1},
+		{"created tasks", testutil.ToFloat64(metrics.TasksCreated) - created, 1},
+		{"status transitions", testutil.ToFloat64(metrics.TaskStatusTransitions.WithLabelValues("todo", "done")) - transitions, 1},
+	} {
+		if tt.got != tt.delta {
+			t.Errorf("expected %s to grow by %v, got %v", tt.name, tt.delta, tt.got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	expectStatus(t, s.serve(req), http.StatusUnauthorized)
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.SetBasicAuth(metricsUsername, metricsPassword)
+	rec = s.serve(req)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, `task_tracking_http_request_duration_seconds_count{method="PATCH",route="/api/tasks/:id/status",status="200"}`)
+	expectBody(t, rec, `task_tracking_logins_total{result="failed"}`)
+}
+
+func TestAPITasks(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+
+	rec := s.api(http.MethodPost, "/api/tasks", token, map[string]interface{}{
+		"title":    "Write tests",
+		"status":   "todo",
+		"estimate": 3,
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var task models.Task
+	decode(t, rec, &task)
+	if task.ID == 0 || task.UserID != userID {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	rec = s.api(http.MethodPost, "/api/tasks", token, map[string]interface{}{"title": "x", "status": "todo"})
+	expectError(t, rec, http.StatusBadRequest, "validation_failed")
+
+	rec = s.apiIfMatch(http.MethodPatch, fmt.Sprintf("/api/tasks/%d/status", task.ID), token, etag(task.Version), map[string]string{"status": "done"})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if task.Status != models.StatusDone {
+		t.Fatalf("expected status done, got %q", task.Status)
+	}
+
+	rec = s.apiIfMatch(http.MethodPut, fmt.Sprintf("/api/tasks/%d", task.ID), token, etag(task.Version), map[string]interface{}{
+		"title":       "Write more tests",
+		"description": "HTTP level",
+		"status":      "in_progress",
+		"user_id":     userID,
+	})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if task.Title != "Write more tests" || task.Status != models.StatusInProgres {
+		t.Fatalf("task was not updated: %+v", task)
+	}
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/user/%d", userID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []models.Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 {
+		t.Fatalf("expected one task, got %d", len(tasks))
+	}
+
+	rec = s.api(http.MethodGet, "/api/tasks?search=more&status=in_progress", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 {
+		t.Fatalf("expected filter to match the task, got %d", len(tasks))
+	}
+
+	rec = s.api(http.MethodGet, "/api/tasks?status=todo", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &tasks)
+	if len(tasks) != 0 {
+		t.Fatalf("expected no todo tasks, got %d", len(tasks))
+	}
+
+	rec = s.apiIfMatch(http.MethodDelete, fmt.Sprintf("/api/tasks/%d", task.ID), token, etag(task.Version), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.apiIfMatch(http.MethodDelete, fmt.Sprintf("/api/tasks/%d", task.ID), token, "*", nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+}
+
+func TestAPIBoards(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	board := createBoard(t, s, token, map[string]interface{}{
+		"name":       "Sprint",
+		"wip_limits": map[string]int{"in_progress": 1},
+	})
+	if board.EstimateUnit != models.EstimatePoints {
+		t.Fatalf("expected default estimate unit, got %q", board.EstimateUnit)
+	}
+
+	rec := s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d", board.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var loaded models.Board
+	decode(t, rec, &loaded)
+	if loaded.WIPLimits[models.StatusInProgres] != 1 {
+		t.Fatalf("expected WIP limit to be stored, got %+v", loaded.WIPLimits)
+	}
+
+	rec = s.apiIfMatch(http.MethodPut, fmt.Sprintf("/api/boards/%d", board.ID), token, rec.Header().Get("ETag"), map[string]interface{}{
+		"name":          "Sprint 2",
+		"estimate_unit": "hours",
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/user-tasks", userID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var boards []models.Board
+	decode(t, rec, &boards)
+	if len(boards) != 1 || boards[0].Name != "Sprint 2" {
+		t.Fatalf("unexpected boards %+v", boards)
+	}
+
+	first := createTask(t, s, token, "First task", "in_progress")
+	second := createTask(t, s, token, "Second task", "todo")
+
+	rec = s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, first.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+	rec = s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, second.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/tasks", board.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var boardTasks []api.BoardTaskResponse
+	decode(t, rec, &boardTasks)
+	if len(boardTasks) != 2 || boardTasks[0].ID != second.ID || boardTasks[1].Title != "First task" || boardTasks[0].Board != nil {
+		t.Fatalf("expected two tasks on board, got %+v", boardTasks)
+	}
+
+	// Колонка in_progress уже заполнена
+	rec = s.apiIfMatch(http.MethodPatch, fmt.Sprintf("/api/tasks/%d/status", second.ID), token, etag(second.Version), map[string]string{"status": "in_progress"})
+	expectError(t, rec, http.StatusConflict, "conflict")
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/summary", board.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var summary models.BoardSummary
+	decode(t, rec, &summary)
+	if summary.EstimateUnit != models.EstimateHours || len(summary.Columns) != len(models.TaskStatuses) {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	for _, column := range summary.Columns {
+		if column.Status == models.StatusInProgres && (column.Tasks != 1 || column.WIPLimit != 1) {
+			t.Fatalf("unexpected in_progress column %+v", column)
+		}
+	}
+
+	target := createBoard(t, s, token, map[string]interface{}{"name": "Backlog"})
+	rec = s.api(http.MethodPatch, "/api/boards/tasks/move", token, map[string]int{
+		"from_board_id": board.ID,
+		"to_board_id":   target.ID,
+		"task_id":       second.ID,
+	})
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodDelete, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, first.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/tasks", board.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "[]")
+
+	// Чужая доска недоступна для поиска задач
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks?board_id=%d", target.ID), otherToken, nil)
+	expectError(t, rec, http.StatusForbidden, "forbidden")
+
+	rec = s.apiIfMatch(http.MethodDelete, fmt.Sprintf("/api/boards/%d", board.ID), token, "*", nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d", board.ID), token, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+}
+
+// Переносить можно только свою задачу, которая лежит на исходной доске
+func TestAPIBoardMoveTaskOwnership(t *testing.T) {
+	s := newTestServer(t)
+	_, victimToken := s.signUp("alice@example.com")
+	_, token := s.signUp("bob@example.com")
+
+	victimBoard := createBoard(t, s, victimToken, map[string]interface{}{"name": "Private"})
+	victim := createTask(t, s, victimToken, "Secret", "todo")
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", victimBoard.ID, victim.ID), victimToken, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	from := createBoard(t, s, token, map[string]interface{}{"name": "From"})
+	to := createBoard(t, s, token, map[string]interface{}{"name": "Target"})
+	rec = s.api(http.MethodPatch, "/api/boards/tasks/move", token, map[string]int{
+		"from_board_id": from.ID,
+		"to_board_id":   to.ID,
+		"task_id":       victim.ID,
+	})
+	expectError(t, rec, http.StatusForbidden, "forbidden")
+
+	own := createTask(t, s, token, "Own", "todo")
+	rec = s.api(http.MethodPatch, "/api/boards/tasks/move", token, map[string]int{
+		"from_board_id": from.ID,
+		"to_board_id":   to.ID,
+		"task_id":       own.ID,
+	})
+	expectError(t, rec, http.StatusNotFound, "not_found")
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/tasks", to.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "[]")
+}
+
+// Задачи доски загружаются целиком, expand добавляет доску и метки
+func TestAPIBoardTasksExpand(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	task := createTask(t, s, token, "Labelled task", "todo")
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, task.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/fields", board.ID), token, map[string]interface{}{
+		"name":    "Labels",
+		"type":    "multi_select",
+		"options": []string{"bug", "docs"},
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var field models.CustomField
+	decode(t, rec, &field)
+
+	rec = s.api(http.MethodPut, fmt.Sprintf("/api/tasks/%d/fields", task.ID), token, map[string]interface{}{
+		fmt.Sprint(field.ID): []string{"bug", "docs"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	path := fmt.Sprintf("/api/boards/%d/tasks", board.ID)
+	rec = s.api(http.MethodGet, path, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []api.BoardTaskResponse
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Labelled task" || len(tasks[0].CustomFields) != 1 || tasks[0].Board != nil || tasks[0].Labels != nil {
+		t.Fatalf("unexpected board tasks %+v", tasks)
+	}
+
+	rec = s.api(http.MethodGet, path+"?expand=board,labels", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	tasks = nil
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].Board == nil || tasks[0].Board.Name != "Sprint" || !slices.Equal(tasks[0].Labels, []string{"bug", "docs"}) {
+		t.Fatalf("unexpected expanded board tasks %+v", tasks)
+	}
+
+	rec = s.api(http.MethodGet, path+"?expand=owner", token, nil)
+	expectError(t, rec, http.StatusBadRequest, "bad_request")
+}
+
+// API применяет те же правила доступа, что и веб-интерфейс
+func TestAPIOwnership(t *testing.T) {
+	s := newTestServer(t)
+	aliceID, token := s.signUp("alice@example.com")
+	bobID, otherToken := s.signUp("bob@example.com")
+
+	task := createTask(t, s, token, "Private task", "todo")
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Private"})
+	bobTask := createTask(t, s, otherToken, "Bob's task", "todo")
+
+	taskPath := fmt.Sprintf("/api/tasks/%d", task.ID)
+	boardPath := fmt.Sprintf("/api/boards/%d", board.ID)
+
+	forbidden := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodGet, taskPath, nil},
+		{http.MethodPut, taskPath, map[string]string{"title": "Stolen", "status": "done"}},
+		{http.MethodPatch, taskPath + "/status", map[string]string{"status": "done"}},
+		{http.MethodDelete, taskPath, nil},
+		{http.MethodGet, fmt.Sprintf("/api/tasks/user/%d", aliceID), nil},
+		{http.MethodGet, boardPath, nil},
+		{http.MethodPut, boardPath, map[string]string{"name": "Stolen"}},
+		{http.MethodDelete, boardPath, nil},
+		{http.MethodGet, boardPath + "/summary", nil},
+		{http.MethodGet, boardPath + "/tasks", nil},
+		{http.MethodPost, fmt.Sprintf("%s/tasks/%d", boardPath, bobTask.ID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/boards/%d/user-tasks", aliceID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/users/%d", aliceID), nil},
+		{http.MethodPut, fmt.Sprintf("/api/users/%d", aliceID), map[string]string{"password": "hijacked"}},
+		{http.MethodDelete, fmt.Sprintf("/api/users/%d", aliceID), nil},
+	}
+	for _, req := range forbidden {
+		rec := s.apiIfMatch(req.method, req.path, otherToken, "*", req.body)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected status 403, got %d: %s", req.method, req.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Чужую задачу нельзя добавить на свою доску
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, bobTask.ID), token, nil)
+	expectError(t, rec, http.StatusForbidden, "forbidden")
+
+	// Владелец задачи не меняется при её изменении
+	rec = s.apiIfMatch(http.MethodPut, taskPath, token, etag(task.Version), map[string]interface{}{
+		"title":   "Still mine",
+		"status":  "todo",
+		"user_id": bobID,
+	})
+	expectStatus(t, rec, http.StatusOK)
+	var updated models.Task
+	decode(t, rec, &updated)
+	if updated.UserID != aliceID {
+		t.Fatalf("expected owner to stay %d, got %d", aliceID, updated.UserID)
+	}
+}
+
+// Задачи и доски изменяются только по ETag из последнего ответа
+func TestAPIOptimisticConcurrency(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	rec := s.api(http.MethodPost, "/api/tasks", token, map[string]interface{}{"title": "Shared task", "status": "todo"})
+	expectStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("ETag"); got != `"1"` {
+		t.Fatalf("expected ETag \"1\" on create, got %q", got)
+	}
+	var task models.Task
+	decode(t, rec, &task)
+	taskPath := fmt.Sprintf("/api/tasks/%d", task.ID)
+	update := map[string]interface{}{"title": "First edit", "status": "todo"}
+
+	rec = s.api(http.MethodPut, taskPath, token, update)
+	expectError(t, rec, http.StatusPreconditionRequired, "precondition_required")
+	rec = s.api(http.MethodDelete, taskPath, token, nil)
+	expectError(t, rec, http.StatusPreconditionRequired, "precondition_required")
+	rec = s.apiIfMatch(http.MethodPut, taskPath, token, "1", update)
+	expectError(t, rec, http.StatusBadRequest, "bad_request")
+
+	// Оба клиента прочитали первую версию, второй опоздал
+	rec = s.api(http.MethodGet, taskPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	seen := rec.Header().Get("ETag")
+
+	rec = s.apiIfMatch(http.MethodPut, taskPath, token, seen, update)
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("expected ETag \"2\" after update, got %q", got)
+	}
+
+	rec = s.apiIfMatch(http.MethodPut, taskPath, token, seen, map[string]interface{}{"title": "Second edit", "status": "done"})
+	expectError(t, rec, http.StatusPreconditionFailed, "precondition_failed")
+	rec = s.apiIfMatch(http.MethodPatch, taskPath+"/status", token, seen, map[string]string{"status": "done"})
+	expectError(t, rec, http.StatusPreconditionFailed, "precondition_failed")
+	rec = s.apiIfMatch(http.MethodDelete, taskPath, token, seen, nil)
+	expectError(t, rec, http.StatusPreconditionFailed, "precondition_failed")
+
+	rec = s.api(http.MethodGet, taskPath, token, nil)
+	decode(t, rec, &task)
+	if task.Title != "First edit" || task.Status != models.StatusToDo || task.Version != 2 {
+		t.Fatalf("stale write changed task %+v", task)
+	}
+
+	// Слабый ETag сравнивается так же, как сильный
+	rec = s.apiIfMatch(http.MethodPatch, taskPath+"/status", token, "W/"+etag(task.Version), map[string]string{"status": "done"})
+	expectStatus(t, rec, http.StatusOK)
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	boardPath := fmt.Sprintf("/api/boards/%d", board.ID)
+	rec = s.apiIfMatch(http.MethodPut, boardPath, token, etag(board.Version), map[string]interface{}{"name": "Sprint 2"})
+	expectStatus(t, rec, http.StatusOK)
+	rec = s.apiIfMatch(http.MethodPut, boardPath, token, etag(board.Version), map[string]interface{}{"name": "Sprint 3"})
+	expectError(t, rec, http.StatusPreconditionFailed, "precondition_failed")
+	rec = s.apiIfMatch(http.MethodDelete, boardPath, token, etag(board.Version), nil)
+	expectError(t, rec, http.StatusPreconditionFailed, "precondition_failed")
+	rec = s.apiIfMatch(http.MethodDelete, boardPath, token, etag(board.Version+1), nil)
+	expectStatus(t, rec, http.StatusNoContent)
+}
+
+func TestAPIMergePatch(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+	bobID, _ := s.signUp("bob@example.com")
+
+	rec := s.api(http.MethodPost, "/api/tasks", token, map[string]interface{}{
+		"title":       "Write tests",
+		"description": "HTTP level",
+		"status":      "todo",
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var task models.Task
+	decode(t, rec, &task)
+	taskPath := fmt.Sprintf("/api/tasks/%d", task.ID)
+
+	rec = s.api(http.MethodPatch, taskPath, token, map[string]string{"status": "done"})
+	expectError(t, rec, http.StatusPreconditionRequired, "precondition_required")
+	rec = s.apiIfMatch(http.MethodPatch, taskPath, token, etag(task.Version), []string{"status"})
+	expectError(t, rec, http.StatusBadRequest, "bad_request")
+
+	rec = s.apiIfMatch(http.MethodPatch, taskPath, token, etag(task.Version), map[string]interface{}{"user_id": bobID})
+	body := expectError(t, rec, http.StatusBadRequest, "validation_failed")
+	if len(body.Details) != 1 || body.Details[0].Field != "user_id" || body.Details[0].Rule != "read_only" {
+		t.Fatalf("expected read_only error for user_id, got %+v", body.Details)
+	}
+
+	rec = s.apiIfMatch(http.MethodPatch, taskPath, token, etag(task.Version), map[string]string{"status": "done"})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if task.Status != models.StatusDone || task.Description != "HTTP level" || task.UserID != userID {
+		t.Fatalf("unexpected task after patch %+v", task)
+	}
+	if rec.Header().Get("ETag") != etag(task.Version) {
+		t.Fatalf("expected ETag %s, got %q", etag(task.Version), rec.Header().Get("ETag"))
+	}
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint", "wip_limits": map[string]int{"todo": 3}})
+	rec = s.apiIfMatch(http.MethodPatch, fmt.Sprintf("/api/boards/%d", board.ID), token, etag(board.Version), map[string]interface{}{
+		"wip_limits": map[string]interface{}{"todo": nil, "in_progress": 1},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	var patched models.Board
+	decode(t, rec, &patched)
+	if patched.Name != "Sprint" || len(patched.WIPLimits) != 1 || patched.WIPLimits[models.StatusInProgres] != 1 {
+		t.Fatalf("unexpected board after patch %+v", patched)
+	}
+}
+
+func TestAPIBulkTasks(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint", "wip_limits": map[string]int{"in_progress": 1}})
+	first := createTask(t, s, token, "First task", "todo")
+	second := createTask(t, s, token, "Second task", "todo")
+	foreign := createTask(t, s, otherToken, "Foreign task", "todo")
+
+	rec := s.api(http.MethodPost, "/api/tasks/bulk", token, map[string]interface{}{"operations": []interface{}{}})
+	expectError(t, rec, http.StatusBadRequest, "validation_failed")
+
+	// Вторая задача в работе превышает WIP-лимит доски, чужая задача недоступна
+	rec = s.api(http.MethodPost, "/api/tasks/bulk", token, map[string]interface{}{
+		"mode": "best_effort",
+		"operations": []map[string]interface{}{
+			{"op": "add_to_board", "task_id": first.ID, "board_id": board.ID},
+			{"op": "add_to_board", "task_id": second.ID, "board_id": board.ID},
+			{"op": "set_status", "task_id": first.ID, "status": "in_progress", "version": first.Version},
+			{"op": "set_status", "task_id": second.ID, "status": "in_progress"},
+			{"op": "delete", "task_id": foreign.ID},
+		},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	var result api.BulkTaskResponse
+	decode(t, rec, &result)
+	if !result.Committed || result.Succeeded != 3 || result.Failed != 2 {
+		t.Fatalf("unexpected bulk result %+v", result)
+	}
+	for i, code := range []apperr.Code{"", "", "", apperr.CodeConflict, apperr.CodeForbidden} {
+		item := result.Results[i]
+		if code == "" && (item.Status != models.BulkItemOK || item.Error != nil) {
+			t.Fatalf("expected operation %d to succeed, got %+v", i, item)
+		}
+		if code != "" && (item.Status != models.BulkItemFailed || item.Error == nil || item.Error.Code != code) {
+			t.Fatalf("expected operation %d to fail with %s, got %+v", i, code, item)
+		}
+	}
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/%d", first.ID), token, nil)
+	var task models.Task
+	decode(t, rec, &task)
+	if task.Status != models.StatusInProgres {
+		t.Fatalf("expected first task to be in progress, got %s", task.Status)
+	}
+
+	// Ошибка в режиме atomic откатывает уже выполненные операции
+	rec = s.api(http.MethodPost, "/api/tasks/bulk", token, map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "delete", "task_id": second.ID},
+			{"op": "set_status", "task_id": first.ID, "status": "done", "version": first.Version},
+		},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	result = api.BulkTaskResponse{}
+	decode(t, rec, &result)
+	if result.Committed || result.Results[0].Status != models.BulkItemRolledBack || result.Results[1].Error.Code != apperr.CodePreconditionFailed {
+		t.Fatalf("unexpected atomic bulk result %+v", result)
+	}
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/%d", second.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	// Перенос проверяет владельца задачи так же, как одиночный запрос
+	target := createBoard(t, s, token, map[string]interface{}{"name": "Backlog"})
+	rec = s.api(http.MethodPost, "/api/tasks/bulk", token, map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "move", "task_id": foreign.ID, "from_board_id": board.ID, "to_board_id": target.ID},
+		},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	result = api.BulkTaskResponse{}
+	decode(t, rec, &result)
+	if result.Committed || result.Results[0].Error == nil || result.Results[0].Error.Code != apperr.CodeForbidden {
+		t.Fatalf("expected foreign task move to be forbidden, got %+v", result)
+	}
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/tasks", target.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "[]")
+}
+
+func TestAPIIdempotencyKeys(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	withKey := func(token, key, method, path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.apiWithHeader(method, path, token, map[string]string{"Idempotency-Key": key}, body)
+	}
+	countTasks := func(token string) int {
+		t.Helper()
+		rec := s.api(http.MethodGet, "/api/tasks", token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var tasks []models.Task
+		decode(t, rec, &tasks)
+		return len(tasks)
+	}
+
+	request := map[string]string{"title": "Buy milk", "status": "todo"}
+	first := withKey(token, "create-1", http.MethodPost, "/api/tasks", request)
+	expectStatus(t, first, http.StatusCreated)
+	retry := withKey(token, "create-1", http.MethodPost, "/api/tasks", request)
+	expectStatus(t, retry, http.StatusCreated)
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Fatalf("expected stored response to be replayed, got %s", retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatal("expected only the retry to be marked as replayed")
+	}
+	if n := countTasks(token); n != 1 {
+		t.Fatalf("expected retry not to create a task, got %d tasks", n)
+	}
+
+	rec := withKey(token, "create-1", http.MethodPost, "/api/tasks", map[string]string{"title": "Buy bread", "status": "todo"})
+	expectError(t, rec, http.StatusUnprocessableEntity, "idempotency_key_reused")
+
+	// Ключи принадлежат пользователю
+	rec = withKey(otherToken, "create-1", http.MethodPost, "/api/tasks", request)
+	expectStatus(t, rec, http.StatusCreated)
+	if n := countTasks(otherToken); n != 1 {
+		t.Fatalf("expected another user's key to create a task, got %d tasks", n)
+	}
+
+	// Ошибка тоже сохраняется и повторяется
+	invalid := map[string]string{"title": "", "status": "todo"}
+	expectError(t, withKey(token, "create-2", http.MethodPost, "/api/tasks", invalid), http.StatusBadRequest, "validation_failed")
+	expectError(t, withKey(token, "create-2", http.MethodPost, "/api/tasks", invalid), http.StatusBadRequest, "validation_failed")
+
+	var task models.Task
+	decode(t, first, &task)
+	path := fmt.Sprintf("/api/tasks/%d", task.ID)
+	deleteTask := func() *httptest.ResponseRecorder {
+		return s.apiWithHeader(http.MethodDelete, path, token, map[string]string{"Idempotency-Key": "delete-1", "If-Match": etag(task.Version)}, nil)
+	}
+	expectStatus(t, deleteTask(), http.StatusNoContent)
+	expectStatus(t, deleteTask(), http.StatusNoContent)
+
+	// Тело запроса с ключом читается в память, поэтому его размер ограничен
+	large := map[string]string{"title": "Large", "status": "todo", "description": strings.Repeat("x", int(testIdempotency.MaxBodySize))}
+	expectError(t, withKey(token, "create-3", http.MethodPost, "/api/tasks", large), http.StatusRequestEntityTooLarge, "payload_too_large")
+
+	// Без ключа запрос выполняется каждый раз
+	expectStatus(t, s.api(http.MethodPost, "/api/tasks", token, request), http.StatusCreated)
+	expectStatus(t, s.api(http.MethodPost, "/api/tasks", token, request), http.StatusCreated)
+	if n := countTasks(token); n != 2 {
+		t.Fatalf("expected two tasks, got %d", n)
+	}
+}
+
+func TestAPIRateLimits(t *testing.T) {
+	s := newLimitedTestServer(t, middleware.RateLimits{
+		Store:      ratelimit.NewMemoryStore(),
+		AuthIP:     ratelimit.Rule{Limit: 8, Window: time.Minute},
+		LoginEmail: ratelimit.Rule{Limit: 3, Window: 15 * time.Minute},
+		APIUser:    ratelimit.Rule{Limit: 3, Window: time.Minute},
+	})
+	_, aliceToken := s.signUp("alice@example.com")
+	_, bobToken := s.signUp("bob@example.com")
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.api(http.MethodPost, "/api/auth/login", "", map[string]string{"email": email, "password": password})
+	}
+	expectLimitHeaders := func(rec *httptest.ResponseRecorder, limit, remaining string) {
+		t.Helper()
+		if rec.Header().Get("RateLimit-Limit") != limit || rec.Header().Get("RateLimit-Remaining") != remaining {
+			t.Fatalf("expected rate limit %s with %s remaining, got %s with %s remaining",
+				limit, remaining, rec.Header().Get("RateLimit-Limit"), rec.Header().Get("RateLimit-Remaining"))
+		}
+		if rec.Header().Get("RateLimit-Reset") == "" {
+			t.Fatal("expected RateLimit-Reset header")
+		}
+	}
+
+	// Попытки входа считаются по email без учёта регистра и пробелов
+	expectError(t, login("alice@example.com", "wrong"), http.StatusUnauthorized, "unauthorized")
+	expectError(t, login("alice@example.com", "wrong"), http.StatusUnauthorized, "unauthorized")
+	rec := login(" Alice@Example.com", "secret123")
+	expectError(t, rec, http.StatusTooManyRequests, "too_many_requests")
+	expectLimitHeaders(rec, "3", "0")
+	if retry := rec.Header().Get("Retry-After"); retry == "" || retry == "0" {
+		t.Fatalf("expected Retry-After header, got %q", retry)
+	}
+
+	// Заголовки показывают самое строгое правило: у IP-адреса осталось меньше попыток, чем у email
+	rec = login("bob@example.com", "secret123")
+	expectStatus(t, rec, http.StatusOK)
+	expectLimitHeaders(rec, "8", "0")
+
+	rec = s.api(http.MethodPost, "/api/auth/register", "", map[string]string{"email": "carol@example.com", "password": "secret123"})
+	expectError(t, rec, http.StatusTooManyRequests, "too_many_requests")
+
+	// Без доверенных прокси X-Forwarded-For не меняет адрес клиента, и подмена заголовка не сбрасывает лимит
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		rec = s.apiWithHeader(http.MethodPost, "/api/auth/register", "", map[string]string{header: "203.0.113.7"},
+			map[string]string{"email": "carol@example.com", "password": "secret123"})
+		expectError(t, rec, http.StatusTooManyRequests, "too_many_requests")
+	}
+
+	// Запросы к API считаются по пользователю
+	for remaining := 2; remaining >= 0; remaining-- {
+		rec = s.api(http.MethodGet, "/api/tasks", aliceToken, nil)
+		expectStatus(t, rec, http.StatusOK)
+		expectLimitHeaders(rec, "3", fmt.Sprint(remaining))
+	}
+	expectError(t, s.api(http.MethodGet, "/api/tasks", aliceToken, nil), http.StatusTooManyRequests, "too_many_requests")
+	expectStatus(t, s.api(http.MethodGet, "/api/tasks", bobToken, nil), http.StatusOK)
+}
+
+func TestAPICustomFields(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	task := createTask(t, s, token, "Labelled task", "todo")
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, task.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	fieldsPath := fmt.Sprintf("/api/boards/%d/fields", board.ID)
+	rec = s.api(http.MethodPost, fieldsPath, token, map[string]interface{}{
+		"name":    "Priority",
+		"type":    "single_select",
+		"options": []string{"low", "high"},
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var field models.CustomField
+	decode(t, rec, &field)
+
+	rec = s.api(http.MethodPost, fieldsPath, token, map[string]interface{}{"name": "Priority", "type": "text"})
+	expectError(t, rec, http.StatusConflict, "conflict")
+
+	rec = s.api(http.MethodPost, fieldsPath, otherToken, map[string]interface{}{"name": "Size", "type": "number"})
+	expectError(t, rec, http.StatusForbidden, "forbidden")
+
+	rec = s.api(http.MethodGet, fieldsPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var fields []models.CustomField
+	decode(t, rec, &fields)
+	if len(fields) != 1 {
+		t.Fatalf("expected one field, got %d", len(fields))
+	}
+
+	valuesPath := fmt.Sprintf("/api/tasks/%d/fields", task.ID)
+	rec = s.api(http.MethodPut, valuesPath, token, map[string]string{fmt.Sprint(field.ID): "urgent"})
+	expectError(t, rec, http.StatusBadRequest, "validation_failed")
+
+	rec = s.api(http.MethodPut, valuesPath, token, map[string]string{fmt.Sprint(field.ID): "high"})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if len(task.CustomFields) != 1 || task.CustomFields[0].Display() != "high" {
+		t.Fatalf("unexpected custom fields %+v", task.CustomFields)
+	}
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks?board_id=%d&field.%d=high", board.ID, field.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []models.Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 {
+		t.Fatalf("expected field filter to match the task, got %d", len(tasks))
+	}
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks?board_id=%d&field.%d=low", board.ID, field.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &tasks)
+	if len(tasks) != 0 {
+		t.Fatalf("expected no tasks with low priority, got %d", len(tasks))
+	}
+
+	// Переименование поля сбрасывает закэшированный список задач доски со старым именем
+	listFieldNames := func() []string {
+		t.Helper()
+		rec := s.api(http.MethodGet, fmt.Sprintf("/api/boards/%d/tasks", board.ID), token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		decode(t, rec, &tasks)
+		var names []string
+		for _, task := range tasks {
+			for _, value := range task.CustomFields {
+				names = append(names, value.Name)
+			}
+		}
+		return names
+	}
+	if names := listFieldNames(); !slices.Equal(names, []string{"Priority"}) {
+		t.Fatalf("expected field name in the task list, got %v", names)
+	}
+	rec = s.api(http.MethodPut, fmt.Sprintf("%s/%d", fieldsPath, field.ID), token, map[string]interface{}{
+		"name":    "Importance",
+		"type":    "single_select",
+		"options": []string{"low", "high"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	if names := listFieldNames(); !slices.Equal(names, []string{"Importance"}) {
+		t.Fatalf("expected renamed field in the task list, got %v", names)
+	}
+
+	rec = s.api(http.MethodDelete, fmt.Sprintf("%s/%d", fieldsPath, field.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/%d", task.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	task = models.Task{}
+	decode(t, rec, &task)
+	if len(task.CustomFields) != 0 {
+		t.Fatalf("expected values of deleted field to be removed, got %+v", task.CustomFields)
+	}
+}
+
+func TestAPISavedViews(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	task := createTask(t, s, token, "Shared task", "todo")
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, task.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodPost, "/api/views", token, map[string]interface{}{
+		"name":   "Sprint todo",
+		"filter": map[string]interface{}{"board_id": board.ID, "statuses": []string{"todo"}},
+		"pinned": true,
+		"shared": true,
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var view models.SavedView
+	decode(t, rec, &view)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/views/%d/tasks", view.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []models.Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].ID != task.ID {
+		t.Fatalf("unexpected view tasks %+v", tasks)
+	}
+
+	// Другой пользователь не участник доски и не видит расшаренное представление
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/views/%d", view.ID), otherToken, nil)
+	expectError(t, rec, http.StatusForbidden, "forbidden")
+
+	rec = s.api(http.MethodPut, fmt.Sprintf("/api/views/%d", view.ID), otherToken, map[string]interface{}{"name": "Hijacked"})
+	expectError(t, rec, http.StatusForbidden, "forbidden")
+
+	rec = s.api(http.MethodGet, "/api/views", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var views []models.SavedView
+	decode(t, rec, &views)
+	if len(views) != 1 {
+		t.Fatalf("expected one view, got %d", len(views))
+	}
+
+	rec = s.api(http.MethodDelete, fmt.Sprintf("/api/views/%d", view.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/views/%d", view.ID), token, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+}
+
+// Владелец представления, которого убрали с доски из фильтра, больше не видит её задачи через представление
+func TestAPISavedViewRequiresBoardMembership(t *testing.T) {
+	s := newTestServer(t)
+	_, ownerToken := s.signUp("alice@example.com")
+	_, memberToken := s.signUp("bob@example.com")
+
+	board := createBoard(t, s, ownerToken, map[string]interface{}{"name": "Sprint"})
+	ownerTask := createTask(t, s, ownerToken, "Owner task", "todo")
+	expectStatus(t, s.api(http.MethodPost, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, ownerTask.ID), ownerToken, nil), http.StatusNoContent)
+	// Через API задачу можно добавить только на свою доску, участником bob становится через хранилище
+	memberTask := createTask(t, s, memberToken, "Member task", "todo")
+	if err := memory_repo.NewBoardTaskMemoryRepo(s.store).AddTask(context.Background(), board.ID, memberTask.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := s.api(http.MethodPost, "/api/views", memberToken, map[string]interface{}{
+		"name":   "Sprint",
+		"filter": map[string]interface{}{"board_id": board.ID},
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var view models.SavedView
+	decode(t, rec, &view)
+	viewTasksPath := fmt.Sprintf("/api/views/%d/tasks", view.ID)
+	expectStatus(t, s.api(http.MethodGet, viewTasksPath, memberToken, nil), http.StatusOK)
+
+	expectStatus(t, s.api(http.MethodDelete, fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, memberTask.ID), ownerToken, nil), http.StatusNoContent)
+
+	expectError(t, s.api(http.MethodGet, fmt.Sprintf("/api/tasks?board_id=%d", board.ID), memberToken, nil), http.StatusForbidden, "forbidden")
+	expectError(t, s.api(http.MethodGet, viewTasksPath, memberToken, nil), http.StatusForbidden, "forbidden")
+	expectStatus(t, s.page(http.MethodGet, fmt.Sprintf("/views/%d", view.ID), memberToken, nil), http.StatusForbidden)
+	// Само представление владелец по-прежнему видит и может удалить
+	expectStatus(t, s.api(http.MethodGet, fmt.Sprintf("/api/views/%d", view.ID), memberToken, nil), http.StatusOK)
+}
+
+func TestAPITimeTracking(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+	task := createTask(t, s, token, "Tracked task", "todo")
+
+	rec := s.api(http.MethodGet, "/api/timer", token, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+
+	rec = s.api(http.MethodPost, fmt.Sprintf("/api/tasks/%d/timer/start", task.ID), token, nil)
+	expectStatus(t, rec, http.StatusCreated)
+
+	rec = s.api(http.MethodPost, fmt.Sprintf("/api/tasks/%d/timer/start", task.ID), token, nil)
+	expectError(t, rec, http.StatusConflict, "conflict")
+
+	rec = s.api(http.MethodGet, "/api/timer", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.api(http.MethodPost, "/api/timer/stop", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var stopped models.TimeEntry
+	decode(t, rec, &stopped)
+	if stopped.EndedAt == nil {
+		t.Fatal("expected stopped timer to have ended_at")
+	}
+
+	rec = s.api(http.MethodPost, fmt.Sprintf("/api/tasks/%d/worklogs", task.ID), token, map[string]interface{}{
+		"started_at": time.Now().Add(-2 * time.Hour),
+		"minutes":    30,
+		"note":       "Review",
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var worklog models.TimeEntry
+	decode(t, rec, &worklog)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/%d/worklogs", task.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var entries []models.TimeEntry
+	decode(t, rec, &entries)
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %d", len(entries))
+	}
+
+	for _, path := range []string{
+		fmt.Sprintf("/api/tasks/%d/time", task.ID),
+		fmt.Sprintf("/api/users/%d/time", userID),
+	} {
+		rec = s.api(http.MethodGet, path, token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var total models.TimeTotal
+		decode(t, rec, &total)
+		if total.Seconds < 30*60 {
+			t.Fatalf("%s: expected at least 30 minutes, got %d seconds", path, total.Seconds)
+		}
+	}
+
+	rec = s.api(http.MethodGet, "/api/reports/time", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var report []models.TimeReportRow
+	decode(t, rec, &report)
+	if len(report) == 0 || report[0].TaskID != task.ID {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	rec = s.api(http.MethodGet, "/api/reports/time?format=csv", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "day,task_id,task_title,seconds,hours\n")
+
+	rec = s.api(http.MethodDelete, fmt.Sprintf("/api/worklogs/%d", worklog.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+}
+
+func TestAPIRecurrence(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	task := createTask(t, s, token, "Weekly report", "todo")
+
+	path := fmt.Sprintf("/api/tasks/%d/recurrence", task.ID)
+	rec := s.api(http.MethodPost, path, token, map[string]interface{}{"frequency": "weekly"})
+	expectStatus(t, rec, http.StatusCreated)
+	var series models.TaskSeries
+	decode(t, rec, &series)
+
+	rec = s.api(http.MethodPost, path, token, map[string]interface{}{"frequency": "weekly"})
+	expectError(t, rec, http.StatusConflict, "conflict")
+
+	rec = s.api(http.MethodPut, fmt.Sprintf("/api/series/%d", series.ID), token, map[string]interface{}{
+		"title": "Weekly status report",
+		"rule":  map[string]interface{}{"frequency": "monthly"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	// Изменения шаблона переносятся на незавершённые экземпляры
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/%d", task.ID), token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if task.Title != "Weekly status report" || task.SeriesID == nil || *task.SeriesID != series.ID {
+		t.Fatalf("unexpected instance %+v", task)
+	}
+
+	rec = s.api(http.MethodDelete, fmt.Sprintf("/api/series/%d", series.ID), token, nil)
+	expectStatus(t, rec, http.StatusNoContent)
+
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/series/%d", series.ID), token, nil)
+	expectError(t, rec, http.StatusNotFound, "not_found")
+}
+
+// ETag записи с версией version, как его возвращает API
+func etag(version int) string {
+	return fmt.Sprintf("%q", fmt.Sprint(version))
+}
+
+func createBoard(t *testing.T, s *testServer, token string, body map[string]interface{}) models.Board {
+	t.Helper()
+	rec := s.api(http.MethodPost, "/api/boards", token, body)
+	expectStatus(t, rec, http.StatusOK)
+	var board models.Board
+	decode(t, rec, &board)
+	return board
+}
+
+func createTask(t *testing.T, s *testServer, token, title, status string) models.Task {
+	t.Helper()
+	rec := s.api(http.MethodPost, "/api/tasks", token, map[string]interface{}{"title": title, "status": status})
+	expectStatus(t, rec, http.StatusCreated)
+	var task models.Task
+	decode(t, rec, &task)
+	return task
+}
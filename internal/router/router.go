@@ -2,24 +2,71 @@ package router
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/CAATHARSIS/task-tracking/docs"
 	"github.com/CAATHARSIS/task-tracking/internal/auth"
 	"github.com/CAATHARSIS/task-tracking/internal/handlers/api"
 	"github.com/CAATHARSIS/task-tracking/internal/handlers/web"
+	"github.com/CAATHARSIS/task-tracking/internal/health"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
+	"github.com/CAATHARSIS/task-tracking/internal/tracing"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func SetupRouter(
-	apiBoardHandler *api.BoardHandler,
-	webBoardHandler *web.BoardHandler,
-	apiBoardTaskHandler *api.BoardTaskRelationHandler,
-	apiTaskHandler *api.TaskHandler,
-	webTaskHandler *web.TaskHandler,
-	apiUserHandler *api.UserHandler,
-	webUserHandler *web.UserHandler,
-	jwtService *auth.JWTService,
-) *gin.Engine {
-	r := gin.Default()
+// Служебные адреса, которые опрашиваются постоянно, не трассируются
+func traced(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/health", "/livez", "/readyz", "/metrics":
+		return false
+	}
+	return !strings.HasPrefix(r.URL.Path, "/static/")
+}
+
+/*
+Обработчики и сервисы, из которых собирается маршрутизатор
+Маршруты с nil-обработчиком регистрируются, но вызывать их нельзя: тестам документации достаточно списка маршрутов
+*/
+type Deps struct {
+	APIBoard       *api.BoardHandler
+	WebBoard       *web.BoardHandler
+	APIBoardTask   *api.BoardTaskRelationHandler
+	APITask        *api.TaskHandler
+	WebTask        *web.TaskHandler
+	APIUser        *api.UserHandler
+	WebUser        *web.UserHandler
+	APISavedView   *api.SavedViewHandler
+	WebSavedView   *web.SavedViewHandler
+	APITaskSeries  *api.TaskSeriesHandler
+	APITimeEntry   *api.TimeEntryHandler
+	APICustomField *api.CustomFieldHandler
+	WebCustomField *web.CustomFieldHandler
+
+	JWT         *auth.JWTService
+	Idempotency gin.HandlerFunc
+	RateLimits  middleware.RateLimits
+	// Обработчик /metrics, nil - метрики отдаются отдельным сервером или отключены
+	Metrics http.Handler
+	Health  *health.Checker
+	// Обратные прокси, которым можно верить в X-Forwarded-For; пусто - адрес клиента берётся из соединения
+	TrustedProxies []string
+}
+
+func SetupRouter(deps Deps) *gin.Engine {
+	r := gin.New()
+	// Без этого gin верит X-Forwarded-For от любого клиента, и ограничение частоты по IP обходится подменой заголовка
+	if err := r.SetTrustedProxies(deps.TrustedProxies); err != nil {
+		panic(err)
+	}
+	r.Use(
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithPropagators(tracing.Propagator), otelgin.WithFilter(traced)),
+		middleware.RequestID(),
+		middleware.AccessLog(),
+		middleware.Metrics(),
+		middleware.Recovery(),
+	)
+	r.NoRoute(middleware.NotFound())
 
 	r.LoadHTMLFiles(
 		"templates/home.html",
@@ -33,6 +80,7 @@ func SetupRouter(
 		"templates/tasks/tasks-form.html",
 		"templates/tasks/tasks-list.html",
 		"templates/tasks/tasks-view.html",
+		"templates/tasks/custom-fields.html",
 	)
 	r.Static("/static", "./static")
 
@@ -53,89 +101,170 @@ func SetupRouter(
 	})
 
 	api := r.Group("/api")
+	api.Use(middleware.Errors())
 	{
+		api.GET("/openapi.json", func(c *gin.Context) {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", docs.OpenAPI)
+		})
+		api.GET("/docs", func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", docs.SwaggerUI)
+		})
+		assets := http.FS(docs.SwaggerAssets())
+		api.StaticFileFS("/docs/swagger-ui.css", "swagger-ui.css", assets)
+		api.StaticFileFS("/docs/swagger-ui-bundle.js", "swagger-ui-bundle.js", assets)
+
 		authAPI := api.Group("/auth")
+		authAPI.Use(deps.RateLimits.ByIP(middleware.RespondError))
 		{
-			authAPI.POST("/register", apiUserHandler.Register)
-			authAPI.POST("/login", apiUserHandler.Login)
+			authAPI.POST("/register", deps.APIUser.Register)
+			authAPI.POST("/login", deps.RateLimits.ByLoginEmail(middleware.RespondError), deps.APIUser.Login)
 		}
 
 		apiProtected := api.Group("")
-		apiProtected.Use(jwtService.JWTAuthMiddleware())
+		apiProtected.Use(deps.JWT.JWTAuthMiddleware(), deps.RateLimits.ByUser(middleware.RespondError), deps.Idempotency)
 		{
 			userAPI := apiProtected.Group("/users")
 			{
-				userAPI.GET("/:id", apiUserHandler.GetUser)
-				userAPI.PUT("/:id", apiUserHandler.UpdateUser)
-				userAPI.DELETE("/:id", apiUserHandler.DeleteUser)
+				userAPI.GET("/:id", deps.APIUser.GetUser)
+				userAPI.PUT("/:id", deps.APIUser.UpdateUser)
+				userAPI.DELETE("/:id", deps.APIUser.DeleteUser)
+				userAPI.GET("/:id/time", deps.APITimeEntry.GetUserTotal)
 			}
 
 			boardAPI := apiProtected.Group("/boards")
 			{
-				boardAPI.POST("", apiBoardHandler.CreateBoard)
-				boardAPI.GET("/:id", apiBoardHandler.GetBoard)
-				boardAPI.PUT("/:id", apiBoardHandler.UpdateBoard)
-				boardAPI.DELETE("/:id", apiBoardHandler.DeleteBoard)
-				boardAPI.GET("/:id/user-tasks", apiBoardHandler.ListBoardByUser)
-
-				boardAPI.POST("/:id/tasks/:task_id", apiBoardTaskHandler.AddTaskToBoard)
-				boardAPI.DELETE("/:id/tasks/:task_id", apiBoardTaskHandler.RemoveTaskFromBoard)
-				boardAPI.GET("/:id/tasks", apiBoardTaskHandler.GetBoardTasks)
-				boardAPI.PATCH("/tasks/move", apiBoardTaskHandler.MoveTasksBeetwenBoards)
+				boardAPI.POST("", deps.APIBoard.CreateBoard)
+				boardAPI.GET("/:id", deps.APIBoard.GetBoard)
+				boardAPI.PUT("/:id", deps.APIBoard.UpdateBoard)
+				boardAPI.PATCH("/:id", deps.APIBoard.PatchBoard)
+				boardAPI.DELETE("/:id", deps.APIBoard.DeleteBoard)
+				boardAPI.GET("/:id/user-tasks", deps.APIBoard.ListBoardByUser)
+				boardAPI.GET("/:id/time", deps.APITimeEntry.GetBoardTotal)
+				boardAPI.GET("/:id/summary", deps.APIBoard.GetBoardSummary)
+
+				boardAPI.POST("/:id/fields", deps.APICustomField.CreateField)
+				boardAPI.GET("/:id/fields", deps.APICustomField.ListFields)
+				boardAPI.PUT("/:id/fields/:field_id", deps.APICustomField.UpdateField)
+				boardAPI.DELETE("/:id/fields/:field_id", deps.APICustomField.DeleteField)
+
+				boardAPI.POST("/:id/tasks/:task_id", deps.APIBoardTask.AddTaskToBoard)
+				boardAPI.DELETE("/:id/tasks/:task_id", deps.APIBoardTask.RemoveTaskFromBoard)
+				boardAPI.GET("/:id/tasks", deps.APIBoardTask.GetBoardTasks)
+				boardAPI.PATCH("/tasks/move", deps.APIBoardTask.MoveTasksBeetwenBoards)
 			}
 
 			taskAPI := apiProtected.Group("/tasks")
 			{
-				taskAPI.POST("", apiTaskHandler.CreateTask)
-				taskAPI.GET("/:id", apiTaskHandler.GetTask)
-				taskAPI.PATCH("/:id/status", apiTaskHandler.UpdateStatus)
-				taskAPI.PUT("/:id", apiTaskHandler.UpdateTask)
-				taskAPI.DELETE("/:id", apiTaskHandler.DeleteTask)
-				taskAPI.GET("/user/:user_id", apiTaskHandler.ListTaskByUser)
+				taskAPI.POST("", deps.APITask.CreateTask)
+				taskAPI.POST("/bulk", deps.APITask.BulkTasks)
+				taskAPI.GET("", deps.APISavedView.FilterTasks)
+				taskAPI.GET("/:id", deps.APITask.GetTask)
+				taskAPI.PATCH("/:id/status", deps.APITask.UpdateStatus)
+				taskAPI.PUT("/:id", deps.APITask.UpdateTask)
+				taskAPI.PATCH("/:id", deps.APITask.PatchTask)
+				taskAPI.DELETE("/:id", deps.APITask.DeleteTask)
+				taskAPI.GET("/user/:user_id", deps.APITask.ListTaskByUser)
+				taskAPI.POST("/:id/recurrence", deps.APITaskSeries.MakeRecurring)
+
+				taskAPI.POST("/:id/timer/start", deps.APITimeEntry.StartTimer)
+				taskAPI.POST("/:id/worklogs", deps.APITimeEntry.CreateWorklog)
+				taskAPI.GET("/:id/worklogs", deps.APITimeEntry.ListWorklogs)
+				taskAPI.GET("/:id/time", deps.APITimeEntry.GetTaskTotal)
+				taskAPI.PUT("/:id/fields", deps.APICustomField.SetTaskValues)
+			}
+
+			timerAPI := apiProtected.Group("/timer")
+			{
+				timerAPI.GET("", deps.APITimeEntry.GetRunningTimer)
+				timerAPI.POST("/stop", deps.APITimeEntry.StopTimer)
+			}
+
+			apiProtected.DELETE("/worklogs/:id", deps.APITimeEntry.DeleteWorklog)
+			apiProtected.GET("/reports/time", deps.APITimeEntry.GetReport)
+
+			seriesAPI := apiProtected.Group("/series")
+			{
+				seriesAPI.GET("/:id", deps.APITaskSeries.GetSeries)
+				seriesAPI.PUT("/:id", deps.APITaskSeries.UpdateSeries)
+				seriesAPI.DELETE("/:id", deps.APITaskSeries.DeleteSeries)
+			}
+
+			viewAPI := apiProtected.Group("/views")
+			{
+				viewAPI.POST("", deps.APISavedView.CreateView)
+				viewAPI.GET("", deps.APISavedView.ListViews)
+				viewAPI.GET("/:id", deps.APISavedView.GetView)
+				viewAPI.PUT("/:id", deps.APISavedView.UpdateView)
+				viewAPI.DELETE("/:id", deps.APISavedView.DeleteView)
+				viewAPI.GET("/:id/tasks", deps.APISavedView.GetViewTasks)
 			}
 		}
 	}
 
+	loginError, registerError := web.FormError("login"), web.FormError("register")
 	web := r.Group("")
 	{
-		web.POST("/login", webUserHandler.LoginWeb)
-		web.POST("/register", webUserHandler.RegisterWeb)
+		web.POST("/login", deps.RateLimits.ByIP(loginError), deps.RateLimits.ByLoginEmail(loginError), deps.WebUser.LoginWeb)
+		web.POST("/register", deps.RateLimits.ByIP(registerError), deps.WebUser.RegisterWeb)
 
 		webProtected := web.Group("")
-		webProtected.Use(jwtService.JWTAuthMiddleware())
+		webProtected.Use(deps.JWT.JWTAuthMiddleware(), deps.WebSavedView.PinnedViewsMiddleware())
 		{
 			boardGroup := webProtected.Group("/boards")
 			{
-				boardGroup.GET("", webBoardHandler.ListBoardsPage)
-				boardGroup.GET("/new", webBoardHandler.HandleBoardForm)
-				boardGroup.GET("/:id", webBoardHandler.GetBoardPage)
-				boardGroup.GET("/:id/edit", webBoardHandler.HandleBoardForm)
-				boardGroup.POST("", webBoardHandler.HandleBoardForm)
-				boardGroup.POST("/:id", webBoardHandler.HandleBoardForm)
-				boardGroup.POST("/:id/delete", webBoardHandler.DeleteBoardWeb)
-
-				boardGroup.POST("/:id/add-task", webBoardHandler.AddTaskToBoard)
-				boardGroup.POST("/:id/create-and-add-task", webBoardHandler.CreateAndAddTaskToBoard)
-				boardGroup.POST("/:id/remove-task/:task_id", webBoardHandler.RemoveTaskFromBoard)
+				boardGroup.GET("", deps.WebBoard.ListBoardsPage)
+				boardGroup.GET("/new", deps.WebBoard.HandleBoardForm)
+				boardGroup.GET("/:id", deps.WebBoard.GetBoardPage)
+				boardGroup.GET("/:id/edit", deps.WebBoard.HandleBoardForm)
+				boardGroup.POST("", deps.WebBoard.HandleBoardForm)
+				boardGroup.POST("/:id", deps.WebBoard.HandleBoardForm)
+				boardGroup.POST("/:id/delete", deps.WebBoard.DeleteBoardWeb)
+
+				boardGroup.POST("/:id/add-task", deps.WebBoard.AddTaskToBoard)
+				boardGroup.POST("/:id/create-and-add-task", deps.WebBoard.CreateAndAddTaskToBoard)
+				boardGroup.POST("/:id/remove-task/:task_id", deps.WebBoard.RemoveTaskFromBoard)
+
+				boardGroup.POST("/:id/fields", deps.WebCustomField.CreateField)
+				boardGroup.POST("/:id/fields/:field_id/delete", deps.WebCustomField.DeleteField)
 			}
 
 			taskGroup := webProtected.Group("/tasks")
 			{
-				taskGroup.GET("", webTaskHandler.ListTasksPage)
-				taskGroup.GET("/new", webTaskHandler.HandleTaskForm)
-				taskGroup.GET("/:id", webTaskHandler.GetTaskPage)
-				taskGroup.GET("/:id/edit", webTaskHandler.HandleTaskForm)
-				taskGroup.POST("", webTaskHandler.HandleTaskForm)
-				taskGroup.POST("/:id", webTaskHandler.HandleTaskForm)
-				taskGroup.POST("/:id/delete", webTaskHandler.DeleteTaskWeb)
-				taskGroup.PATCH("/:id/status", apiTaskHandler.UpdateStatus)
+				taskGroup.GET("", deps.WebTask.ListTasksPage)
+				taskGroup.GET("/new", deps.WebTask.HandleTaskForm)
+				taskGroup.GET("/:id", deps.WebTask.GetTaskPage)
+				taskGroup.GET("/:id/edit", deps.WebTask.HandleTaskForm)
+				taskGroup.POST("", deps.WebTask.HandleTaskForm)
+				taskGroup.POST("/bulk", deps.WebTask.BulkTasksWeb)
+				taskGroup.POST("/:id", deps.WebTask.HandleTaskForm)
+				taskGroup.POST("/:id/delete", deps.WebTask.DeleteTaskWeb)
+				taskGroup.PATCH("/:id/status", middleware.Errors(), deps.APITask.UpdateStatus)
 			}
+
+			webProtected.GET("/views/:id", deps.WebSavedView.GetViewPage)
 		}
 	}
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "OK"})
 	})
+	// Процесс жив и обслуживает запросы, зависимости не проверяются
+	r.GET("/livez", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+	})
+	// Готовность принимать трафик: 503, если недоступна зависимость или идёт остановка
+	r.GET("/readyz", func(c *gin.Context) {
+		report := deps.Health.Ready(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != health.StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	})
+	// Без обработчика метрики отдаются отдельным сервером или отключены
+	if deps.Metrics != nil {
+		r.GET("/metrics", gin.WrapH(deps.Metrics))
+	}
 
 	return r
 }
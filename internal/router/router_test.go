@@ -0,0 +1,113 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/CAATHARSIS/task-tracking/docs"
+)
+
+// Маршруты документации не описываются в самом документе
+var undocumented = map[string]bool{
+	"GET /api/openapi.json": true,
+	"GET /api/docs":         true,
+
+	"GET /api/docs/swagger-ui.css":        true,
+	"HEAD /api/docs/swagger-ui.css":       true,
+	"GET /api/docs/swagger-ui-bundle.js":  true,
+	"HEAD /api/docs/swagger-ui-bundle.js": true,
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	r := SetupRouter(Deps{})
+
+	var spec struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(docs.OpenAPI, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	if len(spec.Servers) != 1 {
+		t.Fatalf("expected one server in OpenAPI document, got %d", len(spec.Servers))
+	}
+	base := strings.TrimSuffix(spec.Servers[0].URL, "/")
+
+	documented := make(map[string]bool)
+	for path, methods := range spec.Paths {
+		for method := range methods {
+			documented[strings.ToUpper(method)+" "+base+path] = true
+		}
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, base+"/") {
+			continue
+		}
+		key := route.Method + " " + pathParam.ReplaceAllString(route.Path, "{$1}")
+		if !undocumented[key] {
+			registered[key] = true
+		}
+	}
+
+	var missing, stale []string
+	for key := range registered {
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+
+	for _, key := range missing {
+		t.Errorf("route %s is not documented, add annotations and run go generate ./docs", key)
+	}
+	for _, key := range stale {
+		t.Errorf("documented operation %s has no route", key)
+	}
+}
+
+// Страница документации загружает Swagger UI только с этого же сервера
+func TestSwaggerUIAssets(t *testing.T) {
+	r := SetupRouter(Deps{})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected docs page, got %d", rec.Code)
+	}
+
+	assets := assetRef.FindAllStringSubmatch(rec.Body.String(), -1)
+	if len(assets) != 2 {
+		t.Fatalf("expected stylesheet and script on the docs page, got %v", assets)
+	}
+	for _, asset := range assets {
+		url := asset[1]
+		if !strings.HasPrefix(url, "/api/docs/") {
+			t.Errorf("expected local asset, got %s", url)
+			continue
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("expected %s to be served, got %d", url, rec.Code)
+		}
+	}
+}
+
+var assetRef = regexp.MustCompile(`(?:href|src)="([^"]+)"`)
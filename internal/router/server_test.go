@@ -0,0 +1,246 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/auth"
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/handlers/api"
+	"github.com/CAATHARSIS/task-tracking/internal/handlers/web"
+	"github.com/CAATHARSIS/task-tracking/internal/health"
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
+	memory_repo "github.com/CAATHARSIS/task-tracking/internal/repository/memory"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Учётные данные basic auth для /metrics в тестах
+const (
+	metricsUsername = "prometheus"
+	metricsPassword = "scrape-secret"
+)
+
+// Небольшой лимит тела, чтобы проверить ответ 413
+var testIdempotency = middleware.IdempotencyOptions{TTL: time.Hour, Lease: time.Minute, MaxBodySize: 4 << 10}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Шаблоны загружаются по путям относительно корня модуля
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// Приложение целиком поверх репозиториев в памяти
+type testServer struct {
+	t       *testing.T
+	router  *gin.Engine
+	jwt     *auth.JWTService
+	checker *health.Checker
+	cache   *cache.Cache
+	// Хранилище для подготовки данных, которые нельзя создать через API
+	store *memory_repo.Store
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newLimitedTestServer(t, middleware.RateLimits{})
+}
+
+// Тестовое приложение с ограничением частоты запросов
+func newLimitedTestServer(t *testing.T, limits middleware.RateLimits) *testServer {
+	t.Helper()
+
+	// Конфигурация задаётся явно, чтобы переменные окружения машины не влияли на тесты
+	jwtService := auth.NewJWTService(&config.Config{JWTSecret: "test-secret", JWTExpiration: time.Hour})
+
+	store := memory_repo.NewStore()
+	savedViewRepo := memory_repo.NewSavedViewMemoryRepo(store)
+	timeEntryRepo := memory_repo.NewTimeEntryMemoryRepo(store)
+	uow := memory_repo.NewUnitOfWork(store)
+
+	// Кэш включён во всех тестах, чтобы устаревший список после изменения ломал проверки
+	taskCache := cache.New(cache.NewMemoryStore(), time.Hour, time.Second)
+	repos := cache.Invalidating(taskCache, cache.Repositories{
+		Tasks:        memory_repo.NewTaskMemoryRepo(store),
+		Boards:       memory_repo.NewBoardMemoryRepo(store),
+		BoardTasks:   memory_repo.NewBoardTaskMemoryRepo(store),
+		CustomFields: memory_repo.NewCustomFieldMemoryRepo(store),
+		Users:        memory_repo.NewUserMemoryRepo(store),
+		TaskSeries:   memory_repo.NewTaskSeriesMemoryRepo(store),
+	})
+	boardRepo := repos.Boards
+	boardTaskRepo := repos.BoardTasks
+	taskRepo := repos.Tasks
+	userRepo := repos.Users
+	taskSeriesRepo := repos.TaskSeries
+	customFieldRepo := repos.CustomFields
+
+	taskService := service.NewTaskService(taskRepo, boardRepo, boardTaskRepo, taskSeriesRepo, customFieldRepo, userRepo, uow, taskCache)
+	boardService := service.NewBoardService(boardRepo, boardTaskRepo, taskRepo, customFieldRepo, uow, taskCache)
+	userService := service.NewUserService(userRepo, jwtService)
+
+	// Проверки готовности тесты добавляют сами
+	checker := health.NewChecker(time.Second)
+
+	r := SetupRouter(Deps{
+		APIBoard:       api.NewBoardHandler(boardService),
+		WebBoard:       web.NewBoardHandler(boardService, taskService),
+		APIBoardTask:   api.NewBoardTaskRealtionHandler(boardService),
+		APITask:        api.NewTaskHandler(taskService),
+		WebTask:        web.NewTaskHandler(taskService, boardService),
+		APIUser:        api.NewUserHandler(userService),
+		WebUser:        web.NewUserHandler(userService, taskService),
+		APISavedView:   api.NewSavedViewHandler(savedViewRepo, taskRepo, boardRepo, customFieldRepo),
+		WebSavedView:   web.NewSavedViewHandler(savedViewRepo, taskRepo, boardRepo),
+		APITaskSeries:  api.NewTaskSeriesHandler(taskSeriesRepo, taskRepo, uow),
+		APITimeEntry:   api.NewTimeEntryHandler(timeEntryRepo, taskRepo, boardRepo),
+		APICustomField: api.NewCustomFieldHandler(customFieldRepo, boardRepo, taskService),
+		WebCustomField: web.NewCustomFieldHandler(customFieldRepo, boardRepo),
+		JWT:            jwtService,
+		Idempotency:    middleware.Idempotency(memory_repo.NewIdempotencyMemoryRepo(store), testIdempotency),
+		RateLimits:     limits,
+		Metrics:        metrics.Handler(metricsUsername, metricsPassword),
+		Health:         checker,
+	})
+
+	return &testServer{t: t, router: r, jwt: jwtService, checker: checker, cache: taskCache, store: store}
+}
+
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// JSON-запрос к API, токен передаётся в заголовке Authorization
+func (s *testServer) api(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+	return s.apiIfMatch(method, path, token, "", body)
+}
+
+// Запрос к API с заголовком If-Match для изменения задач и досок
+func (s *testServer) apiIfMatch(method, path, token, etag string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	header := map[string]string{}
+	if etag != "" {
+		header["If-Match"] = etag
+	}
+	return s.apiWithHeader(method, path, token, header, body)
+}
+
+// Запрос к API с дополнительными заголовками
+func (s *testServer) apiWithHeader(method, path, token string, header map[string]string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return s.serve(req)
+}
+
+// Запрос веб-интерфейса, токен передаётся в cookie
+func (s *testServer) page(method, path, token string, form url.Values) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader io.Reader
+	if form != nil {
+		reader = strings.NewReader(form.Encode())
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+	}
+	return s.serve(req)
+}
+
+// Регистрирует пользователя через API и возвращает его ID и токен
+func (s *testServer) signUp(email string) (int, string) {
+	s.t.Helper()
+
+	credentials := map[string]string{"email": email, "password": "secret123"}
+
+	rec := s.api(http.MethodPost, "/api/auth/register", "", credentials)
+	expectStatus(s.t, rec, http.StatusCreated)
+	var user struct {
+		ID int `json:"id"`
+	}
+	decode(s.t, rec, &user)
+
+	rec = s.api(http.MethodPost, "/api/auth/login", "", credentials)
+	expectStatus(s.t, rec, http.StatusOK)
+	var login struct {
+		Token string `json:"token"`
+	}
+	decode(s.t, rec, &login)
+
+	return user.ID, login.Token
+}
+
+// Счётчики кэша списков задач, наружу они отдаются только метриками
+func (s *testServer) cacheStats() map[string]cache.Counters {
+	return s.cache.Stats()
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, rec.Code, rec.Body.String())
+	}
+}
+
+// Проверяет статус и код ошибки в едином формате ответа API
+func expectError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) middleware.ErrorBody {
+	t.Helper()
+	expectStatus(t, rec, status)
+
+	var resp middleware.ErrorResponse
+	decode(t, rec, &resp)
+	if string(resp.Error.Code) != code {
+		t.Fatalf("expected error code %q, got %q: %s", code, resp.Error.Code, rec.Body.String())
+	}
+	return resp.Error
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+}
@@ -0,0 +1,431 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+	"github.com/CAATHARSIS/task-tracking/internal/middleware"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/ratelimit"
+)
+
+func TestWebPublicPages(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, path := range []string{"/", "/login", "/register", "/health"} {
+		rec := s.page(http.MethodGet, path, "", nil)
+		expectStatus(t, rec, http.StatusOK)
+	}
+
+	rec := s.page(http.MethodGet, "/tasks", "", nil)
+	expectRedirect(t, rec, "/login")
+
+	rec = s.page(http.MethodGet, "/boards", "broken-token", nil)
+	expectRedirect(t, rec, "/login")
+}
+
+func TestWebAuth(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := s.page(http.MethodPost, "/register", "", url.Values{"email": {"alice@example.com"}, "password": {"secret123"}})
+	expectStatus(t, rec, http.StatusOK)
+	if authCookie(rec.Result().Cookies()) == "" {
+		t.Fatal("expected registration to set auth cookie")
+	}
+
+	rec = s.page(http.MethodPost, "/register", "", url.Values{"email": {"alice@example.com"}, "password": {"secret123"}})
+	expectStatus(t, rec, http.StatusConflict)
+
+	rec = s.page(http.MethodPost, "/register", "", url.Values{"email": {"bob"}, "password": {"secret123"}})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = s.page(http.MethodPost, "/login", "", url.Values{"email": {"alice@example.com"}, "password": {"wrong"}})
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	token := s.webLogin("alice@example.com")
+	rec = s.page(http.MethodGet, "/tasks", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+}
+
+func TestWebRateLimits(t *testing.T) {
+	s := newLimitedTestServer(t, middleware.RateLimits{
+		Store:      ratelimit.NewMemoryStore(),
+		AuthIP:     ratelimit.Rule{Limit: 3, Window: time.Minute},
+		LoginEmail: ratelimit.Rule{Limit: 1, Window: 15 * time.Minute},
+	})
+
+	rec := s.page(http.MethodPost, "/register", "", url.Values{"email": {"alice@example.com"}, "password": {"secret123"}})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.page(http.MethodPost, "/login", "", url.Values{"email": {"alice@example.com"}, "password": {"wrong"}})
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	// Отклонённая форма показывается снова с текстом ошибки
+	rec = s.page(http.MethodPost, "/login", "", url.Values{"email": {"alice@example.com"}, "password": {"secret123"}})
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	expectBody(t, rec, `class="alert alert-error"`)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	rec = s.page(http.MethodPost, "/register", "", url.Values{"email": {"bob@example.com"}, "password": {"secret123"}})
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	expectBody(t, rec, "Too many requests")
+}
+
+func TestWebTasks(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	rec := s.page(http.MethodGet, "/tasks/new", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.page(http.MethodPost, "/tasks", token, url.Values{"title": {"Buy milk"}, "status": {"todo"}})
+	expectRedirect(t, rec, "/tasks")
+
+	rec = s.page(http.MethodPost, "/tasks", token, url.Values{"title": {""}, "status": {"todo"}})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = s.page(http.MethodGet, "/tasks", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "Buy milk")
+
+	task := s.onlyTask(token)
+
+	taskPath := fmt.Sprintf("/tasks/%d", task)
+	rec = s.page(http.MethodGet, taskPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "Buy milk")
+
+	rec = s.page(http.MethodGet, taskPath, otherToken, nil)
+	expectStatus(t, rec, http.StatusForbidden)
+
+	rec = s.page(http.MethodGet, taskPath+"/edit", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.page(http.MethodPost, taskPath, token, url.Values{"title": {"Buy oat milk"}, "status": {"in_progress"}})
+	expectRedirect(t, rec, "/tasks")
+
+	rec = s.page(http.MethodGet, taskPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "Buy oat milk")
+
+	// Страница задачи меняет статус через PATCH с JSON и ждёт ошибки в формате API
+	req := s.page(http.MethodPatch, taskPath+"/status", token, nil)
+	expectError(t, req, http.StatusBadRequest, "bad_request")
+
+	rec = s.page(http.MethodPost, taskPath+"/delete", otherToken, url.Values{})
+	expectStatus(t, rec, http.StatusForbidden)
+
+	rec = s.page(http.MethodPost, taskPath+"/delete", token, url.Values{})
+	expectRedirect(t, rec, "/tasks")
+
+	rec = s.page(http.MethodGet, taskPath, token, nil)
+	expectStatus(t, rec, http.StatusNotFound)
+}
+
+// Форма, открытая до чужого изменения, не затирает его, а показывает обе версии
+func TestWebEditConflict(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	task := createTask(t, s, token, "Buy milk", "todo")
+	taskPath := fmt.Sprintf("/tasks/%d", task.ID)
+
+	rec := s.page(http.MethodGet, taskPath+"/edit", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, `name="version" value="1"`)
+
+	rec = s.page(http.MethodPost, taskPath, token, url.Values{"title": {"Buy oat milk"}, "status": {"todo"}, "version": {"1"}})
+	expectRedirect(t, rec, "/tasks")
+
+	rec = s.page(http.MethodPost, taskPath, token, url.Values{"title": {"Buy soy milk"}, "status": {"done"}, "version": {"1"}})
+	expectStatus(t, rec, http.StatusPreconditionFailed)
+	expectBody(t, rec, "Buy oat milk")
+	expectBody(t, rec, "Buy soy milk")
+	expectBody(t, rec, `name="version" value="2"`)
+	expectBody(t, rec, taskPath+"/edit")
+
+	// Повторная отправка формы сохраняет изменения поверх текущей версии
+	rec = s.page(http.MethodPost, taskPath, token, url.Values{"title": {"Buy soy milk"}, "status": {"done"}, "version": {"2"}})
+	expectRedirect(t, rec, "/tasks")
+	rec = s.page(http.MethodGet, taskPath, token, nil)
+	expectBody(t, rec, "Buy soy milk")
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	boardPath := fmt.Sprintf("/boards/%d", board.ID)
+
+	rec = s.page(http.MethodPost, boardPath, token, url.Values{"name": {"Sprint 2"}, "version": {"1"}})
+	expectRedirect(t, rec, "/boards")
+
+	rec = s.page(http.MethodPost, boardPath, token, url.Values{"name": {"Sprint 3"}, "version": {"1"}})
+	expectStatus(t, rec, http.StatusPreconditionFailed)
+	expectBody(t, rec, "Sprint 2")
+	expectBody(t, rec, `name="version" value="2"`)
+}
+
+func TestWebBulkActions(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	first := createTask(t, s, token, "First task", "todo")
+	second := createTask(t, s, token, "Second task", "todo")
+	foreign := createTask(t, s, otherToken, "Foreign task", "todo")
+
+	rec := s.page(http.MethodGet, "/tasks", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, `action="/tasks/bulk"`)
+
+	ids := []string{fmt.Sprint(first.ID), fmt.Sprint(second.ID)}
+	rec = s.page(http.MethodPost, "/tasks/bulk", token, url.Values{"op": {"set_status"}, "status": {"done"}, "task_ids": ids})
+	expectRedirect(t, rec, "/tasks")
+	rec = s.api(http.MethodGet, fmt.Sprintf("/api/tasks/%d", second.ID), token, nil)
+	expectBody(t, rec, `"status":"done"`)
+
+	rec = s.page(http.MethodPost, "/tasks/bulk", token, url.Values{"op": {"delete"}})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	// Недоступная задача не мешает удалить остальные
+	rec = s.page(http.MethodPost, "/tasks/bulk", token, url.Values{"op": {"delete"}, "task_ids": {ids[0], fmt.Sprint(foreign.ID)}})
+	expectStatus(t, rec, http.StatusForbidden)
+	expectBody(t, rec, fmt.Sprintf("Задача #%d", foreign.ID))
+	expectBody(t, rec, "Second task")
+	if strings.Contains(rec.Body.String(), "First task") {
+		t.Fatal("expected first task to be deleted")
+	}
+}
+
+func TestWebBoardPageCache(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	task := createTask(t, s, token, "Write report", "todo")
+	boardPath := fmt.Sprintf("/boards/%d", board.ID)
+	boardTaskPath := fmt.Sprintf("/api/boards/%d/tasks/%d", board.ID, task.ID)
+	taskPath := fmt.Sprintf("/api/tasks/%d", task.ID)
+	// Ссылка есть только в списке задач доски, вариант выбора - только в списке задач пользователя
+	taskLink := fmt.Sprintf(`href="/tasks/%d"`, task.ID)
+	taskOption := fmt.Sprintf(`<option value="%d">`, task.ID)
+
+	expectStatus(t, s.api(http.MethodPost, boardTaskPath, token, nil), http.StatusNoContent)
+
+	before := s.cacheStats()
+	for i := 0; i < 2; i++ {
+		rec := s.page(http.MethodGet, boardPath, token, nil)
+		expectStatus(t, rec, http.StatusOK)
+		expectBody(t, rec, taskLink)
+		expectBody(t, rec, taskOption+"Write report (todo)")
+	}
+	after := s.cacheStats()
+	for _, kind := range []string{cache.BoardTasks, cache.UserTasks} {
+		if after[kind].Hits != before[kind].Hits+1 || after[kind].Misses != before[kind].Misses+1 {
+			t.Fatalf("expected one miss and one hit for %s, got %+v before and %+v after", kind, before[kind], after[kind])
+		}
+	}
+
+	// Изменение задачи сбрасывает и список доски, и список владельца
+	rec := s.apiIfMatch(http.MethodPatch, taskPath, token, etag(task.Version), map[string]string{"title": "Write summary"})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	rec = s.page(http.MethodGet, boardPath, token, nil)
+	expectBody(t, rec, taskOption+"Write summary (todo)")
+	if strings.Contains(rec.Body.String(), "Write report") {
+		t.Fatal("expected board page to show the new title")
+	}
+
+	expectStatus(t, s.api(http.MethodDelete, boardTaskPath, token, nil), http.StatusNoContent)
+	rec = s.page(http.MethodGet, boardPath, token, nil)
+	expectBody(t, rec, taskOption)
+	if strings.Contains(rec.Body.String(), taskLink) {
+		t.Fatal("expected removed task to leave the board page")
+	}
+
+	expectStatus(t, s.apiIfMatch(http.MethodDelete, taskPath, token, etag(task.Version), nil), http.StatusNoContent)
+	rec = s.page(http.MethodGet, boardPath, token, nil)
+	if strings.Contains(rec.Body.String(), taskOption) {
+		t.Fatal("expected deleted task to leave the user's task list")
+	}
+}
+
+// Изменение серии сбрасывает закэшированный список задач владельца
+func TestSeriesUpdateCache(t *testing.T) {
+	s := newTestServer(t)
+	userID, token := s.signUp("alice@example.com")
+	task := createTask(t, s, token, "Weekly report", "todo")
+	listPath := fmt.Sprintf("/api/tasks/user/%d", userID)
+
+	rec := s.api(http.MethodPost, fmt.Sprintf("/api/tasks/%d/recurrence", task.ID), token, map[string]interface{}{"frequency": "weekly"})
+	expectStatus(t, rec, http.StatusCreated)
+	var series models.TaskSeries
+	decode(t, rec, &series)
+
+	before := s.cacheStats()
+	expectStatus(t, s.api(http.MethodGet, listPath, token, nil), http.StatusOK)
+	if after := s.cacheStats(); after[cache.UserTasks].Misses != before[cache.UserTasks].Misses+1 {
+		t.Fatalf("expected the user's task list to be cached, got %+v", after[cache.UserTasks])
+	}
+
+	rec = s.api(http.MethodPut, fmt.Sprintf("/api/series/%d", series.ID), token, map[string]interface{}{
+		"title": "Weekly status report",
+		"rule":  map[string]interface{}{"frequency": "weekly"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.api(http.MethodGet, listPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []models.Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Weekly status report" || tasks[0].Version <= task.Version {
+		t.Fatalf("expected the updated instance, got %+v", tasks)
+	}
+}
+
+func TestWebBoards(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+
+	rec := s.page(http.MethodGet, "/boards/new", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.page(http.MethodPost, "/boards", token, url.Values{"name": {"Home"}, "estimate_unit": {"hours"}, "wip_in_progress": {"2"}})
+	expectRedirect(t, rec, "/boards")
+
+	rec = s.page(http.MethodPost, "/boards", token, url.Values{"name": {""}})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	board := createBoard(t, s, token, map[string]interface{}{"name": "Sprint"})
+	boardPath := fmt.Sprintf("/boards/%d", board.ID)
+
+	rec = s.page(http.MethodGet, "/boards", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "Home")
+	expectBody(t, rec, "Sprint")
+
+	rec = s.page(http.MethodPost, boardPath+"/create-and-add-task", token, url.Values{"title": {"Plan sprint"}, "status": {"todo"}})
+	expectRedirect(t, rec, boardPath)
+
+	rec = s.page(http.MethodPost, boardPath+"/fields", token, url.Values{"name": {"Owner"}, "type": {"text"}})
+	expectRedirect(t, rec, boardPath)
+
+	rec = s.page(http.MethodGet, boardPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "Plan sprint")
+	expectBody(t, rec, "Owner")
+
+	rec = s.page(http.MethodGet, boardPath, otherToken, nil)
+	expectStatus(t, rec, http.StatusForbidden)
+
+	task := createTask(t, s, token, "Loose task", "todo")
+	rec = s.page(http.MethodPost, boardPath+"/add-task", token, url.Values{"task_id": {fmt.Sprint(task.ID)}})
+	expectRedirect(t, rec, boardPath)
+
+	rec = s.page(http.MethodPost, fmt.Sprintf("%s/remove-task/%d", boardPath, task.ID), token, url.Values{})
+	expectRedirect(t, rec, boardPath)
+
+	rec = s.page(http.MethodGet, boardPath+"/edit", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = s.page(http.MethodPost, boardPath, token, url.Values{"name": {"Sprint 2"}, "estimate_unit": {"points"}})
+	expectRedirect(t, rec, "/boards")
+
+	rec = s.page(http.MethodPost, boardPath+"/delete", otherToken, url.Values{})
+	expectStatus(t, rec, http.StatusForbidden)
+
+	rec = s.page(http.MethodPost, boardPath+"/delete", token, url.Values{})
+	expectRedirect(t, rec, "/boards")
+
+	rec = s.page(http.MethodGet, boardPath, token, nil)
+	expectStatus(t, rec, http.StatusNotFound)
+}
+
+func TestWebSavedViews(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.signUp("alice@example.com")
+	_, otherToken := s.signUp("bob@example.com")
+	createTask(t, s, token, "Pinned task", "todo")
+
+	rec := s.api(http.MethodPost, "/api/views", token, map[string]interface{}{
+		"name":   "My todo",
+		"filter": map[string]interface{}{"statuses": []string{"todo"}},
+		"pinned": true,
+	})
+	expectStatus(t, rec, http.StatusCreated)
+	var view struct {
+		ID int `json:"id"`
+	}
+	decode(t, rec, &view)
+
+	// Закреплённые представления выводятся в навигации
+	rec = s.page(http.MethodGet, "/tasks", token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "My todo")
+
+	viewPath := fmt.Sprintf("/views/%d", view.ID)
+	rec = s.page(http.MethodGet, viewPath, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectBody(t, rec, "Pinned task")
+
+	rec = s.page(http.MethodGet, viewPath, otherToken, nil)
+	expectStatus(t, rec, http.StatusForbidden)
+}
+
+func (s *testServer) webLogin(email string) string {
+	s.t.Helper()
+	rec := s.page(http.MethodPost, "/login", "", url.Values{"email": {email}, "password": {"secret123"}})
+	expectRedirect(s.t, rec, "/tasks")
+
+	token := authCookie(rec.Result().Cookies())
+	if token == "" {
+		s.t.Fatal("expected login to set auth cookie")
+	}
+	return token
+}
+
+// ID единственной задачи пользователя
+func (s *testServer) onlyTask(token string) int {
+	s.t.Helper()
+	rec := s.api(http.MethodGet, "/api/tasks", token, nil)
+	expectStatus(s.t, rec, http.StatusOK)
+
+	var tasks []struct {
+		ID int `json:"id"`
+	}
+	decode(s.t, rec, &tasks)
+	if len(tasks) != 1 {
+		s.t.Fatalf("expected one task, got %d", len(tasks))
+	}
+	return tasks[0].ID
+}
+
+func authCookie(cookies []*http.Cookie) string {
+	for _, cookie := range cookies {
+		if cookie.Name == "auth_token" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func expectRedirect(t *testing.T, rec *httptest.ResponseRecorder, location string) {
+	t.Helper()
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != location {
+		t.Fatalf("expected redirect to %s, got %d %q", location, rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func expectBody(t *testing.T, rec *httptest.ResponseRecorder, substr string) {
+	t.Helper()
+	if !strings.Contains(rec.Body.String(), substr) {
+		t.Fatalf("expected page to contain %q", substr)
+	}
+}
@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Фоновая задача, удаляющая истёкшие ключи идемпотентности
+type IdempotencyCleaner struct {
+	keys     repository.IdempotencyRepository
+	interval time.Duration
+}
+
+func NewIdempotencyCleaner(keys repository.IdempotencyRepository, interval time.Duration) *IdempotencyCleaner {
+	return &IdempotencyCleaner{keys: keys, interval: interval}
+}
+
+// Удаляет истёкшие ключи с заданным интервалом до отмены контекста
+func (c *IdempotencyCleaner) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.keys.DeleteExpired(ctx); err != nil {
+			slog.ErrorContext(ctx, "idempotency cleaner failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
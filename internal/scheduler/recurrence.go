@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Фоновая задача, создающая экземпляры повторяющихся задач
+type RecurrenceScheduler struct {
+	seriesRepo    repository.TaskSeriesRepository
+	taskRepo      repository.TaskRepository
+	boardTaskRepo repository.BoardTaskRepository
+	uow           repository.UnitOfWork
+	interval      time.Duration
+}
+
+func NewRecurrenceScheduler(
+	seriesRepo repository.TaskSeriesRepository,
+	taskRepo repository.TaskRepository,
+	boardTaskRepo repository.BoardTaskRepository,
+	uow repository.UnitOfWork,
+	interval time.Duration,
+) *RecurrenceScheduler {
+	return &RecurrenceScheduler{
+		seriesRepo:    seriesRepo,
+		taskRepo:      taskRepo,
+		boardTaskRepo: boardTaskRepo,
+		uow:           uow,
+		interval:      interval,
+	}
+}
+
+// Проверяет серии с заданным интервалом до отмены контекста
+func (s *RecurrenceScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunDue(ctx, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "recurrence scheduler failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Создаёт экземпляры для всех серий, у которых наступило время или завершён последний экземпляр
+func (s *RecurrenceScheduler) RunDue(ctx context.Context, now time.Time) error {
+	list, err := s.seriesRepo.ListDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, series := range list {
+		err := s.generate(ctx, series, now)
+		if errors.Is(err, repository.ErrStale) {
+			slog.DebugContext(ctx, "task series was generated by another instance", "series_id", series.ID)
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to generate instance of task series", "series_id", series.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+/*
+Экземпляр, его доски и время следующего запуска сохраняются атомарно:
+иначе при ошибке следующая проверка создала бы дубликат экземпляра.
+Перенос запуска идёт первым и захватывает серию: если её уже обработал другой экземпляр приложения,
+SetNextRun вернёт ErrStale до создания задачи
+*/
+func (s *RecurrenceScheduler) generate(ctx context.Context, series *models.TaskSeries, now time.Time) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		// Каждый экземпляр соответствует одному периоду, пропущенные периоды не догоняются
+		next := series.Rule.Next(series.NextRunAt)
+		for !next.After(now) {
+			next = series.Rule.Next(next)
+		}
+		if err := s.seriesRepo.SetNextRun(ctx, series.ID, series.NextRunAt, next); err != nil {
+			return err
+		}
+
+		seriesID := series.ID
+		task := models.Task{
+			Title:       series.Title,
+			Description: series.Description,
+			Status:      models.StatusToDo,
+			UserID:      series.UserID,
+			SeriesID:    &seriesID,
+		}
+
+		if err := s.taskRepo.Create(ctx, &task); err != nil {
+			return err
+		}
+		repository.AfterCommit(ctx, metrics.TasksCreated.Inc)
+
+		// Новый экземпляр попадает на те же доски, что и предыдущий
+		if series.LastTaskID != 0 {
+			boardIDs, err := s.boardTaskRepo.GetBoards(ctx, series.LastTaskID)
+			if err != nil {
+				return err
+			}
+			for _, boardID := range boardIDs {
+				err := s.boardTaskRepo.AddTask(ctx, boardID, task.ID)
+				// Период не пропускается из-за лимита: экземпляр создаётся без доски, на которой нет места
+				var limitErr *repository.WIPLimitError
+				if errors.As(err, &limitErr) {
+					slog.WarnContext(ctx, "instance of task series does not fit into the WIP limit of the board",
+						"series_id", series.ID, "task_id", task.ID, "board_id", boardID, "limit", limitErr.Limit)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	memory_repo "github.com/CAATHARSIS/task-tracking/internal/repository/memory"
+)
+
+// Два экземпляра приложения получили одну и ту же серию в ListDue: экземпляр задачи создаёт только первый
+func TestRecurrenceSchedulerGeneratesOncePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	store := memory_repo.NewStore()
+	seriesRepo := memory_repo.NewTaskSeriesMemoryRepo(store)
+	taskRepo := memory_repo.NewTaskMemoryRepo(store)
+	s := NewRecurrenceScheduler(seriesRepo, taskRepo, memory_repo.NewBoardTaskMemoryRepo(store), memory_repo.NewUnitOfWork(store), time.Minute)
+
+	user := &models.User{Email: "alice@example.com"}
+	if err := memory_repo.NewUserMemoryRepo(store).Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	series := &models.TaskSeries{
+		UserID:    user.ID,
+		Title:     "Daily standup",
+		Rule:      models.RecurrenceRule{Frequency: models.FrequencyDaily},
+		NextRunAt: now.Add(-time.Minute),
+	}
+	if err := seriesRepo.Create(ctx, series); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := seriesRepo.ListDue(ctx, now)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("expected one due series, got %v, %v", due, err)
+	}
+	if err := s.RunDue(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.generate(ctx, due[0], now); !errors.Is(err, repository.ErrStale) {
+		t.Fatalf("expected second generation of the period to be stale, got %v", err)
+	}
+
+	tasks, err := taskRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].SeriesID == nil || *tasks[0].SeriesID != series.ID {
+		t.Fatalf("expected one instance of the series, got %+v", tasks)
+	}
+
+	got, err := seriesRepo.GetById(ctx, series.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := series.NextRunAt.AddDate(0, 0, 1); !got.NextRunAt.Equal(want) {
+		t.Errorf("expected next run at %v, got %v", want, got.NextRunAt)
+	}
+}
+
+// Экземпляр, который не помещается в колонку доски предыдущего экземпляра, создаётся без этой доски
+func TestRecurrenceSchedulerRespectsWIPLimits(t *testing.T) {
+	ctx := context.Background()
+	store := memory_repo.NewStore()
+	seriesRepo := memory_repo.NewTaskSeriesMemoryRepo(store)
+	taskRepo := memory_repo.NewTaskMemoryRepo(store)
+	boardRepo := memory_repo.NewBoardMemoryRepo(store)
+	boardTaskRepo := memory_repo.NewBoardTaskMemoryRepo(store)
+	s := NewRecurrenceScheduler(seriesRepo, taskRepo, boardTaskRepo, memory_repo.NewUnitOfWork(store), time.Minute)
+
+	user := &models.User{Email: "alice@example.com"}
+	if err := memory_repo.NewUserMemoryRepo(store).Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	board := &models.Board{Name: "Sprint", UserID: user.ID}
+	if err := boardRepo.Create(ctx, board); err != nil {
+		t.Fatal(err)
+	}
+	if err := boardRepo.SetWIPLimits(ctx, board.ID, map[models.TaskStatus]int{models.StatusToDo: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	last := &models.Task{Title: "Daily standup", Status: models.StatusToDo, UserID: user.ID}
+	if err := taskRepo.Create(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+	if err := boardTaskRepo.AddTask(ctx, board.ID, last.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	series := &models.TaskSeries{
+		UserID:    user.ID,
+		Title:     "Daily standup",
+		Rule:      models.RecurrenceRule{Frequency: models.FrequencyDaily},
+		NextRunAt: now.Add(-time.Minute),
+	}
+	if err := seriesRepo.Create(ctx, series); err != nil {
+		t.Fatal(err)
+	}
+	if err := seriesRepo.AttachTask(ctx, series.ID, last.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RunDue(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := taskRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected new instance to be created, got %+v", tasks)
+	}
+	onBoard, err := boardTaskRepo.GetTasks(ctx, board.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(onBoard) != 1 || onBoard[0] != last.ID {
+		t.Errorf("expected only the previous instance on the board, got %v", onBoard)
+	}
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/go-playground/validator/v10"
+)
+
+/*
+Правила работы с досками
+Доску читают её участники, изменяет и меняет её состав только владелец.
+На доску можно добавить только свою задачу
+*/
+type BoardService struct {
+	boards       repository.BoardRepository
+	boardTasks   repository.BoardTaskRepository
+	tasks        repository.TaskRepository
+	customFields repository.CustomFieldRepository
+	uow          repository.UnitOfWork
+	// Кэш списков задач досок, nil - без кэша
+	cache     *cache.Cache
+	validator *validator.Validate
+}
+
+func NewBoardService(
+	boards repository.BoardRepository,
+	boardTasks repository.BoardTaskRepository,
+	tasks repository.TaskRepository,
+	customFields repository.CustomFieldRepository,
+	uow repository.UnitOfWork,
+	cache *cache.Cache,
+) *BoardService {
+	return &BoardService{
+		boards:       boards,
+		boardTasks:   boardTasks,
+		tasks:        tasks,
+		customFields: customFields,
+		uow:          uow,
+		cache:        cache,
+		validator:    NewValidator(),
+	}
+}
+
+func (s *BoardService) checkRequest(req *models.BoardRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	return validate(s.validator, req)
+}
+
+func (s *BoardService) Create(ctx context.Context, userID int, req models.BoardRequest) (*models.Board, error) {
+	if err := s.checkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	board := &models.Board{
+		Name:         req.Name,
+		UserID:       userID,
+		EstimateUnit: req.EstimateUnit,
+		WIPLimits:    req.WIPLimits,
+		CreatedAt:    time.Now(),
+		UpdateddAt:   time.Now(),
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.boards.Create(ctx, board); err != nil {
+			return err
+		}
+		if len(req.WIPLimits) == 0 {
+			return nil
+		}
+		return s.boards.SetWIPLimits(ctx, board.ID, req.WIPLimits)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return board, nil
+}
+
+// Доска вместе с WIP-лимитами
+func (s *BoardService) Get(ctx context.Context, userID, id int) (*models.Board, error) {
+	board, err := memberBoard(ctx, s.boards, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	board.WIPLimits, err = s.boards.GetWIPLimits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// WIP-лимиты заменяются, только если переданы в запросе, пустая единица оценки не меняет текущую
+func (s *BoardService) Update(ctx context.Context, userID, id, version int, req models.BoardRequest) (*models.Board, error) {
+	board, err := ownBoard(ctx, s.boards, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(board.Version, version, "board"); err != nil {
+		return nil, err
+	}
+	if err := s.checkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	return s.save(ctx, userID, board, req)
+}
+
+/*
+Частичное изменение доски по JSON Merge Patch
+WIP-лимиты сливаются по статусам, null снимает лимит; null в estimate_unit возвращает единицу по умолчанию
+*/
+func (s *BoardService) Patch(ctx context.Context, userID, id, version int, patch map[string]json.RawMessage) (*models.Board, error) {
+	board, err := ownBoard(ctx, s.boards, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(board.Version, version, "board"); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.boards.GetWIPLimits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := models.BoardRequest{
+		Name:         board.Name,
+		EstimateUnit: board.EstimateUnit,
+		WIPLimits:    limits,
+	}
+	changed, err := applyMergePatch(&req, board, patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return s.Get(ctx, userID, id)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if err := s.validator.StructPartial(req, changed...); err != nil {
+		return nil, apperr.Validation(err)
+	}
+
+	if req.EstimateUnit == "" {
+		req.EstimateUnit = models.EstimatePoints
+	}
+	// Лимиты перезаписываются, только если их меняет патч
+	if _, ok := patch["wip_limits"]; !ok {
+		req.WIPLimits = nil
+	} else if req.WIPLimits == nil {
+		req.WIPLimits = map[models.TaskStatus]int{}
+	}
+
+	return s.save(ctx, userID, board, req)
+}
+
+// Сохраняет проверенные изменения доски, WIP-лимиты заменяются, только если заданы
+func (s *BoardService) save(ctx context.Context, userID int, board *models.Board, req models.BoardRequest) (*models.Board, error) {
+	id := board.ID
+	board.Name = req.Name
+	board.UpdateddAt = time.Now()
+	if req.EstimateUnit != "" {
+		board.EstimateUnit = req.EstimateUnit
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.boards.Update(ctx, board); err != nil {
+			return err
+		}
+		if req.WIPLimits == nil {
+			return nil
+		}
+		return s.boards.SetWIPLimits(ctx, id, req.WIPLimits)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, userID, id)
+}
+
+func (s *BoardService) Delete(ctx context.Context, userID, id, version int) error {
+	board, err := ownBoard(ctx, s.boards, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(board.Version, version, "board"); err != nil {
+		return err
+	}
+	return s.boards.Delete(ctx, id, board.Version)
+}
+
+// Доски пользователя ownerID, список доступен только ему самому
+func (s *BoardService) ListByUser(ctx context.Context, userID, ownerID int) ([]*models.Board, error) {
+	if userID != ownerID {
+		return nil, accessDenied()
+	}
+	return s.boards.ListByUser(ctx, ownerID)
+}
+
+// Итоги по колонкам статусов: количество задач, сумма оценок и WIP-лимит
+func (s *BoardService) Summary(ctx context.Context, userID, id int) (*models.BoardSummary, error) {
+	board, err := memberBoard(ctx, s.boards, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := s.boards.Summary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BoardSummary{
+		EstimateUnit: board.EstimateUnit,
+		Columns:      columns,
+	}, nil
+}
+
+// Пользовательские поля доски
+func (s *BoardService) Fields(ctx context.Context, userID, id int) ([]*models.CustomField, error) {
+	if _, err := memberBoard(ctx, s.boards, userID, id); err != nil {
+		return nil, err
+	}
+	return s.customFields.ListByBoard(ctx, id)
+}
+
+// Задачи доски вместе со значениями пользовательских полей: два запроса независимо от числа задач
+func (s *BoardService) Tasks(ctx context.Context, userID, id int) ([]*models.Task, error) {
+	if _, err := memberBoard(ctx, s.boards, userID, id); err != nil {
+		return nil, err
+	}
+
+	return cache.Load(ctx, s.cache, cache.BoardTasks, id, func() ([]*models.Task, error) {
+		tasks, err := s.boardTasks.ListTasks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]int, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		values, err := s.customFields.GetValuesForTasks(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			task.CustomFields = values[task.ID]
+		}
+
+		return tasks, nil
+	})
+}
+
+func (s *BoardService) AddTask(ctx context.Context, userID, boardID, taskID int) error {
+	if _, err := ownBoard(ctx, s.boards, userID, boardID); err != nil {
+		return err
+	}
+	if _, err := ownTask(ctx, s.tasks, userID, taskID); err != nil {
+		return err
+	}
+	return s.boardTasks.AddTask(ctx, boardID, taskID)
+}
+
+func (s *BoardService) RemoveTask(ctx context.Context, userID, boardID, taskID int) error {
+	if _, err := ownBoard(ctx, s.boards, userID, boardID); err != nil {
+		return err
+	}
+	return s.boardTasks.RemoveTask(ctx, boardID, taskID)
+}
+
+// Переносит свою задачу между досками владельца с проверкой WIP-лимита целевой доски
+func (s *BoardService) MoveTask(ctx context.Context, userID, fromBoardID, toBoardID, taskID int) error {
+	return moveTask(ctx, s.boards, s.tasks, s.boardTasks, userID, fromBoardID, toBoardID, taskID)
+}
+
+// Общее правило переноса для одиночного запроса и пакета: обе доски и задача принадлежат пользователю
+func moveTask(ctx context.Context, boards repository.BoardRepository, tasks repository.TaskRepository, boardTasks repository.BoardTaskRepository, userID, fromBoardID, toBoardID, taskID int) error {
+	if _, err := ownBoard(ctx, boards, userID, fromBoardID); err != nil {
+		return err
+	}
+	if _, err := ownBoard(ctx, boards, userID, toBoardID); err != nil {
+		return err
+	}
+	if _, err := ownTask(ctx, tasks, userID, taskID); err != nil {
+		return err
+	}
+	return boardTasks.MoveTask(ctx, fromBoardID, toBoardID, taskID)
+}
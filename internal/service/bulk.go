@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+)
+
+// Итог одной операции пакета; Err заполнен у неудачной операции
+type BulkItemResult struct {
+	Index  int
+	Op     models.BulkOp
+	TaskID int
+	Status models.BulkItemStatus
+	Err    error
+}
+
+type BulkResult struct {
+	Mode models.BulkMode
+	// Транзакция пакета зафиксирована; в режиме atomic false означает, что ничего не изменилось
+	Committed bool
+	Items     []BulkItemResult
+}
+
+// Прерывает транзакцию пакета atomic после неудачной операции
+var errBulkAborted = errors.New("bulk operation aborted")
+
+/*
+Задачи пакета, загруженные одним запросом, чтобы проверка владельца не ходила в базу за каждой задачей
+Задачи, которых нет в пакете или которые изменила предыдущая операция, загружаются из репозитория
+*/
+type bulkTasks struct {
+	repository.TaskRepository
+	loaded map[int]*models.Task
+}
+
+func (s *TaskService) loadBulkTasks(ctx context.Context, ops []models.BulkOperation) (*bulkTasks, error) {
+	ids := make([]int, 0, len(ops))
+	for _, op := range ops {
+		if op.TaskID > 0 {
+			ids = append(ids, op.TaskID)
+		}
+	}
+	tasks, err := s.tasks.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[int]*models.Task, len(tasks))
+	for _, task := range tasks {
+		loaded[task.ID] = task
+	}
+	return &bulkTasks{TaskRepository: s.tasks, loaded: loaded}, nil
+}
+
+func (t *bulkTasks) GetById(ctx context.Context, id int) (*models.Task, error) {
+	if task, ok := t.loaded[id]; ok {
+		copied := *task
+		return &copied, nil
+	}
+	return t.TaskRepository.GetById(ctx, id)
+}
+
+/*
+Выполняет пакет операций над задачами пользователя в одной транзакции
+Каждая операция проверяется по тем же правилам, что и одиночный запрос, и выполняется в своей точке сохранения.
+В режиме atomic первая неудачная операция откатывает весь пакет, в режиме best_effort - только себя.
+Ошибки операций возвращаются в результатах, а сбой базы, таймаут или отмена запроса прерывают пакет целиком
+*/
+func (s *TaskService) Bulk(ctx context.Context, userID int, req models.BulkTaskRequest) (*BulkResult, error) {
+	if err := validate(s.validator, req); err != nil {
+		return nil, err
+	}
+	if req.Mode == "" {
+		req.Mode = models.BulkAtomic
+	}
+
+	result := &BulkResult{Mode: req.Mode, Items: make([]BulkItemResult, len(req.Operations))}
+	for i, op := range req.Operations {
+		result.Items[i] = BulkItemResult{Index: i, Op: op.Op, TaskID: op.TaskID, Status: models.BulkItemSkipped}
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		tasks, err := s.loadBulkTasks(ctx, req.Operations)
+		if err != nil {
+			return err
+		}
+
+		for i, op := range req.Operations {
+			item := &result.Items[i]
+
+			err := s.uow.Savepoint(ctx, func(ctx context.Context) error {
+				return s.applyBulk(ctx, userID, op, tasks)
+			})
+			if err == nil {
+				item.Status = models.BulkItemOK
+				// Статус и удаление меняют саму задачу, дальше она читается из репозитория
+				if op.Op == models.BulkSetStatus || op.Op == models.BulkDelete {
+					delete(tasks.loaded, op.TaskID)
+				}
+				continue
+			}
+			if !isClientError(err) {
+				return err
+			}
+
+			item.Status = models.BulkItemFailed
+			item.Err = err
+			if req.Mode == models.BulkAtomic {
+				return errBulkAborted
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBulkAborted):
+		for i := range result.Items {
+			if result.Items[i].Status == models.BulkItemOK {
+				result.Items[i].Status = models.BulkItemRolledBack
+			}
+		}
+		return result, nil
+	case err != nil:
+		return nil, err
+	}
+
+	result.Committed = true
+	return result, nil
+}
+
+func (s *TaskService) applyBulk(ctx context.Context, userID int, op models.BulkOperation, tasks repository.TaskRepository) error {
+	if op.TaskID <= 0 {
+		return missingField("task_id")
+	}
+
+	switch op.Op {
+	case models.BulkSetStatus:
+		if op.Status == "" {
+			return missingField("status")
+		}
+		_, err := s.UpdateStatus(ctx, userID, op.TaskID, op.Version, op.Status)
+		return err
+
+	case models.BulkDelete:
+		return s.Delete(ctx, userID, op.TaskID, op.Version)
+
+	case models.BulkAddToBoard:
+		if op.BoardID <= 0 {
+			return missingField("board_id")
+		}
+		if _, err := ownBoard(ctx, s.boards, userID, op.BoardID); err != nil {
+			return err
+		}
+		if _, err := ownTask(ctx, tasks, userID, op.TaskID); err != nil {
+			return err
+		}
+		return s.boardTasks.AddTask(ctx, op.BoardID, op.TaskID)
+
+	case models.BulkRemoveFromBoard:
+		if op.BoardID <= 0 {
+			return missingField("board_id")
+		}
+		if _, err := ownBoard(ctx, s.boards, userID, op.BoardID); err != nil {
+			return err
+		}
+		return s.boardTasks.RemoveTask(ctx, op.BoardID, op.TaskID)
+
+	case models.BulkMove:
+		if op.FromBoardID <= 0 {
+			return missingField("from_board_id")
+		}
+		if op.ToBoardID <= 0 {
+			return missingField("to_board_id")
+		}
+		return moveTask(ctx, s.boards, tasks, s.boardTasks, userID, op.FromBoardID, op.ToBoardID, op.TaskID)
+
+	case models.BulkLabel, models.BulkUnlabel:
+		return s.applyLabels(ctx, userID, op, tasks)
+	}
+
+	return apperr.InvalidFields([]apperr.FieldError{{
+		Field:   "op",
+		Rule:    "oneof",
+		Message: fmt.Sprintf("op %q is not a known operation", op.Op),
+	}})
+}
+
+// Добавляет метки в поле типа multi_select или убирает их оттуда, остальные метки задачи сохраняются
+func (s *TaskService) applyLabels(ctx context.Context, userID int, op models.BulkOperation, tasks repository.TaskRepository) error {
+	if op.FieldID <= 0 {
+		return missingField("field_id")
+	}
+	if len(op.Labels) == 0 {
+		return missingField("labels")
+	}
+	if _, err := ownTask(ctx, tasks, userID, op.TaskID); err != nil {
+		return err
+	}
+
+	fields, err := s.customFields.ListForTask(ctx, op.TaskID)
+	if err != nil {
+		return err
+	}
+	var field *models.CustomField
+	for _, f := range fields {
+		if f.ID == op.FieldID {
+			field = f
+		}
+	}
+	if field == nil {
+		return apperr.Validation(fmt.Errorf("field %d is not defined on the task's boards", op.FieldID))
+	}
+	if field.Type != models.FieldMultiSelect {
+		return apperr.Validation(fmt.Errorf("field %q is not a multi_select field", field.Name))
+	}
+
+	current, err := s.customFields.GetValues(ctx, op.TaskID)
+	if err != nil {
+		return err
+	}
+	var value json.RawMessage
+	for _, v := range current {
+		if v.FieldID == field.ID {
+			value = v.Value
+		}
+	}
+
+	merged := models.MergeLabels(value, op.Labels, op.Op == models.BulkUnlabel)
+	values, err := s.normalizeFieldValues(ctx, fields, map[int]json.RawMessage{field.ID: merged})
+	if err != nil {
+		return err
+	}
+	return s.customFields.SetValues(ctx, op.TaskID, values)
+}
+
+func missingField(name string) error {
+	return apperr.InvalidFields([]apperr.FieldError{{Field: name, Rule: "required", Message: name + " is required"}})
+}
+
+// Ошибка вызвана самой операцией, а не сбоем базы или отменой запроса
+func isClientError(err error) bool {
+	switch apperr.From(err).Code {
+	case apperr.CodeInternal, apperr.CodeTimeout, apperr.CodeCanceled:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+)
+
+/*
+Применяет JSON Merge Patch (RFC 7396) к запросу на изменение записи
+req заполнен текущими значениями записи, patch заменяет переданные поля, null удаляет значение,
+вложенные объекты сливаются по тем же правилам. Поля записи, которых нет в запросе, клиент не меняет:
+они отклоняются как read_only, остальные незнакомые поля - как unknown.
+Возвращает имена изменённых полей структуры req, чтобы проверить только их
+*/
+func applyMergePatch(req interface{}, record interface{}, patch map[string]json.RawMessage) ([]string, error) {
+	mutable := jsonFields(req)
+	readOnly := jsonFields(record)
+
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var rejected []apperr.FieldError
+	changed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if name, ok := mutable[key]; ok {
+			changed = append(changed, name)
+			continue
+		}
+		if _, ok := readOnly[key]; ok {
+			rejected = append(rejected, apperr.FieldError{Field: key, Rule: "read_only", Message: key + " cannot be changed"})
+			continue
+		}
+		rejected = append(rejected, apperr.FieldError{Field: key, Rule: "unknown", Message: key + " is not a known field"})
+	}
+	if len(rejected) > 0 {
+		return nil, apperr.InvalidFields(rejected)
+	}
+
+	current, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(current, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		var value interface{}
+		if err := json.Unmarshal(patch[key], &value); err != nil {
+			return nil, apperr.BadRequest("Invalid merge patch")
+		}
+		if value == nil {
+			delete(doc, key)
+			continue
+		}
+		doc[key] = mergeValue(doc[key], value)
+	}
+
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	target := reflect.ValueOf(req).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	if err := json.Unmarshal(merged, req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			field := strings.Split(typeErr.Field, ".")[0]
+			return nil, apperr.InvalidFields([]apperr.FieldError{{
+				Field:   field,
+				Rule:    "type",
+				Message: field + " must be " + jsonType(typeErr.Type),
+			}})
+		}
+		return nil, apperr.BadRequest("Invalid merge patch")
+	}
+
+	return changed, nil
+}
+
+// Слияние значения по RFC 7396: объект дополняет объект, любое другое значение заменяет текущее
+func mergeValue(target, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = make(map[string]interface{}, len(fields))
+	}
+	for key, value := range fields {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = mergeValue(result[key], value)
+	}
+	return result
+}
+
+// Тип JSON, в который декодируется значение типа Go
+func jsonType(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return typ.String()
+}
+
+// Имена полей структуры по тегу json
+func jsonFields(v interface{}) map[string]string {
+	typ := reflect.TypeOf(v)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	fields := make(map[string]string, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field.Name
+	}
+	return fields
+}
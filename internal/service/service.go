@@ -0,0 +1,94 @@
+/*
+Бизнес-правила трекера: проверка данных, права доступа и побочные эффекты
+
+Обработчики API и веб-интерфейса только разбирают запрос и отображают результат,
+поэтому оба транспорта применяют одни и те же правила. Нарушения возвращаются как *apperr.Error,
+ошибки репозиториев передаются как есть и приводятся к ответу через apperr.From
+*/
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/go-playground/validator/v10"
+)
+
+// Валидатор, который называет поля в ошибках по тегу json, как их видит клиент
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+func validate(v *validator.Validate, s interface{}) error {
+	if err := v.Struct(s); err != nil {
+		return apperr.Validation(err)
+	}
+	return nil
+}
+
+func accessDenied() error {
+	return apperr.Forbidden("Access denied")
+}
+
+// Задача доступна только своему владельцу
+func ownTask(ctx context.Context, tasks repository.TaskRepository, userID, id int) (*models.Task, error) {
+	task, err := tasks.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.UserID != userID {
+		return nil, accessDenied()
+	}
+	return task, nil
+}
+
+/*
+Изменение допускается только для той версии записи, которую видел клиент; 0 - версия не передана
+Гонку между чтением и записью ловит репозиторий, сравнивая версию в том же запросе
+*/
+func checkVersion(current, expected int, entity string) error {
+	if expected != 0 && expected != current {
+		return repository.Stale(entity)
+	}
+	return nil
+}
+
+// Изменять доску и её состав может только владелец
+func ownBoard(ctx context.Context, boards repository.BoardRepository, userID, id int) (*models.Board, error) {
+	board, err := boards.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if board.UserID != userID {
+		return nil, accessDenied()
+	}
+	return board, nil
+}
+
+// Читать доску могут её участники: владелец и владельцы задач на ней
+func memberBoard(ctx context.Context, boards repository.BoardRepository, userID, id int) (*models.Board, error) {
+	board, err := boards.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := boards.IsMember(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, accessDenied()
+	}
+	return board, nil
+}
@@ -0,0 +1,527 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/auth"
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	memory_repo "github.com/CAATHARSIS/task-tracking/internal/repository/memory"
+	"github.com/CAATHARSIS/task-tracking/internal/service"
+)
+
+// Сервисы поверх общего хранилища в памяти
+type services struct {
+	tasks  *service.TaskService
+	boards *service.BoardService
+	users  *service.UserService
+	fields *memory_repo.CustomFieldMemoryRepo
+}
+
+func newServices(t *testing.T) *services {
+	t.Helper()
+
+	store := memory_repo.NewStore()
+	boardRepo := memory_repo.NewBoardMemoryRepo(store)
+	boardTaskRepo := memory_repo.NewBoardTaskMemoryRepo(store)
+	taskRepo := memory_repo.NewTaskMemoryRepo(store)
+	userRepo := memory_repo.NewUserMemoryRepo(store)
+	seriesRepo := memory_repo.NewTaskSeriesMemoryRepo(store)
+	customFieldRepo := memory_repo.NewCustomFieldMemoryRepo(store)
+	uow := memory_repo.NewUnitOfWork(store)
+	jwt := auth.NewJWTService(&config.Config{JWTSecret: "test-secret"})
+
+	return &services{
+		tasks:  service.NewTaskService(taskRepo, boardRepo, boardTaskRepo, seriesRepo, customFieldRepo, userRepo, uow, nil),
+		boards: service.NewBoardService(boardRepo, boardTaskRepo, taskRepo, customFieldRepo, uow, nil),
+		users:  service.NewUserService(userRepo, jwt),
+		fields: customFieldRepo,
+	}
+}
+
+func (s *services) register(t *testing.T, email string) int {
+	t.Helper()
+	user, err := s.users.Register(context.Background(), email, "secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func TestTaskRules(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+	bob := s.register(t, "bob@example.com")
+
+	_, err := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "  ", Status: models.StatusToDo}, nil)
+	expectCode(t, err, apperr.CodeValidation)
+
+	task, err := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "  Write tests ", Status: models.StatusToDo}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Title != "Write tests" || task.UserID != alice {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	_, err = s.tasks.Get(ctx, bob, task.ID)
+	expectCode(t, err, apperr.CodeForbidden)
+	_, err = s.tasks.Update(ctx, bob, task.ID, 0, models.TaskRequest{Title: "Stolen", Status: models.StatusDone}, service.TaskUpdateOptions{})
+	expectCode(t, err, apperr.CodeForbidden)
+	_, err = s.tasks.UpdateStatus(ctx, bob, task.ID, 0, models.StatusDone)
+	expectCode(t, err, apperr.CodeForbidden)
+	expectCode(t, s.tasks.Delete(ctx, bob, task.ID, 0), apperr.CodeForbidden)
+	_, err = s.tasks.ListByUser(ctx, bob, alice)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	_, err = s.tasks.Get(ctx, alice, task.ID+100)
+	expectCode(t, err, apperr.CodeNotFound)
+
+	// Полное изменение задачи проверяет WIP-лимит так же, как смена статуса
+	board, err := s.boards.Create(ctx, alice, models.BoardRequest{
+		Name:      "Sprint",
+		WIPLimits: map[models.TaskStatus]int{models.StatusInProgres: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy, err := s.tasks.CreateOnBoard(ctx, alice, board.ID, models.TaskRequest{Title: "Busy", Status: models.StatusInProgres}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.boards.AddTask(ctx, alice, board.ID, task.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Задача, которая не помещается в колонку, не создаётся и на доску не добавляется
+	_, err = s.tasks.CreateOnBoard(ctx, alice, board.ID, models.TaskRequest{Title: "Overflow", Status: models.StatusInProgres}, nil)
+	expectCode(t, err, apperr.CodeConflict)
+	if tasks, err := s.tasks.ListByUser(ctx, alice, alice); err != nil || len(tasks) != 2 {
+		t.Fatalf("expected overflowing task to be rolled back, got %d tasks, %v", len(tasks), err)
+	}
+	overflow, err := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "Overflow", Status: models.StatusInProgres}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectCode(t, s.boards.AddTask(ctx, alice, board.ID, overflow.ID), apperr.CodeConflict)
+
+	_, err = s.tasks.Update(ctx, alice, task.ID, 0, models.TaskRequest{Title: "Write tests", Status: models.StatusInProgres}, service.TaskUpdateOptions{})
+	expectCode(t, err, apperr.CodeConflict)
+	_, err = s.tasks.UpdateStatus(ctx, alice, task.ID, 0, models.StatusInProgres)
+	expectCode(t, err, apperr.CodeConflict)
+
+	if err := s.tasks.Delete(ctx, alice, busy.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := s.tasks.Update(ctx, alice, task.ID, 0, models.TaskRequest{Title: "Write tests", Status: models.StatusInProgres}, service.TaskUpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != models.StatusInProgres || updated.UserID != alice {
+		t.Fatalf("unexpected task %+v", updated)
+	}
+}
+
+func TestTaskVersions(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+
+	task, err := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "Write tests", Status: models.StatusToDo}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Version != 1 {
+		t.Fatalf("expected new task to have version 1, got %d", task.Version)
+	}
+
+	updated, err := s.tasks.Update(ctx, alice, task.ID, task.Version, models.TaskRequest{Title: "Write more tests", Status: models.StatusToDo}, service.TaskUpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("expected version 2 after update, got %d", updated.Version)
+	}
+
+	// Клиент, который видел первую версию, не затирает чужие изменения
+	_, err = s.tasks.Update(ctx, alice, task.ID, task.Version, models.TaskRequest{Title: "Stale title", Status: models.StatusDone}, service.TaskUpdateOptions{})
+	expectCode(t, err, apperr.CodePreconditionFailed)
+	_, err = s.tasks.UpdateStatus(ctx, alice, task.ID, task.Version, models.StatusDone)
+	expectCode(t, err, apperr.CodePreconditionFailed)
+	expectCode(t, s.tasks.Delete(ctx, alice, task.ID, task.Version), apperr.CodePreconditionFailed)
+
+	current, err := s.tasks.Get(ctx, alice, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Title != "Write more tests" || current.Status != models.StatusToDo {
+		t.Fatalf("stale write changed task %+v", current)
+	}
+
+	if err := s.tasks.Delete(ctx, alice, task.ID, current.Version); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+
+	estimate := 3.0
+	task, err := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "Write tests", Description: "Cover services", Status: models.StatusToDo, Estimate: &estimate}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(body string) map[string]json.RawMessage {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(body), &fields); err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+
+	// Непереданные поля сохраняют значения
+	task, err = s.tasks.Patch(ctx, alice, task.ID, 0, patch(`{"status": "in_progress"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != models.StatusInProgres || task.Description != "Cover services" || task.Estimate == nil || *task.Estimate != 3 {
+		t.Fatalf("unexpected task after patch %+v", task)
+	}
+
+	task, err = s.tasks.Patch(ctx, alice, task.ID, 0, patch(`{"description": null, "estimate": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Description != "" || task.Estimate != nil || task.Title != "Write tests" {
+		t.Fatalf("expected description and estimate to be cleared, got %+v", task)
+	}
+
+	for body, field := range map[string]string{
+		`{"user_id": 2}`:         "user_id",
+		`{"version": 7}`:         "version",
+		`{"owner": "bob"}`:       "owner",
+		`{"title": null}`:        "title",
+		`{"title": "x"}`:         "title",
+		`{"title": 5}`:           "title",
+		`{"status": "archived"}`: "status",
+	} {
+		_, err := s.tasks.Patch(ctx, alice, task.ID, 0, patch(body))
+		expectCode(t, err, apperr.CodeValidation)
+		if fields := apperr.From(err).Fields; len(fields) != 1 || fields[0].Field != field {
+			t.Fatalf("%s: expected single error for %s, got %+v", body, field, fields)
+		}
+	}
+
+	board, err := s.boards.Create(ctx, alice, models.BoardRequest{
+		Name:      "Sprint",
+		WIPLimits: map[models.TaskStatus]int{models.StatusToDo: 5, models.StatusInProgres: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Лимиты сливаются по статусам, null снимает лимит
+	board, err = s.boards.Patch(ctx, alice, board.ID, 0, patch(`{"wip_limits": {"todo": null, "done": 10}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[models.TaskStatus]int{models.StatusInProgres: 2, models.StatusDone: 10}
+	if board.Name != "Sprint" || len(board.WIPLimits) != len(want) ||
+		board.WIPLimits[models.StatusInProgres] != 2 || board.WIPLimits[models.StatusDone] != 10 {
+		t.Fatalf("unexpected board after patch %+v", board)
+	}
+
+	board, err = s.boards.Patch(ctx, alice, board.ID, 0, patch(`{"name": "Sprint 2", "estimate_unit": "hours"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.Name != "Sprint 2" || board.EstimateUnit != models.EstimateHours || len(board.WIPLimits) != 2 {
+		t.Fatalf("unexpected board after patch %+v", board)
+	}
+
+	_, err = s.boards.Patch(ctx, alice, board.ID, 0, patch(`{"user_id": 2}`))
+	expectCode(t, err, apperr.CodeValidation)
+	_, err = s.boards.Patch(ctx, alice, board.ID, 0, patch(`{"wip_limits": {"todo": 0}}`))
+	expectCode(t, err, apperr.CodeValidation)
+}
+
+func TestTaskCustomFields(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+
+	board, err := s.boards.Create(ctx, alice, models.BoardRequest{Name: "Sprint"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := &models.CustomField{BoardID: board.ID, Name: "Points", Type: models.FieldNumber}
+	owner := &models.CustomField{BoardID: board.ID, Name: "Owner", Type: models.FieldUser, Required: true}
+	for _, field := range []*models.CustomField{points, owner} {
+		if err := s.fields.Create(ctx, field); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := models.TaskRequest{Title: "Estimate", Status: models.StatusToDo}
+
+	_, err = s.tasks.CreateOnBoard(ctx, alice, board.ID, request, map[int]json.RawMessage{points.ID: json.RawMessage(`"many"`)})
+	expectCode(t, err, apperr.CodeValidation)
+	_, err = s.tasks.CreateOnBoard(ctx, alice, board.ID, request, map[int]json.RawMessage{owner.ID: json.RawMessage(`999`)})
+	expectCode(t, err, apperr.CodeValidation)
+	_, err = s.tasks.CreateOnBoard(ctx, alice, board.ID, request, map[int]json.RawMessage{owner.ID + 100: json.RawMessage(`1`)})
+	expectCode(t, err, apperr.CodeValidation)
+	// Обязательное поле доски нельзя пропустить при создании задачи
+	_, err = s.tasks.CreateOnBoard(ctx, alice, board.ID, request, map[int]json.RawMessage{points.ID: json.RawMessage(`3`)})
+	expectCode(t, err, apperr.CodeValidation)
+	if err == nil || !strings.Contains(err.Error(), `field "Owner" is required`) {
+		t.Errorf("expected missing required field error, got %v", err)
+	}
+
+	// Отклонённые значения не оставляют задачу без доски
+	tasks, err := s.tasks.ListByUser(ctx, alice, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Fatalf("expected no tasks after rejected creation, got %d", len(tasks))
+	}
+
+	task, err := s.tasks.CreateOnBoard(ctx, alice, board.ID, request, map[int]json.RawMessage{
+		points.ID: json.RawMessage(`3`),
+		owner.ID:  json.RawMessage(`1`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = s.tasks.SetCustomFields(ctx, alice, task.ID, map[int]json.RawMessage{points.ID: json.RawMessage(`null`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(task.CustomFields) != 1 || task.CustomFields[0].FieldID != owner.ID {
+		t.Fatalf("expected only owner value to remain, got %+v", task.CustomFields)
+	}
+}
+
+func TestBulkTasks(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+	bob := s.register(t, "bob@example.com")
+
+	board, err := s.boards.Create(ctx, alice, models.BoardRequest{Name: "Sprint"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := &models.CustomField{BoardID: board.ID, Name: "Labels", Type: models.FieldMultiSelect, Options: []string{"bug", "docs"}}
+	if err := s.fields.Create(ctx, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	milk, _ := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "Buy milk", Status: models.StatusToDo}, nil)
+	docs, _ := s.tasks.CreateOnBoard(ctx, alice, board.ID, models.TaskRequest{Title: "Write docs", Status: models.StatusToDo}, nil)
+	stolen, _ := s.tasks.Create(ctx, bob, models.TaskRequest{Title: "Bob's task", Status: models.StatusToDo}, nil)
+
+	operations := []models.BulkOperation{
+		{Op: models.BulkSetStatus, TaskID: milk.ID, Status: models.StatusDone},
+		{Op: models.BulkAddToBoard, TaskID: milk.ID, BoardID: board.ID},
+		{Op: models.BulkLabel, TaskID: docs.ID, FieldID: labels.ID, Labels: []string{"docs", "bug"}},
+		{Op: models.BulkSetStatus, TaskID: stolen.ID, Status: models.StatusDone},
+		{Op: models.BulkDelete, TaskID: docs.ID},
+	}
+
+	// В режиме atomic чужая задача откатывает весь пакет
+	result, err := s.tasks.Bulk(ctx, alice, models.BulkTaskRequest{Operations: operations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatuses(t, result, models.BulkItemRolledBack, models.BulkItemRolledBack, models.BulkItemRolledBack, models.BulkItemFailed, models.BulkItemSkipped)
+	if result.Committed {
+		t.Fatal("expected atomic batch not to be committed")
+	}
+	expectCode(t, result.Items[3].Err, apperr.CodeForbidden)
+	if task, _ := s.tasks.Get(ctx, alice, milk.ID); task.Status != models.StatusToDo {
+		t.Fatalf("expected status change to be rolled back, got %s", task.Status)
+	}
+
+	// В режиме best_effort откатывается только неудачная операция
+	result, err = s.tasks.Bulk(ctx, alice, models.BulkTaskRequest{Mode: models.BulkBestEffort, Operations: operations[:4]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatuses(t, result, models.BulkItemOK, models.BulkItemOK, models.BulkItemOK, models.BulkItemFailed)
+	tasks, err := s.boards.Tasks(ctx, alice, board.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected both tasks on the board, got %d", len(tasks))
+	}
+	task, _ := s.tasks.Get(ctx, alice, docs.ID)
+	if len(task.CustomFields) != 1 || string(task.CustomFields[0].Value) != `["bug","docs"]` {
+		t.Fatalf("expected labels to be set, got %+v", task.CustomFields)
+	}
+
+	result, err = s.tasks.Bulk(ctx, alice, models.BulkTaskRequest{Mode: models.BulkBestEffort, Operations: []models.BulkOperation{
+		{Op: models.BulkUnlabel, TaskID: docs.ID, FieldID: labels.ID, Labels: []string{"bug"}},
+		{Op: models.BulkLabel, TaskID: docs.ID, FieldID: labels.ID, Labels: []string{"urgent"}},
+		{Op: models.BulkSetStatus, TaskID: docs.ID},
+		{Op: "archive", TaskID: docs.ID},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatuses(t, result, models.BulkItemOK, models.BulkItemFailed, models.BulkItemFailed, models.BulkItemFailed)
+	for _, item := range result.Items[1:] {
+		expectCode(t, item.Err, apperr.CodeValidation)
+	}
+	task, _ = s.tasks.Get(ctx, alice, docs.ID)
+	if len(task.CustomFields) != 1 || string(task.CustomFields[0].Value) != `["docs"]` {
+		t.Fatalf("expected only docs label to remain, got %+v", task.CustomFields)
+	}
+
+	// Задачи пакета загружаются заранее, но удалённая в пакете и несуществующая задача не находятся
+	result, err = s.tasks.Bulk(ctx, alice, models.BulkTaskRequest{Mode: models.BulkBestEffort, Operations: []models.BulkOperation{
+		{Op: models.BulkDelete, TaskID: milk.ID},
+		{Op: models.BulkLabel, TaskID: milk.ID, FieldID: labels.ID, Labels: []string{"bug"}},
+		{Op: models.BulkAddToBoard, TaskID: milk.ID + 1000, BoardID: board.ID},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatuses(t, result, models.BulkItemOK, models.BulkItemFailed, models.BulkItemFailed)
+	for _, item := range result.Items[1:] {
+		expectCode(t, item.Err, apperr.CodeNotFound)
+	}
+
+	_, err = s.tasks.Bulk(ctx, alice, models.BulkTaskRequest{Mode: "sometimes", Operations: operations})
+	expectCode(t, err, apperr.CodeValidation)
+	_, err = s.tasks.Bulk(ctx, alice, models.BulkTaskRequest{})
+	expectCode(t, err, apperr.CodeValidation)
+}
+
+func expectStatuses(t *testing.T, result *service.BulkResult, statuses ...models.BulkItemStatus) {
+	t.Helper()
+	if len(result.Items) != len(statuses) {
+		t.Fatalf("expected %d results, got %d", len(statuses), len(result.Items))
+	}
+	for i, item := range result.Items {
+		if item.Status != statuses[i] {
+			t.Fatalf("expected operation %d to be %s, got %s (%v)", i, statuses[i], item.Status, item.Err)
+		}
+	}
+}
+
+func TestBoardRules(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+	bob := s.register(t, "bob@example.com")
+
+	_, err := s.boards.Create(ctx, alice, models.BoardRequest{Name: "  "})
+	expectCode(t, err, apperr.CodeValidation)
+
+	board, err := s.boards.Create(ctx, alice, models.BoardRequest{Name: "Sprint"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.boards.Create(ctx, bob, models.BoardRequest{Name: "Backlog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.boards.Get(ctx, bob, board.ID)
+	expectCode(t, err, apperr.CodeForbidden)
+	_, err = s.boards.ListByUser(ctx, bob, alice)
+	expectCode(t, err, apperr.CodeForbidden)
+
+	bobTask, err := s.tasks.Create(ctx, bob, models.TaskRequest{Title: "Bob's task", Status: models.StatusToDo}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliceTask, err := s.tasks.Create(ctx, alice, models.TaskRequest{Title: "Alice's task", Status: models.StatusToDo}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// На свою доску нельзя добавить чужую задачу, на чужую доску - свою
+	expectCode(t, s.boards.AddTask(ctx, alice, board.ID, bobTask.ID), apperr.CodeForbidden)
+	expectCode(t, s.boards.AddTask(ctx, alice, other.ID, aliceTask.ID), apperr.CodeForbidden)
+	expectCode(t, s.boards.MoveTask(ctx, alice, board.ID, other.ID, aliceTask.ID), apperr.CodeForbidden)
+
+	// Изменять чужую доску и её состав нельзя
+	if err := s.boards.AddTask(ctx, bob, other.ID, bobTask.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.boards.Update(ctx, alice, other.ID, 0, models.BoardRequest{Name: "Renamed"})
+	expectCode(t, err, apperr.CodeForbidden)
+	expectCode(t, s.boards.RemoveTask(ctx, alice, other.ID, bobTask.ID), apperr.CodeForbidden)
+	expectCode(t, s.boards.Delete(ctx, alice, other.ID, 0), apperr.CodeForbidden)
+
+	updated, err := s.boards.Update(ctx, alice, board.ID, 0, models.BoardRequest{
+		Name:      "Sprint 2",
+		WIPLimits: map[models.TaskStatus]int{models.StatusToDo: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Sprint 2" || updated.WIPLimits[models.StatusToDo] != 3 {
+		t.Fatalf("unexpected board %+v", updated)
+	}
+
+	_, err = s.boards.Update(ctx, alice, board.ID, board.Version, models.BoardRequest{Name: "Stale"})
+	expectCode(t, err, apperr.CodePreconditionFailed)
+	expectCode(t, s.boards.Delete(ctx, alice, board.ID, board.Version), apperr.CodePreconditionFailed)
+}
+
+func TestUserRules(t *testing.T) {
+	ctx := context.Background()
+	s := newServices(t)
+	alice := s.register(t, "alice@example.com")
+	bob := s.register(t, "bob@example.com")
+
+	_, err := s.users.Register(ctx, "alice@example.com", "secret123")
+	expectCode(t, err, apperr.CodeConflict)
+	_, err = s.users.Register(ctx, "alice", "123")
+	expectCode(t, err, apperr.CodeValidation)
+
+	_, err = s.users.Login(ctx, "alice@example.com", "wrong")
+	expectCode(t, err, apperr.CodeUnauthorized)
+	_, err = s.users.Login(ctx, "carol@example.com", "secret123")
+	expectCode(t, err, apperr.CodeUnauthorized)
+	if token, err := s.users.Login(ctx, "alice@example.com", "secret123"); err != nil || token == "" {
+		t.Fatalf("expected token, got %q, %v", token, err)
+	}
+
+	_, err = s.users.Get(ctx, bob, alice)
+	expectCode(t, err, apperr.CodeForbidden)
+	expectCode(t, s.users.Update(ctx, bob, alice, "", "hijacked"), apperr.CodeForbidden)
+	expectCode(t, s.users.Delete(ctx, bob, alice), apperr.CodeForbidden)
+	expectCode(t, s.users.Update(ctx, alice, alice, "bob@example.com", ""), apperr.CodeConflict)
+
+	user, err := s.users.Get(ctx, alice, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.PasswordHash != "" {
+		t.Fatal("password hash must not leave the service")
+	}
+}
+
+func expectCode(t *testing.T, err error, code apperr.Code) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected %s error, got nil", code)
+	}
+	if got := apperr.From(err).Code; got != code {
+		t.Fatalf("expected %s error, got %s: %v", code, got, err)
+	}
+}
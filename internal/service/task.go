@@ -0,0 +1,500 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/cache"
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/go-playground/validator/v10"
+)
+
+/*
+Правила работы с задачами
+Задачу читает и изменяет только её владелец, владелец задачи не меняется.
+Смена статуса проверяется по WIP-лимитам всех досок задачи в той же транзакции, что и изменение
+*/
+type TaskService struct {
+	tasks        repository.TaskRepository
+	boards       repository.BoardRepository
+	boardTasks   repository.BoardTaskRepository
+	series       repository.TaskSeriesRepository
+	customFields repository.CustomFieldRepository
+	users        repository.UserRepository
+	uow          repository.UnitOfWork
+	// Кэш списков задач пользователя, nil - без кэша
+	cache     *cache.Cache
+	validator *validator.Validate
+}
+
+func NewTaskService(
+	tasks repository.TaskRepository,
+	boards repository.BoardRepository,
+	boardTasks repository.BoardTaskRepository,
+	series repository.TaskSeriesRepository,
+	customFields repository.CustomFieldRepository,
+	users repository.UserRepository,
+	uow repository.UnitOfWork,
+	cache *cache.Cache,
+) *TaskService {
+	return &TaskService{
+		tasks:        tasks,
+		boards:       boards,
+		boardTasks:   boardTasks,
+		series:       series,
+		customFields: customFields,
+		users:        users,
+		uow:          uow,
+		cache:        cache,
+		validator:    NewValidator(),
+	}
+}
+
+// Дополнительные изменения, которые сохраняются вместе с задачей
+type TaskUpdateOptions struct {
+	// Значения пользовательских полей по ID поля, nil - значения не меняются
+	CustomFields map[int]json.RawMessage
+	// Перенести название и описание на серию, к которой относится задача
+	ApplyToSeries bool
+}
+
+func (s *TaskService) checkRequest(req *models.TaskRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+	return validate(s.validator, req)
+}
+
+/*
+Создаёт задачу пользователя
+Если задано правило повторения, задача становится первым экземпляром новой серии
+*/
+func (s *TaskService) Create(ctx context.Context, userID int, req models.TaskRequest, rule *models.RecurrenceRule) (*models.Task, error) {
+	if err := s.checkRequest(&req); err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		if err := validate(s.validator, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	task := newTask(userID, req)
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.tasks.Create(ctx, task); err != nil {
+			return err
+		}
+		countTaskCreated(ctx)
+		if rule == nil {
+			return nil
+		}
+
+		series := models.TaskSeries{
+			UserID:      userID,
+			Title:       task.Title,
+			Description: task.Description,
+			Rule:        *rule,
+			NextRunAt:   rule.Next(time.Now()),
+		}
+		if err := s.series.Create(ctx, &series); err != nil {
+			return err
+		}
+		return s.series.AttachTask(ctx, series.ID, task.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Создаёт задачу сразу на доске владельца вместе со значениями пользовательских полей доски
+func (s *TaskService) CreateOnBoard(
+	ctx context.Context,
+	userID, boardID int,
+	req models.TaskRequest,
+	values map[int]json.RawMessage,
+) (*models.Task, error) {
+	if _, err := ownBoard(ctx, s.boards, userID, boardID); err != nil {
+		return nil, err
+	}
+	if err := s.checkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	fields, err := s.customFields.ListByBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	values, err = s.normalizeFieldValues(ctx, fields, values)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireFieldValues(fields, values); err != nil {
+		return nil, err
+	}
+
+	// Задача без доски не должна остаться, если добавление на доску не удалось
+	task := newTask(userID, req)
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.tasks.Create(ctx, task); err != nil {
+			return err
+		}
+		countTaskCreated(ctx)
+		if err := s.boardTasks.AddTask(ctx, boardID, task.ID); err != nil {
+			return err
+		}
+		return s.customFields.SetValues(ctx, task.ID, values)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Задача вместе со значениями пользовательских полей
+func (s *TaskService) Get(ctx context.Context, userID, id int) (*models.Task, error) {
+	task, err := ownTask(ctx, s.tasks, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachCustomFields(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// Пользовательские поля досок, на которых находится задача
+func (s *TaskService) Fields(ctx context.Context, userID, id int) ([]*models.CustomField, error) {
+	if _, err := ownTask(ctx, s.tasks, userID, id); err != nil {
+		return nil, err
+	}
+	return s.customFields.ListForTask(ctx, id)
+}
+
+func (s *TaskService) Update(
+	ctx context.Context,
+	userID, id, version int,
+	req models.TaskRequest,
+	opts TaskUpdateOptions,
+) (*models.Task, error) {
+	task, err := ownTask(ctx, s.tasks, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(task.Version, version, "task"); err != nil {
+		return nil, err
+	}
+	if err := s.checkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	return s.save(ctx, userID, task, req, opts)
+}
+
+/*
+Частичное изменение задачи по JSON Merge Patch
+Меняются только переданные поля, и проверяются тоже только они; владелец, серия и служебные поля не меняются
+*/
+func (s *TaskService) Patch(ctx context.Context, userID, id, version int, patch map[string]json.RawMessage) (*models.Task, error) {
+	task, err := ownTask(ctx, s.tasks, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(task.Version, version, "task"); err != nil {
+		return nil, err
+	}
+
+	req := models.TaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		Estimate:    task.Estimate,
+	}
+	changed, err := applyMergePatch(&req, task, patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 {
+		return s.Get(ctx, userID, id)
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+	if err := s.validator.StructPartial(req, changed...); err != nil {
+		return nil, apperr.Validation(err)
+	}
+
+	return s.save(ctx, userID, task, req, TaskUpdateOptions{})
+}
+
+// Сохраняет проверенные изменения задачи вместе с дополнительными изменениями из opts
+func (s *TaskService) save(ctx context.Context, userID int, task *models.Task, req models.TaskRequest, opts TaskUpdateOptions) (*models.Task, error) {
+	id := task.ID
+
+	var values map[int]json.RawMessage
+	if opts.CustomFields != nil {
+		fields, err := s.customFields.ListForTask(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if values, err = s.normalizeFieldValues(ctx, fields, opts.CustomFields); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := task.Status
+	task.Title = req.Title
+	task.Description = req.Description
+	task.Status = req.Status
+	task.Estimate = req.Estimate
+	task.UpdatedAt = time.Now()
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.tasks.Update(ctx, task); err != nil {
+			return err
+		}
+		if err := s.checkLimits(ctx, previous, task); err != nil {
+			return err
+		}
+		countStatusChange(ctx, previous, task.Status)
+
+		if values != nil {
+			if err := s.customFields.SetValues(ctx, task.ID, values); err != nil {
+				return err
+			}
+		}
+
+		// Изменение всей серии переносит название и описание на остальные незавершённые экземпляры
+		if task.SeriesID == nil || !opts.ApplyToSeries {
+			return nil
+		}
+
+		series, err := s.series.GetById(ctx, *task.SeriesID)
+		if err != nil {
+			return err
+		}
+
+		series.Title = task.Title
+		series.Description = task.Description
+		return s.series.Update(ctx, series)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, userID, id)
+}
+
+func (s *TaskService) UpdateStatus(ctx context.Context, userID, id, version int, status models.TaskStatus) (*models.Task, error) {
+	if err := validate(s.validator, models.TaskStatusUpdate{Status: status}); err != nil {
+		return nil, err
+	}
+
+	task, err := ownTask(ctx, s.tasks, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(task.Version, version, "task"); err != nil {
+		return nil, err
+	}
+
+	previous := task.Status
+	task.Status = status
+	task.UpdatedAt = time.Now()
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.tasks.Update(ctx, task); err != nil {
+			return err
+		}
+		if err := s.checkLimits(ctx, previous, task); err != nil {
+			return err
+		}
+		countStatusChange(ctx, previous, status)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachCustomFields(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+/*
+Задаёт значения пользовательских полей задачи
+Передаются только изменяемые поля, значение null очищает поле
+*/
+func (s *TaskService) SetCustomFields(ctx context.Context, userID, id int, values map[int]json.RawMessage) (*models.Task, error) {
+	task, err := ownTask(ctx, s.tasks, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := s.customFields.ListForTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if values, err = s.normalizeFieldValues(ctx, fields, values); err != nil {
+		return nil, err
+	}
+
+	if err := s.customFields.SetValues(ctx, id, values); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachCustomFields(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *TaskService) Delete(ctx context.Context, userID, id, version int) error {
+	task, err := ownTask(ctx, s.tasks, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(task.Version, version, "task"); err != nil {
+		return err
+	}
+	return s.tasks.Delete(ctx, id, task.Version)
+}
+
+// Задачи пользователя ownerID, список доступен только ему самому
+func (s *TaskService) ListByUser(ctx context.Context, userID, ownerID int) ([]*models.Task, error) {
+	if userID != ownerID {
+		return nil, accessDenied()
+	}
+
+	return cache.Load(ctx, s.cache, cache.UserTasks, ownerID, func() ([]*models.Task, error) {
+		tasks, err := s.tasks.ListByUser(ctx, ownerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.attachCustomFields(ctx, tasks...); err != nil {
+			return nil, err
+		}
+		return tasks, nil
+	})
+}
+
+// Счётчики метрик увеличиваются только после фиксации единицы работы, в которой произошло изменение
+func countTaskCreated(ctx context.Context) {
+	repository.AfterCommit(ctx, metrics.TasksCreated.Inc)
+}
+
+func countStatusChange(ctx context.Context, from, to models.TaskStatus) {
+	if from == to {
+		return
+	}
+	repository.AfterCommit(ctx, func() {
+		metrics.TaskStatusTransitions.WithLabelValues(string(from), string(to)).Inc()
+	})
+}
+
+/*
+Проверяет WIP-лимиты досок задачи после смены статуса
+Вызывается в единице работы после сохранения задачи, так что параллельные изменения одной колонки не превысят лимит вместе
+*/
+func (s *TaskService) checkLimits(ctx context.Context, previous models.TaskStatus, task *models.Task) error {
+	if previous == task.Status {
+		return nil
+	}
+	return s.boardTasks.CheckLimits(ctx, task.ID)
+}
+
+// Добавляет к задачам значения их пользовательских полей
+func (s *TaskService) attachCustomFields(ctx context.Context, tasks ...*models.Task) error {
+	ids := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	values, err := s.customFields.GetValuesForTasks(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		task.CustomFields = values[task.ID]
+	}
+
+	return nil
+}
+
+/*
+Проверяет значения по определениям полей и приводит их к каноническому виду
+Для полей типа user дополнительно проверяется существование пользователя
+*/
+func (s *TaskService) normalizeFieldValues(
+	ctx context.Context,
+	fields []*models.CustomField,
+	raw map[int]json.RawMessage,
+) (map[int]json.RawMessage, error) {
+	byID := make(map[int]*models.CustomField, len(fields))
+	for _, field := range fields {
+		byID[field.ID] = field
+	}
+
+	values := make(map[int]json.RawMessage, len(raw))
+	for fieldID, value := range raw {
+		field, ok := byID[fieldID]
+		if !ok {
+			return nil, apperr.Validation(fmt.Errorf("field %d is not defined on the task's boards", fieldID))
+		}
+
+		normalized, err := field.Normalize(value)
+		if err != nil {
+			return nil, apperr.Validation(err)
+		}
+
+		if field.Type == models.FieldUser && normalized != nil {
+			var userID int
+			if err := json.Unmarshal(normalized, &userID); err != nil {
+				return nil, err
+			}
+			if _, err := s.users.GetById(ctx, userID); err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					return nil, apperr.Validation(fmt.Errorf("field %q refers to unknown user %d", field.Name, userID))
+				}
+				return nil, err
+			}
+		}
+
+		values[fieldID] = normalized
+	}
+
+	return values, nil
+}
+
+// Новая задача получает значения всех обязательных полей доски, пропущенное поле осталось бы пустым
+func requireFieldValues(fields []*models.CustomField, values map[int]json.RawMessage) error {
+	for _, field := range fields {
+		if field.Required && values[field.ID] == nil {
+			return apperr.Validation(fmt.Errorf("field %q is required", field.Name))
+		}
+	}
+	return nil
+}
+
+func newTask(userID int, req models.TaskRequest) *models.Task {
+	return &models.Task{
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		UserID:      userID,
+		Estimate:    req.Estimate,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+}
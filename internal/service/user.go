@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/CAATHARSIS/task-tracking/internal/apperr"
+	"github.com/CAATHARSIS/task-tracking/internal/auth"
+	"github.com/CAATHARSIS/task-tracking/internal/metrics"
+	"github.com/CAATHARSIS/task-tracking/internal/models"
+	"github.com/CAATHARSIS/task-tracking/internal/repository"
+	"github.com/CAATHARSIS/task-tracking/internal/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+/*
+Регистрация, вход и управление учётной записью
+Пользователь может читать, изменять и удалять только свою учётную запись
+*/
+type UserService struct {
+	users     repository.UserRepository
+	jwt       *auth.JWTService
+	validator *validator.Validate
+}
+
+func NewUserService(users repository.UserRepository, jwt *auth.JWTService) *UserService {
+	return &UserService{
+		users:     users,
+		jwt:       jwt,
+		validator: NewValidator(),
+	}
+}
+
+type registration struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type credentials struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type accountUpdate struct {
+	Email    string `json:"email" validate:"omitempty,email"`
+	Password string `json:"password" validate:"omitempty,min=6"`
+}
+
+func (s *UserService) Register(ctx context.Context, email, password string) (*models.User, error) {
+	req := registration{Email: strings.TrimSpace(email), Password: password}
+	if err := validate(s.validator, req); err != nil {
+		return nil, err
+	}
+
+	_, err := s.users.GetByEmail(ctx, req.Email)
+	if err == nil {
+		return nil, apperr.Conflict("User already exists")
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:        req.Email,
+		PasswordHash: hashedPassword,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Проверяет email и пароль и выдаёт JWT
+func (s *UserService) Login(ctx context.Context, email, password string) (string, error) {
+	req := credentials{Email: strings.TrimSpace(email), Password: password}
+	if err := validate(s.validator, req); err != nil {
+		return "", err
+	}
+
+	// Неизвестный email и неверный пароль неотличимы для клиента
+	user, err := s.users.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", invalidCredentials()
+		}
+		return "", err
+	}
+
+	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
+		return "", invalidCredentials()
+	}
+
+	token, err := s.jwt.GenerateJWT(user.ID)
+	if err != nil {
+		return "", err
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
+	return token, nil
+}
+
+func invalidCredentials() error {
+	metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+	return apperr.Unauthorized("Invalid email or password")
+}
+
+func (s *UserService) Get(ctx context.Context, userID, id int) (*models.User, error) {
+	if userID != id {
+		return nil, accessDenied()
+	}
+
+	user, err := s.users.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = ""
+	return user, nil
+}
+
+// Меняет email и/или пароль, пустые значения остаются прежними
+func (s *UserService) Update(ctx context.Context, userID, id int, email, password string) error {
+	if userID != id {
+		return accessDenied()
+	}
+
+	req := accountUpdate{Email: strings.TrimSpace(email), Password: password}
+	if err := validate(s.validator, req); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if req.Email != "" && req.Email != user.Email {
+		if _, err := s.users.GetByEmail(ctx, req.Email); err == nil {
+			return apperr.Conflict("User already exists")
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		user.Email = req.Email
+	}
+
+	if req.Password != "" {
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash = hashedPassword
+	}
+
+	return s.users.Update(ctx, user)
+}
+
+func (s *UserService) Delete(ctx context.Context, userID, id int) error {
+	if userID != id {
+		return accessDenied()
+	}
+	return s.users.Delete(ctx, id)
+}
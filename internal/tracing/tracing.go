@@ -0,0 +1,121 @@
+/*
+Трассировка OpenTelemetry: спаны HTTP-запросов (otelgin) и SQL-запросов (repository.DB)
+
+Контекст трассировки принимается из входящих заголовков traceparent и tracestate (W3C Trace Context).
+Спаны выгружаются по OTLP/HTTP или пишутся в JSON в stderr или файл, чтобы их можно было проверить без коллектора.
+stdout занят журналом приложения, и спаны в нём сломали бы разбор JSON-строк журнала
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Имя сервиса в спанах и ресурсе трассировки
+const ServiceName = "task-tracking"
+
+// Способы выгрузки спанов
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStderr = "stderr"
+	ExporterFile   = "file"
+)
+
+// Распространитель контекста трассировки W3C (traceparent, tracestate) и baggage
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+/*
+Настраивает глобальные провайдер трассировки и распространитель контекста
+Возвращённая функция выгружает оставшиеся спаны и закрывает экспортёр.
+С экспортёром none спаны не создаются, но входящий контекст трассировки всё равно разбирается
+*/
+func Setup(ctx context.Context, cfg *config.Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", cfg.TracingSampleRatio)
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider := Install(cfg.TracingSampleRatio, sdktrace.WithBatcher(exporter))
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+/*
+Создаёт провайдер с выборкой доли ratio новых трасс и делает его глобальным
+Решение о выборке трассы, начатой вызывающей стороной, берётся из её контекста
+*/
+func Install(ratio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(attribute.String("service.name", ServiceName))
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	}, opts...)
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return provider
+}
+
+// Экспортёр по TRACING_EXPORTER и функция, закрывающая файл вывода
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch cfg.TracingExporter {
+	case ExporterNone, "":
+		return nil, noClose, nil
+
+	case ExporterOTLP:
+		// Без OTLP_ENDPOINT адрес берётся из стандартных переменных OTEL_EXPORTER_OTLP_*
+		var opts []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, noClose, err
+
+	case ExporterStderr:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+		return exporter, noClose, err
+
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return exporter, file.Close, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown TRACING_EXPORTER %q, expected %s, %s, %s or %s",
+		cfg.TracingExporter, ExporterNone, ExporterOTLP, ExporterStderr, ExporterFile)
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), &config.Config{
+		TracingExporter:    ExporterFile,
+		TracingFile:        path,
+		TracingSampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "GET /api/tasks/:id")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"GET /api/tasks/:id"`) || !strings.Contains(string(data), ServiceName) {
+		t.Fatalf("expected span in trace file, got %s", data)
+	}
+}
+
+func TestSetupRejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []config.Config{
+		{TracingExporter: "jaeger", TracingSampleRatio: 1},
+		{TracingExporter: ExporterNone, TracingSampleRatio: 1.5},
+	} {
+		if _, err := Setup(context.Background(), &cfg); err == nil {
+			t.Errorf("expected config %+v to be rejected", cfg)
+		}
+	}
+}
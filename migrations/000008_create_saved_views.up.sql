@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS saved_views (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name CHARACTER VARYING(50) NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}'::jsonb,
+    board_id INTEGER REFERENCES boards(id) ON DELETE CASCADE,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT saved_view_name_min_length CHECK (length(name) >= 3),
+    CONSTRAINT saved_view_shared_board CHECK (NOT shared OR board_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_user_id ON saved_views(user_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_board_id ON saved_views(board_id) WHERE shared;
@@ -0,0 +1,5 @@
+ALTER TABLE tasks DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS task_series;
+
+DROP TYPE IF EXISTS recurrence_frequency;
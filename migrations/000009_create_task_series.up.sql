@@ -0,0 +1,22 @@
+CREATE TYPE recurrence_frequency AS ENUM ('daily', 'weekly', 'monthly');
+
+CREATE TABLE IF NOT EXISTS task_series (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title CHARACTER VARYING(100) NOT NULL,
+    description CHARACTER VARYING(500),
+    frequency recurrence_frequency NOT NULL,
+    interval_count INTEGER NOT NULL DEFAULT 1,
+    until TIMESTAMP WITH TIME ZONE,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT task_series_interval_positive CHECK (interval_count > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_series_next_run_at ON task_series(next_run_at);
+
+ALTER TABLE tasks
+ADD COLUMN series_id INTEGER REFERENCES task_series(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_series_id ON tasks(series_id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS board_wip_limits;
+
+ALTER TABLE tasks
+DROP CONSTRAINT IF EXISTS task_estimate_non_negative,
+DROP COLUMN IF EXISTS estimate;
+
+ALTER TABLE boards DROP COLUMN IF EXISTS estimate_unit;
+
+DROP TYPE IF EXISTS estimate_unit;
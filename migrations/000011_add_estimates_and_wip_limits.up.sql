@@ -0,0 +1,16 @@
+CREATE TYPE estimate_unit AS ENUM ('points', 'hours');
+
+ALTER TABLE boards
+ADD COLUMN estimate_unit estimate_unit NOT NULL DEFAULT 'points';
+
+ALTER TABLE tasks
+ADD COLUMN estimate NUMERIC(6, 2),
+ADD CONSTRAINT task_estimate_non_negative CHECK (estimate IS NULL OR estimate >= 0);
+
+CREATE TABLE IF NOT EXISTS board_wip_limits (
+    board_id INTEGER NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    status task_status NOT NULL,
+    max_tasks INTEGER NOT NULL,
+    PRIMARY KEY (board_id, status),
+    CONSTRAINT board_wip_limit_positive CHECK (max_tasks > 0)
+);
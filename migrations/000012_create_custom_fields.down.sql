@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS task_custom_field_values;
+
+DROP TABLE IF EXISTS board_custom_fields;
+
+DROP TYPE IF EXISTS custom_field_type;
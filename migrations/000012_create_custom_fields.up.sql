@@ -0,0 +1,23 @@
+CREATE TYPE custom_field_type AS ENUM ('text', 'number', 'date', 'single_select', 'multi_select', 'user');
+
+CREATE TABLE IF NOT EXISTS board_custom_fields (
+    id SERIAL PRIMARY KEY,
+    board_id INTEGER NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    name CHARACTER VARYING(50) NOT NULL,
+    type custom_field_type NOT NULL,
+    options JSONB NOT NULL DEFAULT '[]'::jsonb,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT board_custom_field_name_unique UNIQUE (board_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_board_custom_fields_board_id ON board_custom_fields(board_id);
+
+CREATE TABLE IF NOT EXISTS task_custom_field_values (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    field_id INTEGER NOT NULL REFERENCES board_custom_fields(id) ON DELETE CASCADE,
+    value JSONB NOT NULL,
+    PRIMARY KEY (task_id, field_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_custom_field_values_field_id ON task_custom_field_values(field_id);
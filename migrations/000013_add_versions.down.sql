@@ -0,0 +1,3 @@
+ALTER TABLE boards DROP COLUMN IF EXISTS version;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS version;
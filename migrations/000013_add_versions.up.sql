@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE boards
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
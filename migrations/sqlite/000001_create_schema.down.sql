@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS task_custom_field_values;
+DROP TABLE IF EXISTS board_custom_fields;
+DROP TABLE IF EXISTS time_entries;
+DROP TABLE IF EXISTS saved_views;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS board_wip_limits;
+DROP TABLE IF EXISTS board_tasks;
+DROP TABLE IF EXISTS tasks;
+DROP TABLE IF EXISTS task_series;
+DROP TABLE IF EXISTS boards;
+DROP TABLE IF EXISTS users;
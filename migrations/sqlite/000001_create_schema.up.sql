@@ -0,0 +1,140 @@
+-- Схема SQLite повторяет миграции Postgres 000001-000012
+-- Перечисления заменены ограничениями CHECK, JSONB - текстом с JSON
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email VARCHAR(100) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS boards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(50) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    estimate_unit TEXT NOT NULL DEFAULT 'points',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT board_name_min_length CHECK (length(name) >= 3),
+    CONSTRAINT board_estimate_unit CHECK (estimate_unit IN ('points', 'hours'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_boards_user_id ON boards(user_id);
+
+CREATE TABLE IF NOT EXISTS task_series (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(500),
+    frequency TEXT NOT NULL,
+    interval_count INTEGER NOT NULL DEFAULT 1,
+    until TIMESTAMP,
+    next_run_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT task_series_frequency CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+    CONSTRAINT task_series_interval_positive CHECK (interval_count > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_series_next_run_at ON task_series(next_run_at);
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(500),
+    status TEXT NOT NULL DEFAULT 'todo',
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    series_id INTEGER REFERENCES task_series(id) ON DELETE SET NULL,
+    estimate REAL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT task_title_length CHECK (length(title) >= 3),
+    CONSTRAINT task_status CHECK (status IN ('todo', 'in_progress', 'done')),
+    CONSTRAINT task_estimate_non_negative CHECK (estimate IS NULL OR estimate >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+CREATE INDEX IF NOT EXISTS idx_tasks_series_id ON tasks(series_id);
+
+CREATE TABLE IF NOT EXISTS board_tasks (
+    board_id INTEGER NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    PRIMARY KEY (board_id, task_id)
+);
+
+CREATE TABLE IF NOT EXISTS board_wip_limits (
+    board_id INTEGER NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    max_tasks INTEGER NOT NULL,
+    PRIMARY KEY (board_id, status),
+    CONSTRAINT board_wip_limit_status CHECK (status IN ('todo', 'in_progress', 'done')),
+    CONSTRAINT board_wip_limit_positive CHECK (max_tasks > 0)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash VARCHAR(255) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    filter TEXT NOT NULL DEFAULT '{}',
+    board_id INTEGER REFERENCES boards(id) ON DELETE CASCADE,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT saved_view_name_min_length CHECK (length(name) >= 3),
+    CONSTRAINT saved_view_shared_board CHECK (NOT shared OR board_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_views_user_id ON saved_views(user_id);
+CREATE INDEX IF NOT EXISTS idx_saved_views_board_id ON saved_views(board_id) WHERE shared;
+
+CREATE TABLE IF NOT EXISTS time_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    manual BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT time_entry_range CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_task_id ON time_entries(task_id);
+CREATE INDEX IF NOT EXISTS idx_time_entries_user_started ON time_entries(user_id, started_at);
+
+-- Не более одного запущенного таймера на пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE ended_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS board_custom_fields (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    board_id INTEGER NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    type TEXT NOT NULL,
+    options TEXT NOT NULL DEFAULT '[]',
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT board_custom_field_type CHECK (type IN ('text', 'number', 'date', 'single_select', 'multi_select', 'user')),
+    CONSTRAINT board_custom_field_name_unique UNIQUE (board_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_board_custom_fields_board_id ON board_custom_fields(board_id);
+
+CREATE TABLE IF NOT EXISTS task_custom_field_values (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    field_id INTEGER NOT NULL REFERENCES board_custom_fields(id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (task_id, field_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_custom_field_values_field_id ON task_custom_field_values(field_id);
@@ -0,0 +1,4 @@
+-- Повторяет миграцию Postgres 000013
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE boards ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
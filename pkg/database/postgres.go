@@ -1,27 +1,111 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"time"
 
-	_ "github.com/lib/pq"
 	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/lib/pq"
 )
 
-func NewPostgresDB(cfg *config.Config) (*sql.DB, error) {
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode,
-	)
+// Паузы между попытками подключения при запуске: удваиваются от первой до последней
+const (
+	firstRetryDelay = 250 * time.Millisecond
+	maxRetryDelay   = 5 * time.Second
+)
 
-	db, err := sql.Open("postgres", connStr)
+/*
+Ошибки PostgreSQL, которые не исправятся сами: неверный пароль или пользователь и отсутствующая база
+Повторять подключение с ними бессмысленно, запуск сразу завершается с понятной причиной
+*/
+var permanentErrors = map[pq.ErrorCode]bool{
+	"28P01": true, // invalid_password
+	"28000": true, // invalid_authorization_specification
+	"3D000": true, // invalid_catalog_name
+}
+
+/*
+Подключается к PostgreSQL и настраивает пул соединений
+База может подняться позже приложения (docker compose, Kubernetes), поэтому подключение
+повторяется с растущими паузами, пока не истечёт DB_CONNECT_TIMEOUT
+*/
+func NewPostgresDB(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", postgresDSN(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err = db.Ping(); err != nil {
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+
+	if err = pingWithRetry(db, cfg.DBConnectTimeout); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	return db, nil
-}
\ No newline at end of file
+}
+
+// Строка подключения: DATABASE_URL как есть, иначе URL из полей DB_*, где спецсимволы пароля экранируются
+func postgresDSN(cfg *config.Config) string {
+	if cfg.DatabaseURL != "" {
+		return cfg.DatabaseURL
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.DBUser, cfg.DBPassword),
+		Host:     net.JoinHostPort(cfg.DBHost, cfg.DBPort),
+		Path:     "/" + cfg.DBName,
+		RawQuery: url.Values{"sslmode": {cfg.DBSSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+/*
+Проверяет соединение, пока оно не установится или не истечёт timeout; при timeout 0 попытка одна
+В ошибке - причина последней завершившейся попытки, а не истечение общего срока.
+Ошибки аутентификации и отсутствие базы не повторяются
+*/
+func pingWithRetry(db *sql.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return db.Ping()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var lastErr error
+	delay := firstRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && permanentErrors[pqErr.Code] {
+			return err
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("database is unavailable after %d attempts: %w", attempt, lastErr)
+		}
+
+		slog.Warn("Database is unavailable, retrying", "attempt", attempt, "retry_in", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database is unavailable after %d attempts: %w", attempt, lastErr)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
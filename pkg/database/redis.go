@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/CAATHARSIS/task-tracking/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Подключается к Redis и проверяет соединение
+func NewRedisClient(cfg *config.Config) (*redis.Client, error) {
+	db, err := strconv.Atoi(cfg.RedisDB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_DB %q: %w", cfg.RedisDB, err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     net.JoinHostPort(cfg.RedisHost, cfg.RedisPort),
+		Password: cfg.RedisPassword,
+		DB:       db,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	return client, nil
+}